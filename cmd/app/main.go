@@ -2,17 +2,30 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/en9inerd/postpal/internal/announce"
 	"github.com/en9inerd/postpal/internal/config"
+	"github.com/en9inerd/postpal/internal/forge"
+	"github.com/en9inerd/postpal/internal/git"
 	"github.com/en9inerd/postpal/internal/log"
+	"github.com/en9inerd/postpal/internal/pipeline"
 	"github.com/en9inerd/postpal/internal/server"
+	"github.com/en9inerd/postpal/internal/telegram"
+	"github.com/en9inerd/postpal/internal/zola"
 )
 
 var version = "dev"
@@ -28,20 +41,116 @@ func run(ctx context.Context, args []string, getenv func(string) string) error {
 		return fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	if cfg.PrintConfig {
+		if err := cfg.WriteTOML(os.Stdout); err != nil {
+			return fmt.Errorf("failed to print config: %w", err)
+		}
+		return cfg.Validate()
+	}
+
 	logger := log.NewLogger(verbose)
+
+	gitService := git.NewService(
+		cfg.RepoDir,
+		cfg.RepoURL,
+		cfg.RepoBranch,
+		cfg.RepoToken,
+		git.Author{Name: cfg.GitAuthorName, Email: cfg.GitAuthorEmail},
+	).
+		WithHTTPUsername(cfg.RepoUsername).
+		WithSSHKey(cfg.RepoSSHKey, cfg.RepoSSHKeyPassphrase).
+		WithKnownHosts(cfg.RepoKnownHosts)
+
+	if cfg.GitSigningKey != "" {
+		signer, err := git.NewSigner(cfg.GitSigningFormat, cfg.GitSigningKey, cfg.GitSigningPassphrase)
+		if err != nil {
+			return fmt.Errorf("failed to load commit signing key: %w", err)
+		}
+		gitService.WithSigner(signer)
+	}
+
+	if cfg.DryRun != "" {
+		return previewUpdate(ctx, cfg, gitService, logger)
+	}
+
+	journal, err := pipeline.LoadJournal(filepath.Join(cfg.DataDir, "publish-journal.json"))
+	if err != nil {
+		return fmt.Errorf("failed to load publish journal: %w", err)
+	}
+	if pending := len(journal.Pending()); pending > 0 && gitService.RepoExists() {
+		// A restart interrupted publishing: drop its partial changes, the jobs are replayed
+		logger.Info("recovering interrupted publish jobs", "jobs", pending)
+		if err := gitService.Discard(); err != nil {
+			return fmt.Errorf("failed to discard interrupted changes: %w", err)
+		}
+	}
+	if cfg.PublishMode == config.PublishModeBranch && gitService.RepoExists() {
+		// A restart may have interrupted publishing on a post branch
+		if err := gitService.CheckoutBase(); err != nil {
+			return fmt.Errorf("failed to check out %s: %w", cfg.RepoBranch, err)
+		}
+	}
+
+	// Fail fast if the site repository cannot be prepared
+	if err := gitService.Bootstrap(ctx); err != nil {
+		return fmt.Errorf("failed to prepare site repository: %w", err)
+	}
+
+	channels, err := newChannels(cfg, gitService)
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		if info, err := os.Stat(channel.Zola.PostsDir()); err != nil || !info.IsDir() {
+			return fmt.Errorf("posts directory %s does not exist in the site repository", channel.Zola.PostsDir())
+		}
+	}
+
+	if cfg.RebuildIndex {
+		for _, channel := range channels {
+			count, err := channel.Zola.RebuildIndex()
+			if err != nil {
+				return fmt.Errorf("failed to rebuild post index: %w", err)
+			}
+			fmt.Printf("indexed %d posts in %s\n", count, channel.Zola.PostsDir())
+		}
+		return nil
+	}
+
 	logger.Info("starting server", "version", version, "port", cfg.Port)
 
-	handler, err := server.NewServer(logger, cfg)
+	telegramClient := telegram.NewClient(cfg.TelegramToken, logger)
+	p := pipeline.New(telegramClient, gitService, channels, logger).
+		WithMediaGroupWait(cfg.MediaGroupWait).
+		WithBatchWait(cfg.PublishBatchWait).
+		WithJournal(journal)
+	if cfg.PublishMode == config.PublishModeBranch {
+		requester, err := forge.New(cfg.ForgeType, cfg.ForgeAPIURL, cfg.ForgeRepository, cfg.ForgeToken)
+		if err != nil {
+			return fmt.Errorf("failed to set up change requests: %w", err)
+		}
+		p.WithBranchPublishing(requester)
+	}
+	p.Start()
+
+	announcer := announce.NewAnnouncer(telegramClient, cfg.SiteURL, logger)
+	handler, err := server.NewServer(logger, cfg, p, announcer)
 	if err != nil {
 		return fmt.Errorf("failed to create server: %w", err)
 	}
 
+	// Requests outlive the shutdown signal so Shutdown can drain them, but are cancelled
+	// together with their Telegram calls once the grace period is over
+	requestCtx, cancelRequests := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRequests()
+
 	httpServer := &http.Server{
 		Addr:         ":" + cfg.Port,
 		Handler:      handler,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		BaseContext:  func(net.Listener) context.Context { return requestCtx },
 	}
 
 	go func() {
@@ -52,6 +161,13 @@ func run(ctx context.Context, args []string, getenv func(string) string) error {
 	}()
 
 	var wg sync.WaitGroup
+
+	if cfg.TelegramMode == config.TelegramModePolling {
+		wg.Go(func() {
+			pollUpdates(ctx, logger, cfg, telegramClient, p)
+		})
+	}
+
 	wg.Go(func() {
 		<-ctx.Done()
 		logger.Info("shutdown signal received")
@@ -61,11 +177,15 @@ func run(ctx context.Context, args []string, getenv func(string) string) error {
 
 		if err := httpServer.Shutdown(shutdownCtx); err != nil {
 			fmt.Fprintf(os.Stderr, "error shutting down http server: %s\n", err)
+			cancelRequests()
 		}
 		logger.Info("server stopped")
 	})
 	wg.Wait()
 
+	// Publish albums that were still waiting for more messages and the queued changes
+	p.Close()
+
 	return nil
 }
 
@@ -77,6 +197,115 @@ func main() {
 	}
 }
 
+// newChannels creates a Zola service for every configured channel.
+// Without channels in the config file, posts of TelegramChannel are written to PostsDir.
+func newChannels(cfg *config.Config, gitService *git.Service) ([]pipeline.Channel, error) {
+	if len(cfg.Channels) == 0 {
+		zolaService, err := newZolaService(cfg, gitService, cfg.PostsDir, cfg.TelegramChannel, "post-index.json")
+		if err != nil {
+			return nil, err
+		}
+
+		channel := pipeline.Channel{Zola: zolaService}
+		if chatID, err := strconv.ParseInt(cfg.TelegramChannel, 10, 64); err == nil {
+			channel.ChatID = chatID
+		} else {
+			channel.Username = cfg.TelegramChannel
+		}
+		return []pipeline.Channel{channel}, nil
+	}
+
+	channels := make([]pipeline.Channel, 0, len(cfg.Channels))
+	for _, channelCfg := range cfg.Channels {
+		indexFile := fmt.Sprintf("post-index-%d.json", channelCfg.ChatID)
+		zolaService, err := newZolaService(cfg, gitService, channelCfg.Section, channelCfg.Name, indexFile)
+		if err != nil {
+			return nil, err
+		}
+		zolaService.
+			WithTitleStrategy(channelCfg.TitleStrategy).
+			WithTags(channelCfg.Tags).
+			WithDraft(!channelCfg.Publishes())
+
+		channels = append(channels, pipeline.Channel{ChatID: channelCfg.ChatID, Zola: zolaService})
+	}
+	return channels, nil
+}
+
+func newZolaService(cfg *config.Config, gitService *git.Service, section, channelName, indexFile string) (*zola.Service, error) {
+	index, err := zola.LoadIndex(filepath.Join(cfg.DataDir, indexFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load post index: %w", err)
+	}
+
+	zolaService := zola.NewService(
+		filepath.Join(cfg.RepoDir, section),
+		section,
+		cfg.RepoDir,
+		channelName,
+		gitService,
+		"",
+	).WithIndex(index)
+
+	return zolaService, nil
+}
+
+// previewUpdate prints the files the post of a Telegram update read from cfg.DryRun would change,
+// without touching the site repository
+func previewUpdate(ctx context.Context, cfg *config.Config, gitService *git.Service, logger *slog.Logger) error {
+	var data []byte
+	var err error
+	if cfg.DryRun == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(cfg.DryRun)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read telegram update: %w", err)
+	}
+
+	var update telegram.Update
+	if err := json.Unmarshal(data, &update); err != nil {
+		return fmt.Errorf("failed to parse telegram update: %w", err)
+	}
+
+	channels, err := newChannels(cfg, gitService)
+	if err != nil {
+		return err
+	}
+
+	telegramClient := telegram.NewClient(cfg.TelegramToken, logger)
+	changes, err := pipeline.New(telegramClient, gitService, channels, logger).Preview(ctx, &update)
+	if err != nil {
+		return fmt.Errorf("failed to preview post: %w", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Changes []zola.FileChange `json:"changes"`
+	}{changes})
+}
+
+// pollUpdates receives updates with getUpdates until ctx is cancelled
+func pollUpdates(ctx context.Context, logger *slog.Logger, cfg *config.Config, telegramClient *telegram.Client, p *pipeline.Pipeline) {
+	// getUpdates is rejected while a webhook is set
+	if _, err := telegramClient.DeleteWebhook(ctx, telegram.DeleteWebhookRequest{}); err != nil {
+		logger.Warn("failed to delete telegram webhook", "error", err)
+	}
+
+	err := telegramClient.Poll(ctx, telegram.PollOptions{
+		OffsetFile:     filepath.Join(cfg.DataDir, "telegram-offset"),
+		AllowedUpdates: []string{"channel_post", "edited_channel_post"},
+		OnSkip:         p.FailUpdate,
+	}, p.HandleUpdate)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		logger.Error("telegram polling stopped", "error", err)
+		return
+	}
+	logger.Info("telegram polling stopped")
+}
+
 func cleanArgs(args []string) (cleanArgs []string, verbose bool) {
 	for _, arg := range args {
 		if arg == "--verbose" || arg == "-v" {
@@ -0,0 +1,158 @@
+// Package announce publishes Zola pages to Telegram channels as messages linking back to the site.
+package announce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/en9inerd/postpal/internal/telegram"
+	"github.com/en9inerd/postpal/internal/zola"
+)
+
+// MaxMessageLength is the longest message text telegram.SendMessageRequest accepts
+const MaxMessageLength = 4096
+
+// summaryMarker ends the summary of a Zola page
+const summaryMarker = "<!-- more -->"
+
+// DefaultLinkText is the text of the link to the page on the site
+const DefaultLinkText = "Read on the site"
+
+// Announcer renders Zola pages to Telegram messages and sends them
+type Announcer struct {
+	client   *telegram.Client
+	siteURL  string
+	linkText string
+	logger   *slog.Logger
+}
+
+// NewAnnouncer creates an Announcer linking to pages below siteURL, the base URL of the Zola site.
+// Messages have no link when siteURL is empty.
+func NewAnnouncer(client *telegram.Client, siteURL string, logger *slog.Logger) *Announcer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Announcer{
+		client:   client,
+		siteURL:  siteURL,
+		linkText: DefaultLinkText,
+		logger:   logger,
+	}
+}
+
+// WithLinkText sets the text of the link to the page on the site
+func (a *Announcer) WithLinkText(text string) *Announcer {
+	a.linkText = text
+	return a
+}
+
+// Render builds the message announcing a page in the given parse mode, ParseModeHTML or ParseModeMarkdownV2.
+// The message has the page title in bold, the content up to the <!-- more --> summary marker and a link
+// to the page. Content that doesn't fit in MaxMessageLength characters is cut and ends with an ellipsis.
+func (a *Announcer) Render(page *zola.Page, parseMode string) (string, error) {
+	if parseMode != ParseModeHTML && parseMode != ParseModeMarkdownV2 {
+		return "", fmt.Errorf("unsupported parse mode %q", parseMode)
+	}
+
+	content, _, _ := strings.Cut(page.Content, summaryMarker)
+	doc := parseMarkdown(content)
+
+	s := newRenderer(parseMode, -1).syntax
+	var header, footer string
+	if page.Title != "" {
+		header = s.wrap(formatBold, s.text(page.Title), "")
+	}
+	if a.siteURL != "" {
+		footer = s.wrap(formatLink, s.text(a.linkText), page.Permalink(a.siteURL))
+	}
+
+	render := func(budget int) string {
+		parts := []string{header, newRenderer(parseMode, budget).render(doc), footer}
+		return strings.Join(slices.DeleteFunc(parts, func(part string) bool { return part == "" }), "\n\n")
+	}
+
+	message := render(-1)
+	if utf8.RuneCountInString(message) <= MaxMessageLength {
+		return message, nil
+	}
+
+	// Keep as much content as fits
+	low, high := 0, utf8.RuneCountInString(content)
+	for low < high {
+		mid := (low + high + 1) / 2
+		if utf8.RuneCountInString(render(mid)) <= MaxMessageLength {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	message = render(low)
+	if utf8.RuneCountInString(message) > MaxMessageLength {
+		return "", errors.New("page title and link do not fit in a message")
+	}
+	return message, nil
+}
+
+// Publish sends the message announcing a page to a channel. Drafts are not published.
+func (a *Announcer) Publish(ctx context.Context, chatID string, page *zola.Page, parseMode string) (*telegram.Message, error) {
+	if page.Draft {
+		return nil, fmt.Errorf("page %s is a draft", page.Path)
+	}
+
+	text, err := a.Render(page, parseMode)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := a.client.SendMessage(ctx, telegram.SendMessageRequest{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: parseMode,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send announcement of %s: %w", page.Path, err)
+	}
+
+	a.logger.Info("published page to telegram", "page", page.Path, "chat_id", chatID, "message_id", msg.MessageID)
+	return msg, nil
+}
+
+// Update edits an earlier announcement of a page to match its current content. An announcement
+// that is already up to date is left alone, and one that was deleted from the channel is sent again,
+// so the returned message may have a new ID.
+func (a *Announcer) Update(ctx context.Context, chatID string, messageID int64, page *zola.Page, parseMode string) (*telegram.Message, error) {
+	if page.Draft {
+		return nil, fmt.Errorf("page %s is a draft", page.Path)
+	}
+
+	text, err := a.Render(page, parseMode)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := a.client.EditMessageText(ctx, telegram.EditMessageTextRequest{
+		ChatID:    chatID,
+		MessageID: messageID,
+		Text:      text,
+		ParseMode: parseMode,
+	})
+	switch {
+	case errors.Is(err, telegram.ErrMessageNotModified):
+		a.logger.Debug("announcement is up to date", "page", page.Path, "chat_id", chatID, "message_id", messageID)
+		return &telegram.Message{MessageID: messageID, Text: text}, nil
+	case errors.Is(err, telegram.ErrMessageNotFound):
+		a.logger.Info("announcement was deleted, sending it again", "page", page.Path, "chat_id", chatID, "message_id", messageID)
+		return a.Publish(ctx, chatID, page, parseMode)
+	case err != nil:
+		return nil, fmt.Errorf("failed to update announcement of %s: %w", page.Path, err)
+	}
+
+	a.logger.Info("updated page announcement", "page", page.Path, "chat_id", chatID, "message_id", msg.MessageID)
+	return msg, nil
+}
@@ -0,0 +1,211 @@
+package announce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/en9inerd/postpal/internal/telegram"
+	"github.com/en9inerd/postpal/internal/zola"
+)
+
+const testToken = "123456:test-token"
+
+func TestRender(t *testing.T) {
+	page := &zola.Page{
+		Path:    "posts/hello-world.md",
+		Title:   "Hello & welcome",
+		Content: "\nThe **first** post.\n\n<!-- more -->\n\nThe rest of the post.\n",
+	}
+	announcer := NewAnnouncer(nil, "https://example.com/", nil)
+
+	tests := []struct {
+		name      string
+		parseMode string
+		expected  string
+	}{
+		{"html", ParseModeHTML, "<b>Hello &amp; welcome</b>\n\nThe <b>first</b> post.\n\n" +
+			`<a href="https://example.com/posts/hello-world/">Read on the site</a>`},
+		{"markdown v2", ParseModeMarkdownV2, "*Hello & welcome*\n\nThe *first* post\\.\n\n" +
+			"[Read on the site](https://example.com/posts/hello-world/)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := announcer.Render(page, tt.parseMode)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRender_NoSiteURL(t *testing.T) {
+	page := &zola.Page{Path: "posts/a.md", Content: "Just text"}
+	result, err := NewAnnouncer(nil, "", nil).WithLinkText("unused").Render(page, ParseModeHTML)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if result != "Just text" {
+		t.Errorf("Expected only the content, got %q", result)
+	}
+}
+
+func TestRender_Truncates(t *testing.T) {
+	page := &zola.Page{
+		Path:    "posts/long.md",
+		Title:   "Long",
+		Content: strings.Repeat("Some **bold** words & more. ", 400),
+	}
+	announcer := NewAnnouncer(nil, "https://example.com", nil)
+
+	for _, parseMode := range []string{ParseModeHTML, ParseModeMarkdownV2} {
+		t.Run(parseMode, func(t *testing.T) {
+			result, err := announcer.Render(page, parseMode)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if n := utf8.RuneCountInString(result); n > MaxMessageLength || n < MaxMessageLength-100 {
+				t.Errorf("Expected a message just below %d characters, got %d", MaxMessageLength, n)
+			}
+			if !strings.Contains(result, ellipsis) {
+				t.Error("Expected the content to end with an ellipsis")
+			}
+			if !strings.Contains(result, "https://example.com/posts/long/") {
+				t.Error("Expected the link to the page to be kept")
+			}
+			if parseMode == ParseModeHTML && strings.Count(result, "<b>") != strings.Count(result, "</b>") {
+				t.Error("Expected balanced bold tags")
+			}
+		})
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	announcer := NewAnnouncer(nil, "https://example.com", nil)
+
+	if _, err := announcer.Render(&zola.Page{Path: "posts/a.md"}, "Markdown"); err == nil {
+		t.Error("Expected an error for an unsupported parse mode")
+	}
+
+	page := &zola.Page{Path: "posts/a.md", Title: strings.Repeat("x", MaxMessageLength)}
+	if _, err := announcer.Render(page, ParseModeHTML); err == nil {
+		t.Error("Expected an error for a title that doesn't fit")
+	}
+}
+
+func TestPublish(t *testing.T) {
+	var received telegram.SendMessageRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /bot"+testToken+"/sendMessage", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":42,"date":0,"chat":{"id":-100,"type":"channel"},"text":"sent"}}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := telegram.NewClient(testToken, nil).WithAPIURL(server.URL)
+	announcer := NewAnnouncer(client, "https://example.com", nil)
+	page := &zola.Page{Path: "posts/a.md", Title: "A", Content: "Body"}
+
+	msg, err := announcer.Publish(context.Background(), "@channel", page, ParseModeHTML)
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if msg.MessageID != 42 {
+		t.Errorf("Expected message 42, got %d", msg.MessageID)
+	}
+	if received.ChatID != "@channel" || received.ParseMode != ParseModeHTML {
+		t.Errorf("Unexpected request: %+v", received)
+	}
+	if !strings.HasPrefix(received.Text, "<b>A</b>\n\nBody") {
+		t.Errorf("Unexpected text %q", received.Text)
+	}
+
+	page.Draft = true
+	if _, err := announcer.Publish(context.Background(), "@channel", page, ParseModeHTML); err == nil {
+		t.Error("Expected an error publishing a draft")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name      string
+		edit      string // editMessageText response
+		messageID int64
+		sent      bool
+	}{
+		{"edited", `{"ok":true,"result":{"message_id":7,"date":0,"text":"edited"}}`, 7, false},
+		{"not modified", `{"ok":false,"error_code":400,"description":"Bad Request: message is not modified: specified new message content and reply markup are exactly the same as a current content and reply markup of the message"}`, 7, false},
+		{"deleted", `{"ok":false,"error_code":400,"description":"Bad Request: message to edit not found"}`, 42, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := false
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /bot"+testToken+"/editMessageText", func(w http.ResponseWriter, r *http.Request) {
+				var req telegram.EditMessageTextRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MessageID != 7 {
+					http.Error(w, "unexpected request", http.StatusBadRequest)
+					return
+				}
+				if !strings.Contains(tt.edit, `"ok":true`) {
+					w.WriteHeader(http.StatusBadRequest)
+				}
+				fmt.Fprint(w, tt.edit)
+			})
+			mux.HandleFunc("POST /bot"+testToken+"/sendMessage", func(w http.ResponseWriter, r *http.Request) {
+				sent = true
+				fmt.Fprint(w, `{"ok":true,"result":{"message_id":42,"date":0,"text":"sent"}}`)
+			})
+			server := httptest.NewServer(mux)
+			t.Cleanup(server.Close)
+
+			client := telegram.NewClient(testToken, nil).WithAPIURL(server.URL)
+			announcer := NewAnnouncer(client, "https://example.com", nil)
+			page := &zola.Page{Path: "posts/a.md", Title: "A", Content: "Body"}
+
+			msg, err := announcer.Update(context.Background(), "@channel", 7, page, ParseModeHTML)
+			if err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+			if msg.MessageID != tt.messageID {
+				t.Errorf("Expected message %d, got %d", tt.messageID, msg.MessageID)
+			}
+			if sent != tt.sent {
+				t.Errorf("Expected sent=%v, got %v", tt.sent, sent)
+			}
+		})
+	}
+}
+
+func TestUpdate_OtherErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /bot"+testToken+"/editMessageText", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"ok":false,"error_code":403,"description":"Forbidden: bot was kicked from the channel chat"}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := telegram.NewClient(testToken, nil).WithAPIURL(server.URL)
+	announcer := NewAnnouncer(client, "", nil)
+
+	_, err := announcer.Update(context.Background(), "@channel", 7, &zola.Page{Path: "posts/a.md", Title: "A"}, ParseModeHTML)
+	if !errors.Is(err, telegram.ErrBotKicked) {
+		t.Errorf("Expected a bot kicked error, got %v", err)
+	}
+}
@@ -0,0 +1,487 @@
+package announce
+
+import (
+	"bufio"
+	"bytes"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// Telegram parse modes supported by the renderers
+const (
+	ParseModeHTML       = "HTML"
+	ParseModeMarkdownV2 = "MarkdownV2"
+)
+
+// ellipsis marks text cut to fit the message length limit
+const ellipsis = "…"
+
+// format is the formatting a syntax wraps text in
+type format int
+
+const (
+	formatBold format = iota
+	formatItalic
+	formatUnderline
+	formatStrike
+	formatSpoiler
+	formatLink
+)
+
+// syntax writes the markup of one Telegram parse mode
+type syntax interface {
+	text(s string) string
+	code(s string) string
+	codeBlock(code, language string) string
+	wrap(f format, inner, url string) string
+	quote(inner string) string
+}
+
+// markdown parses CommonMark with GitHub's strikethrough
+var markdown = goldmark.New(goldmark.WithExtensions(extension.Strikethrough))
+
+// document is a parsed Markdown document and the source its nodes point into
+type document struct {
+	root   ast.Node
+	source []byte
+}
+
+func parseMarkdown(src string) *document {
+	source := []byte(src)
+	return &document{root: markdown.Parser().Parse(text.NewReader(source)), source: source}
+}
+
+var (
+	shortcodeRegex = regexp.MustCompile(`^\s*(\{\{.*\}\}|\{%.*%\})\s*$`)
+	htmlTagRegex   = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9-]*)([^>]*)>$`)
+)
+
+// inlineTags maps inline HTML elements to the formatting they keep
+var inlineTags = map[string]format{
+	"b": formatBold, "strong": formatBold,
+	"i": formatItalic, "em": formatItalic,
+	"u": formatUnderline, "ins": formatUnderline,
+	"s": formatStrike, "del": formatStrike, "strike": formatStrike,
+	"tg-spoiler": formatSpoiler,
+}
+
+// renderer writes a Markdown document with a syntax. It stops after budget characters of text,
+// so that the message can be shortened without breaking its markup. Images, thematic breaks,
+// HTML blocks and Zola shortcodes on their own are dropped.
+type renderer struct {
+	syntax    syntax
+	source    []byte
+	budget    int // Characters of text left, negative for no limit
+	truncated bool
+}
+
+func newRenderer(parseMode string, budget int) *renderer {
+	var s syntax = htmlSyntax{}
+	if parseMode == ParseModeMarkdownV2 {
+		s = markdownV2Syntax{}
+	}
+	return &renderer{syntax: s, budget: budget}
+}
+
+// render writes the blocks of doc
+func (r *renderer) render(doc *document) string {
+	r.source = doc.source
+	return r.blocks(doc.root)
+}
+
+// take returns the part of s that fits the budget
+func (r *renderer) take(s string) string {
+	switch {
+	case r.truncated:
+		return ""
+	case r.budget < 0:
+		return s
+	}
+
+	n := utf8.RuneCountInString(s)
+	if n <= r.budget {
+		r.budget -= n
+		return s
+	}
+
+	runes := []rune(s)
+	cut := strings.TrimRight(string(runes[:r.budget]), " \n")
+	r.budget = 0
+	r.truncated = true
+	return cut + ellipsis
+}
+
+func (r *renderer) blocks(parent ast.Node) string {
+	var sb strings.Builder
+	for block := parent.FirstChild(); block != nil; block = block.NextSibling() {
+		out := r.block(block)
+		if out == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(out)
+		if r.truncated {
+			break
+		}
+	}
+	return sb.String()
+}
+
+func (r *renderer) block(n ast.Node) string {
+	if r.truncated {
+		return ""
+	}
+
+	switch n := n.(type) {
+	case *ast.Heading:
+		inner := r.inlines(n)
+		if inner == "" {
+			return ""
+		}
+		return r.syntax.wrap(formatBold, inner, "")
+	case *ast.FencedCodeBlock:
+		code := r.take(r.lines(n))
+		if code == "" {
+			return ""
+		}
+		return r.syntax.codeBlock(code, string(n.Language(r.source)))
+	case *ast.CodeBlock:
+		code := r.take(r.lines(n))
+		if code == "" {
+			return ""
+		}
+		return r.syntax.codeBlock(code, "")
+	case *ast.Blockquote:
+		inner := r.blocks(n)
+		if inner == "" {
+			return ""
+		}
+		return r.syntax.quote(inner)
+	case *ast.List:
+		return r.list(n, "")
+	case *ast.Paragraph, *ast.TextBlock:
+		if shortcodeRegex.MatchString(r.lines(n)) {
+			return ""
+		}
+		return r.inlines(n)
+	default:
+		return ""
+	}
+}
+
+// list writes the items of a list on consecutive lines, nested lists indented below their item
+func (r *renderer) list(n *ast.List, indent string) string {
+	items := make([]string, 0, n.ChildCount())
+	number := n.Start
+	for item := n.FirstChild(); item != nil && !r.truncated; item = item.NextSibling() {
+		marker := "•"
+		if n.IsOrdered() {
+			marker = strconv.Itoa(number) + "."
+			number++
+		}
+
+		var sb strings.Builder
+		sb.WriteString(r.syntax.text(r.take(indent + marker + " ")))
+		for child := item.FirstChild(); child != nil && !r.truncated; child = child.NextSibling() {
+			if nested, ok := child.(*ast.List); ok {
+				if out := r.list(nested, indent+"  "); out != "" {
+					sb.WriteString("\n" + out)
+				}
+				continue
+			}
+			if out := r.block(child); out != "" {
+				if child != item.FirstChild() {
+					sb.WriteString("\n" + r.syntax.text(r.take(indent+"  ")))
+				}
+				sb.WriteString(out)
+			}
+		}
+		items = append(items, sb.String())
+	}
+	return strings.Join(items, "\n")
+}
+
+// lines returns the source lines of a block without the final line break
+func (r *renderer) lines(n ast.Node) string {
+	var sb strings.Builder
+	lines := n.Lines()
+	for i := range lines.Len() {
+		segment := lines.At(i)
+		sb.Write(segment.Value(r.source))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (r *renderer) inlines(parent ast.Node) string {
+	var nodes []ast.Node
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		nodes = append(nodes, n)
+	}
+	return strings.TrimRight(r.inlineNodes(nodes), "\n")
+}
+
+func (r *renderer) inlineNodes(nodes []ast.Node) string {
+	var sb strings.Builder
+	for i := 0; i < len(nodes) && !r.truncated; i++ {
+		switch n := nodes[i].(type) {
+		case *ast.Text:
+			value := n.Segment.Value(r.source)
+			if n.IsRaw() {
+				sb.WriteString(r.syntax.text(r.take(string(value))))
+			} else {
+				sb.WriteString(r.syntax.text(r.take(plainText(value))))
+			}
+			switch {
+			case n.HardLineBreak():
+				sb.WriteString("\n")
+			case n.SoftLineBreak():
+				sb.WriteString(r.syntax.text(r.take(" ")))
+			}
+		case *ast.String:
+			sb.WriteString(r.syntax.text(r.take(string(n.Value))))
+		case *ast.CodeSpan:
+			if code := r.take(r.codeSpan(n)); code != "" {
+				sb.WriteString(r.syntax.code(code))
+			}
+		case *ast.Emphasis:
+			f := formatItalic
+			if n.Level >= 2 {
+				f = formatBold
+			}
+			sb.WriteString(r.wrap(f, r.inlines(n), ""))
+		case *extast.Strikethrough:
+			sb.WriteString(r.wrap(formatStrike, r.inlines(n), ""))
+		case *ast.Link:
+			sb.WriteString(r.wrap(formatLink, r.inlines(n), string(n.Destination)))
+		case *ast.AutoLink:
+			url := string(n.URL(r.source))
+			if n.AutoLinkType == ast.AutoLinkEmail {
+				url = "mailto:" + url
+			}
+			label := r.syntax.text(r.take(string(n.Label(r.source))))
+			sb.WriteString(r.wrap(formatLink, label, url))
+		case *ast.RawHTML:
+			out, end := r.htmlElement(nodes, i)
+			sb.WriteString(out)
+			i = end
+		}
+	}
+	return sb.String()
+}
+
+// wrap formats inner unless it is empty
+func (r *renderer) wrap(f format, inner, url string) string {
+	if inner == "" {
+		return ""
+	}
+	return r.syntax.wrap(f, inner, url)
+}
+
+// htmlElement writes the inline HTML tag at nodes[i]. Formatting elements keep their formatting
+// up to their end tag, a <br> is a line break and other tags are dropped, keeping their content.
+// It returns the output and the index of the last node it consumed.
+func (r *renderer) htmlElement(nodes []ast.Node, i int) (string, int) {
+	m := htmlTagRegex.FindStringSubmatch(r.rawHTML(nodes[i]))
+	if m == nil || m[1] == "/" {
+		return "", i
+	}
+	name := strings.ToLower(m[2])
+	if name == "br" {
+		return "\n", i
+	}
+
+	f, ok := inlineTags[name]
+	if name == "span" && strings.Contains(m[3], "spoiler") {
+		f, ok = formatSpoiler, true
+	}
+	if !ok && name != "code" {
+		return "", i
+	}
+
+	end := r.closingTag(nodes, i, name)
+	if end < 0 {
+		return "", i
+	}
+	inner := nodes[i+1 : end]
+	if name == "code" {
+		var code strings.Builder
+		for _, n := range inner {
+			if t, ok := n.(*ast.Text); ok {
+				code.Write(t.Segment.Value(r.source))
+				if t.SoftLineBreak() || t.HardLineBreak() {
+					code.WriteByte(' ')
+				}
+			}
+		}
+		if text := r.take(html.UnescapeString(code.String())); text != "" {
+			return r.syntax.code(text), end
+		}
+		return "", end
+	}
+	return r.wrap(f, r.inlineNodes(inner), ""), end
+}
+
+// closingTag returns the index of the end tag matching the start tag at nodes[i], or -1
+func (r *renderer) closingTag(nodes []ast.Node, i int, name string) int {
+	depth := 0
+	for j := i + 1; j < len(nodes); j++ {
+		if _, ok := nodes[j].(*ast.RawHTML); !ok {
+			continue
+		}
+		m := htmlTagRegex.FindStringSubmatch(r.rawHTML(nodes[j]))
+		if m == nil || !strings.EqualFold(m[2], name) {
+			continue
+		}
+		if m[1] == "" {
+			depth++
+		} else if depth == 0 {
+			return j
+		} else {
+			depth--
+		}
+	}
+	return -1
+}
+
+func (r *renderer) rawHTML(n ast.Node) string {
+	var sb strings.Builder
+	segments := n.(*ast.RawHTML).Segments
+	for i := range segments.Len() {
+		segment := segments.At(i)
+		sb.Write(segment.Value(r.source))
+	}
+	return sb.String()
+}
+
+// codeSpan returns the code of a code span, with line breaks as spaces
+func (r *renderer) codeSpan(n *ast.CodeSpan) string {
+	var sb strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if t, ok := c.(*ast.Text); ok {
+			sb.WriteString(strings.ReplaceAll(string(t.Segment.Value(r.source)), "\n", " "))
+		}
+	}
+	return sb.String()
+}
+
+// plainText resolves the backslash escapes and entity references of Markdown text.
+// It writes the text as goldmark writes HTML and unescapes the result.
+func plainText(value []byte) string {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	gmhtml.DefaultWriter.Write(w, value)
+	w.Flush()
+	return html.UnescapeString(buf.String())
+}
+
+// htmlSyntax writes Telegram HTML
+type htmlSyntax struct{}
+
+func (htmlSyntax) text(s string) string {
+	return html.EscapeString(s)
+}
+
+func (htmlSyntax) code(s string) string {
+	return "<code>" + html.EscapeString(s) + "</code>"
+}
+
+func (htmlSyntax) codeBlock(code, language string) string {
+	if language == "" {
+		return "<pre>" + html.EscapeString(code) + "</pre>"
+	}
+	return `<pre><code class="language-` + html.EscapeString(language) + `">` + html.EscapeString(code) + "</code></pre>"
+}
+
+func (htmlSyntax) wrap(f format, inner, url string) string {
+	switch f {
+	case formatBold:
+		return "<b>" + inner + "</b>"
+	case formatItalic:
+		return "<i>" + inner + "</i>"
+	case formatUnderline:
+		return "<u>" + inner + "</u>"
+	case formatStrike:
+		return "<s>" + inner + "</s>"
+	case formatSpoiler:
+		return "<tg-spoiler>" + inner + "</tg-spoiler>"
+	case formatLink:
+		return `<a href="` + html.EscapeString(url) + `">` + inner + "</a>"
+	}
+	return inner
+}
+
+func (htmlSyntax) quote(inner string) string {
+	return "<blockquote>" + inner + "</blockquote>"
+}
+
+// markdownV2Syntax writes Telegram MarkdownV2
+type markdownV2Syntax struct{}
+
+var (
+	markdownV2Escaper = strings.NewReplacer(
+		`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+		">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+	)
+	markdownV2CodeEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+	markdownV2URLEscaper  = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+)
+
+func (markdownV2Syntax) text(s string) string {
+	return markdownV2Escaper.Replace(s)
+}
+
+func (markdownV2Syntax) code(s string) string {
+	return "`" + markdownV2CodeEscaper.Replace(s) + "`"
+}
+
+func (markdownV2Syntax) codeBlock(code, language string) string {
+	return "```" + language + "\n" + markdownV2CodeEscaper.Replace(code) + "\n```"
+}
+
+func (markdownV2Syntax) wrap(f format, inner, url string) string {
+	switch f {
+	case formatBold:
+		return "*" + inner + "*"
+	case formatItalic:
+		return separateUnderscores("_", inner)
+	case formatUnderline:
+		return separateUnderscores("__", inner)
+	case formatStrike:
+		return "~" + inner + "~"
+	case formatSpoiler:
+		return "||" + inner + "||"
+	case formatLink:
+		return "[" + inner + "](" + markdownV2URLEscaper.Replace(url) + ")"
+	}
+	return inner
+}
+
+func (markdownV2Syntax) quote(inner string) string {
+	return ">" + strings.ReplaceAll(inner, "\n", "\n>")
+}
+
+// separateUnderscores wraps inner in italic or underline delimiters. Telegram reads a run of
+// underscores greedily, so a carriage return, which it ignores, separates them from the
+// delimiters of nested italic or underline.
+func separateUnderscores(delimiter, inner string) string {
+	open, close := delimiter, delimiter
+	if strings.HasPrefix(inner, "_") {
+		open += "\r"
+	}
+	if strings.HasSuffix(inner, "_") && !strings.HasSuffix(inner, `\_`) {
+		close = "\r" + close
+	}
+	return open + inner + close
+}
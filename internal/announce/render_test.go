@@ -0,0 +1,87 @@
+package announce
+
+import "testing"
+
+func render(src, parseMode string, budget int) string {
+	return newRenderer(parseMode, budget).render(parseMarkdown(src))
+}
+
+func TestRender_HTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{"emphasis", "Some **bold**, *italic* and ~~struck~~ text", "Some <b>bold</b>, <i>italic</i> and <s>struck</s> text"},
+		{"escaped text", "1 < 2 & 3 > 2", "1 &lt; 2 &amp; 3 &gt; 2"},
+		{"link", `[docs & more](https://example.com/?a=1&b=2)`, `<a href="https://example.com/?a=1&amp;b=2">docs &amp; more</a>`},
+		{"heading", "## Title\n\nText", "<b>Title</b>\n\nText"},
+		{"code", "Run `go <test>`", "Run <code>go &lt;test&gt;</code>"},
+		{"code block", "```go\nfmt.Println(\"<hi>\")\n```", `<pre><code class="language-go">fmt.Println(&#34;&lt;hi&gt;&#34;)</code></pre>`},
+		{"list", "- one\n- two\n\n1. first", "• one\n• two\n\n1. first"},
+		{"quote", "> quoted\n> text", "<blockquote>quoted text</blockquote>"},
+		{"inline html", "<u>under</u> <tg-spoiler>secret</tg-spoiler>", "<u>under</u> <tg-spoiler>secret</tg-spoiler>"},
+		{"images and shortcodes dropped", "![alt](a.png)\n\n{{ youtube(id=\"x\") }}\n\nText", "Text"},
+		{"setext heading", "Title\n=====\n\nText", "<b>Title</b>\n\nText"},
+		{"indented code", "Run:\n\n    go test ./...\n    go vet ./...", "Run:\n\n<pre>go test ./...\ngo vet ./...</pre>"},
+		{"nested list", "- one\n  - one a\n  - one b\n- two", "• one\n  • one a\n  • one b\n• two"},
+		{"ordered list start", "3. three\n4. four", "3. three\n4. four"},
+		{"link with brackets and emphasis", "[see *[1]*](https://example.com/a_(b))", `<a href="https://example.com/a_(b)">see <i>[1]</i></a>`},
+		{"entities and escapes", "&copy; \\*not italic\\* &amp;", "© *not italic* &amp;"},
+		{"autolinks", "<https://example.com> <me@example.com>", `<a href="https://example.com">https://example.com</a> <a href="mailto:me@example.com">me@example.com</a>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := render(tt.src, ParseModeHTML, -1); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRender_MarkdownV2(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{"escaped text", "Version 1.0 (beta)!", `Version 1\.0 \(beta\)\!`},
+		{"emphasis", "**bold** and *italic*", `*bold* and _italic_`},
+		{"nested italic in underline", "<u>*both*</u>", "__\r_both_\r__"},
+		{"link", "[a_b](https://example.com/(x))", `[a\_b](https://example.com/(x\))`},
+		{"code", "`a_b\\c`", "`a_b\\\\c`"},
+		{"code block", "```sh\necho `x`\n```", "```sh\necho \\`x\\`\n```"},
+		{"quote", "> one  \n> two", ">one\n>two"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := render(tt.src, ParseModeMarkdownV2, -1); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRender_Budget(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		budget   int
+		expected string
+	}{
+		{"fits", "Short **text**", 10, "Short <b>text</b>"},
+		{"cut inside emphasis", "Short **bold text** here", 10, "Short <b>bold…</b>"},
+		{"cut inside link", "[a long link](https://example.com)", 6, `<a href="https://example.com">a long…</a>`},
+		{"later blocks dropped", "First paragraph\n\nSecond paragraph", 8, "First pa…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := render(tt.src, ParseModeHTML, tt.budget); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
@@ -1,19 +1,79 @@
 package config
 
 import (
+	"cmp"
 	"flag"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/en9inerd/postpal/internal/git"
+)
+
+// Publishing modes
+const (
+	PublishModeDirect = "direct" // Commit to the repository branch
+	PublishModeBranch = "branch" // Push a branch per post and open a change request
+)
+
+// Telegram update delivery modes
+const (
+	TelegramModeWebhook = "webhook"
+	TelegramModePolling = "polling"
 )
 
 type Config struct {
-	Port              string
-	TelegramToken     string
-	AuthPasswordHash  string
-	AuthSessionSecret string
-	AuthSessionMaxAge int
+	Port                  string
+	TelegramToken         string
+	TelegramWebhookSecret string
+	TelegramChannel       string
+	TelegramMode          string
+	MediaGroupWait        time.Duration
+	AuthPasswordHash      string
+	AuthSessionSecret     string
+	AuthSessionMaxAge     int
+	DataDir               string
+	SiteURL               string
+	RepoDir               string
+	RepoURL               string
+	RepoBranch            string
+	RepoToken             string
+	RepoUsername          string
+	RepoSSHKey            string
+	RepoSSHKeyPassphrase  string
+	RepoKnownHosts        string
+	PostsDir              string
+	PublishBatchWait      time.Duration
+	PublishMode           string
+	GitAuthorName         string
+	GitAuthorEmail        string
+	GitSigningKey         string
+	GitSigningFormat      string
+	GitSigningPassphrase  string
+	ForgeType             string
+	ForgeAPIURL           string
+	ForgeRepository       string
+	ForgeToken            string
+	RebuildIndex          bool
+	DryRun                string
+	PrintConfig           bool
+	ConfigFile            string
+	Channels              []ChannelConfig
 }
 
+// ParseConfig builds the configuration from flags, environment variables and the optional
+// config file, in that order of precedence, and validates it.
+// Validation is skipped with --print-config so an invalid configuration can still be inspected.
 func ParseConfig(args []string, getenv func(string) string) (*Config, error) {
+	file := &fileConfig{}
+	configFile := configPath(args[1:], getenv)
+	if configFile != "" {
+		var err error
+		if file, err = loadFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+
 	getEnv := func(key, fallback string) string {
 		if v := getenv(key); v != "" {
 			return v
@@ -30,23 +90,101 @@ func ParseConfig(args []string, getenv func(string) string) (*Config, error) {
 		return fallback
 	}
 
+	getEnvDuration := func(key string, fallback time.Duration) time.Duration {
+		if v := getenv(key); v != "" {
+			if d, err := time.ParseDuration(v); err == nil {
+				return d
+			}
+		}
+		return fallback
+	}
+
 	fs := flag.NewFlagSet("app", flag.ContinueOnError)
 
-	port := fs.String("port", getEnv("APP_PORT", "8000"), "Port to listen on")
-	telegramToken := fs.String("telegram-token", getEnv("TELEGRAM_BOT_TOKEN", ""), "Telegram Bot API token")
-	authPasswordHash := fs.String("auth-password-hash", getEnv("AUTH_PASSWORD_HASH", ""), "Argon2id password hash")
-	authSessionSecret := fs.String("auth-session-secret", getEnv("AUTH_SESSION_SECRET", ""), "Session secret (base64-encoded, 32+ bytes)")
-	authSessionMaxAge := fs.Int("auth-session-max-age", getEnvInt("AUTH_SESSION_MAX_AGE", 86400), "Session duration in seconds")
+	port := fs.String("port", getEnv("APP_PORT", cmp.Or(file.Port, "8000")), "Port to listen on")
+	telegramToken := fs.String("telegram-token", getEnv("TELEGRAM_BOT_TOKEN", file.Telegram.Token), "Telegram Bot API token")
+	telegramWebhookSecret := fs.String("telegram-webhook-secret", getEnv("TELEGRAM_WEBHOOK_SECRET", file.Telegram.WebhookSecret), "Secret token expected in X-Telegram-Bot-Api-Secret-Token")
+	telegramChannel := fs.String("telegram-channel", getEnv("TELEGRAM_CHANNEL", file.Telegram.Channel), "Channel username (@channel) or ID to accept posts from")
+	telegramMode := fs.String("telegram-mode", getEnv("TELEGRAM_UPDATE_MODE", cmp.Or(file.Telegram.Mode, TelegramModeWebhook)), "How to receive updates: webhook or polling")
+	mediaGroupWait := fs.Duration("media-group-wait", getEnvDuration("MEDIA_GROUP_WAIT", cmp.Or(file.Telegram.MediaGroupWait, 2*time.Second)), "How long to wait for more album messages before publishing")
+	authPasswordHash := fs.String("auth-password-hash", getEnv("AUTH_PASSWORD_HASH", file.Auth.PasswordHash), "Argon2id password hash")
+	authSessionSecret := fs.String("auth-session-secret", getEnv("AUTH_SESSION_SECRET", file.Auth.SessionSecret), "Session secret (base64-encoded, 32+ bytes)")
+	authSessionMaxAge := fs.Int("auth-session-max-age", getEnvInt("AUTH_SESSION_MAX_AGE", cmp.Or(file.Auth.SessionMaxAge, 86400)), "Session duration in seconds")
+	dataDir := fs.String("data-dir", getEnv("DATA_DIR", cmp.Or(file.DataDir, "data")), "Directory for PostPal state files")
+	siteURL := fs.String("site-url", getEnv("SITE_URL", file.SiteURL), "Base URL of the Zola site, for links from Telegram announcements to pages")
+	repoDir := fs.String("repo-dir", getEnv("REPO_DIR", cmp.Or(file.Repo.Dir, "site")), "Local path of the Zola site repository")
+	repoURL := fs.String("repo-url", getEnv("REPO_URL", file.Repo.URL), "Clone URL of the site repository, used when repo-dir does not exist")
+	repoBranch := fs.String("repo-branch", getEnv("REPO_BRANCH", cmp.Or(file.Repo.Branch, "main")), "Branch to publish posts to")
+	repoToken := fs.String("repo-token", getEnv("REPO_TOKEN", file.Repo.Token), "Access token for pushing to the site repository")
+	repoUsername := fs.String("repo-username", getEnv("REPO_USERNAME", cmp.Or(file.Repo.Username, git.DefaultHTTPUsername)), "Username sent with the access token over HTTPS")
+	repoSSHKey := fs.String("repo-ssh-key", getEnv("REPO_SSH_KEY", file.Repo.SSHKey), "Private key file for SSH repository URLs")
+	repoSSHKeyPassphrase := fs.String("repo-ssh-key-passphrase", getEnv("REPO_SSH_KEY_PASSPHRASE", file.Repo.SSHKeyPassphrase), "Passphrase of an encrypted SSH key")
+	repoKnownHosts := fs.String("repo-known-hosts", getEnv("REPO_KNOWN_HOSTS", file.Repo.KnownHosts), "known_hosts file for verifying the SSH host (default: ~/.ssh/known_hosts)")
+	postsDir := fs.String("posts-dir", getEnv("POSTS_DIR", cmp.Or(file.Repo.PostsDir, "content/posts")), "Posts directory relative to the repository root")
+	publishBatchWait := fs.Duration("publish-batch-wait", getEnvDuration("PUBLISH_BATCH_WAIT", cmp.Or(file.Repo.BatchWait, time.Second)), "How long to wait for more changes before committing them together")
+	publishMode := fs.String("publish-mode", getEnv("PUBLISH_MODE", cmp.Or(file.Repo.PublishMode, PublishModeDirect)), "How to publish posts: direct (commit to repo-branch) or branch (a branch and change request per post)")
+	gitAuthorName := fs.String("git-author-name", getEnv("GIT_AUTHOR_NAME", cmp.Or(file.Repo.AuthorName, "PostPal")), "Author name for site commits")
+	gitAuthorEmail := fs.String("git-author-email", getEnv("GIT_AUTHOR_EMAIL", cmp.Or(file.Repo.AuthorEmail, "postpal@localhost")), "Author email for site commits")
+	gitSigningKey := fs.String("git-signing-key", getEnv("GIT_SIGNING_KEY", file.Repo.SigningKey), "Key file for signing site commits (commits are unsigned when empty)")
+	gitSigningFormat := fs.String("git-signing-format", getEnv("GIT_SIGNING_FORMAT", cmp.Or(file.Repo.SigningFormat, git.SigningFormatOpenPGP)), "Signature format of the signing key: openpgp or ssh")
+	gitSigningPassphrase := fs.String("git-signing-passphrase", getEnv("GIT_SIGNING_PASSPHRASE", file.Repo.SigningPassphrase), "Passphrase of an encrypted signing key")
+	forgeType := fs.String("forge-type", getEnv("FORGE_TYPE", file.Forge.Type), "Code hosting service for change requests in branch mode: github, gitea or gitlab")
+	forgeAPIURL := fs.String("forge-api-url", getEnv("FORGE_API_URL", file.Forge.APIURL), "API root of the forge (default: api.github.com or gitlab.com/api/v4)")
+	forgeRepository := fs.String("forge-repository", getEnv("FORGE_REPOSITORY", file.Forge.Repository), "Repository on the forge as owner/name")
+	forgeToken := fs.String("forge-token", getEnv("FORGE_TOKEN", file.Forge.Token), "Access token for opening change requests (default: repo-token)")
+	fs.String("config", configFile, "Path to a TOML config file (env: POSTPAL_CONFIG)")
+	rebuildIndex := fs.Bool("rebuild-index", false, "Rebuild the message-to-post index from existing posts and exit")
+	dryRun := fs.String("dry-run", "", "Render the post of a Telegram update read from a JSON file (- for stdin), print the files it would change and exit")
+	printConfig := fs.Bool("print-config", false, "Print the effective configuration with secrets redacted and exit")
 
 	if err := fs.Parse(args[1:]); err != nil {
 		return nil, err
 	}
 
-	return &Config{
-		Port:              *port,
-		TelegramToken:     *telegramToken,
-		AuthPasswordHash:  *authPasswordHash,
-		AuthSessionSecret: *authSessionSecret,
-		AuthSessionMaxAge: *authSessionMaxAge,
-	}, nil
+	cfg := &Config{
+		Port:                  *port,
+		TelegramToken:         *telegramToken,
+		TelegramWebhookSecret: *telegramWebhookSecret,
+		TelegramChannel:       *telegramChannel,
+		TelegramMode:          *telegramMode,
+		MediaGroupWait:        *mediaGroupWait,
+		AuthPasswordHash:      *authPasswordHash,
+		AuthSessionSecret:     *authSessionSecret,
+		AuthSessionMaxAge:     *authSessionMaxAge,
+		DataDir:               *dataDir,
+		SiteURL:               strings.TrimSuffix(*siteURL, "/"),
+		RepoDir:               *repoDir,
+		RepoURL:               *repoURL,
+		RepoBranch:            *repoBranch,
+		RepoToken:             *repoToken,
+		RepoUsername:          *repoUsername,
+		RepoSSHKey:            *repoSSHKey,
+		RepoSSHKeyPassphrase:  *repoSSHKeyPassphrase,
+		RepoKnownHosts:        *repoKnownHosts,
+		PostsDir:              *postsDir,
+		PublishBatchWait:      *publishBatchWait,
+		PublishMode:           *publishMode,
+		GitAuthorName:         *gitAuthorName,
+		GitAuthorEmail:        *gitAuthorEmail,
+		GitSigningKey:         *gitSigningKey,
+		GitSigningFormat:      *gitSigningFormat,
+		GitSigningPassphrase:  *gitSigningPassphrase,
+		ForgeType:             *forgeType,
+		ForgeAPIURL:           *forgeAPIURL,
+		ForgeRepository:       *forgeRepository,
+		ForgeToken:            cmp.Or(*forgeToken, *repoToken),
+		RebuildIndex:          *rebuildIndex,
+		DryRun:                *dryRun,
+		PrintConfig:           *printConfig,
+		ConfigFile:            configFile,
+		Channels:              file.Channels,
+	}
+
+	if !cfg.PrintConfig {
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
 }
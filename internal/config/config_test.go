@@ -0,0 +1,329 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSessionSecret = "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE=" // 32 bytes
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "postpal.toml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func envFunc(env map[string]string) func(string) string {
+	return func(key string) string { return env[key] }
+}
+
+func TestParseConfig_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `
+port = "9000"
+data_dir = "/var/lib/postpal"
+
+[telegram]
+token = "file-token"
+mode = "polling"
+media_group_wait = "5s"
+
+[auth]
+password_hash = "$argon2id$v=19$m=65536,t=3,p=2$salt$hash"
+session_secret = "`+testSessionSecret+`"
+
+[repo]
+branch = "file-branch"
+posts_dir = "content/blog"
+`)
+
+	env := map[string]string{
+		"REPO_BRANCH":        "env-branch",
+		"TELEGRAM_BOT_TOKEN": "env-token",
+	}
+	args := []string{"app", "--config", path, "--telegram-token", "flag-token"}
+
+	cfg, err := ParseConfig(args, envFunc(env))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	if cfg.TelegramToken != "flag-token" {
+		t.Errorf("expected flag to override env and file, got '%s'", cfg.TelegramToken)
+	}
+	if cfg.RepoBranch != "env-branch" {
+		t.Errorf("expected env to override file, got '%s'", cfg.RepoBranch)
+	}
+	if cfg.Port != "9000" || cfg.DataDir != "/var/lib/postpal" || cfg.PostsDir != "content/blog" {
+		t.Errorf("expected file values, got port=%s data_dir=%s posts_dir=%s", cfg.Port, cfg.DataDir, cfg.PostsDir)
+	}
+	if cfg.TelegramMode != TelegramModePolling || cfg.MediaGroupWait != 5*time.Second {
+		t.Errorf("expected telegram file values, got mode=%s wait=%s", cfg.TelegramMode, cfg.MediaGroupWait)
+	}
+	if cfg.RepoDir != "site" || cfg.AuthSessionMaxAge != 86400 {
+		t.Errorf("expected defaults for unset values, got repo_dir=%s max_age=%d", cfg.RepoDir, cfg.AuthSessionMaxAge)
+	}
+	if cfg.ConfigFile != path {
+		t.Errorf("expected config file '%s', got '%s'", path, cfg.ConfigFile)
+	}
+}
+
+func TestParseConfig_ConfigFromEnv(t *testing.T) {
+	path := writeConfigFile(t, `
+[[channels]]
+chat_id = -100123
+section = "/content/news/"
+`)
+
+	env := map[string]string{"POSTPAL_CONFIG": path}
+	cfg, err := ParseConfig([]string{"app", "--print-config"}, envFunc(env))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	if len(cfg.Channels) != 1 {
+		t.Fatalf("expected 1 channel, got %d", len(cfg.Channels))
+	}
+	channel := cfg.Channels[0]
+	if channel.Section != "content/news" || channel.Name != "-100123" || channel.TitleStrategy != "address" || !channel.Publishes() {
+		t.Errorf("expected channel defaults to be applied, got %+v", channel)
+	}
+}
+
+func TestParseConfig_ValidationErrors(t *testing.T) {
+	path := writeConfigFile(t, `
+port = "http"
+site_url = "example.com"
+
+[telegram]
+mode = "push"
+
+[repo]
+posts_dir = "../outside"
+signing_format = "x509"
+
+[[channels]]
+chat_id = -100123
+section = "content/news"
+title_strategy = "random"
+
+[[channels]]
+chat_id = -100123
+`)
+
+	_, err := ParseConfig([]string{"app", "--config=" + path}, envFunc(nil))
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+
+	for _, field := range []string{
+		`"port"`,
+		`"site_url"`,
+		`"telegram.token"`,
+		`"telegram.mode"`,
+		`"auth.password_hash"`,
+		`"auth.session_secret"`,
+		`"repo.posts_dir"`,
+		`"repo.signing_format"`,
+		`"channels[0].title_strategy"`,
+		`"channels[1].chat_id"`,
+		`"channels[1].section"`,
+	} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected error for field %s, got: %s", field, err)
+		}
+	}
+}
+
+func TestParseConfig_UnknownKey(t *testing.T) {
+	path := writeConfigFile(t, "[repo]\nbrnch = \"main\"\n")
+
+	_, err := ParseConfig([]string{"app", "--config", path}, envFunc(nil))
+	if err == nil || !strings.Contains(err.Error(), `unknown key "repo.brnch"`) {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestConfig_WriteTOMLRedactsSecrets(t *testing.T) {
+	env := map[string]string{
+		"TELEGRAM_BOT_TOKEN":  "123456:secret-token",
+		"AUTH_SESSION_SECRET": testSessionSecret,
+		"REPO_TOKEN":          "ghp_secret",
+	}
+	cfg, err := ParseConfig([]string{"app", "--print-config"}, envFunc(env))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.WriteTOML(&buf); err != nil {
+		t.Fatalf("WriteTOML failed: %v", err)
+	}
+	output := buf.String()
+
+	for _, secret := range []string{"secret-token", testSessionSecret, "ghp_secret"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("expected secret %q to be redacted, got:\n%s", secret, output)
+		}
+	}
+	if !strings.Contains(output, `token = "[redacted]"`) {
+		t.Errorf("expected redacted token, got:\n%s", output)
+	}
+	if !strings.Contains(output, `password_hash = ""`) {
+		t.Errorf("expected unset secret to stay empty, got:\n%s", output)
+	}
+
+	// The output is a valid config file
+	path := writeConfigFile(t, output)
+	if _, err := ParseConfig([]string{"app", "--config", path, "--print-config"}, envFunc(nil)); err != nil {
+		t.Errorf("expected printed config to parse, got %v", err)
+	}
+}
+
+func TestConfig_WriteTOMLRoundTripsControlCharacters(t *testing.T) {
+	cfg, err := ParseConfig([]string{"app", "--print-config"}, envFunc(nil))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	cfg.GitAuthorName = "bell\a tab\v nul\x00 del\x7f emoji 😀"
+	cfg.Channels = []ChannelConfig{
+		{ChatID: -100123, Tags: []string{`back\slash`, "line\nbreak", "\x1b[0m"}},
+		{ChatID: -100456},
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.WriteTOML(&buf); err != nil {
+		t.Fatalf("WriteTOML failed: %v", err)
+	}
+
+	path := writeConfigFile(t, buf.String())
+	loaded, err := ParseConfig([]string{"app", "--config", path, "--print-config"}, envFunc(nil))
+	if err != nil {
+		t.Fatalf("expected printed config to parse, got %v:\n%s", err, buf.String())
+	}
+	if loaded.GitAuthorName != cfg.GitAuthorName {
+		t.Errorf("expected author name %q, got %q", cfg.GitAuthorName, loaded.GitAuthorName)
+	}
+	if len(loaded.Channels) != 2 || !slices.Equal(loaded.Channels[0].Tags, cfg.Channels[0].Tags) || len(loaded.Channels[1].Tags) != 0 {
+		t.Errorf("expected the channel tags to round-trip, got %+v", loaded.Channels)
+	}
+}
+
+func TestParseConfig_SSHRepoRequiresKey(t *testing.T) {
+	env := map[string]string{
+		"TELEGRAM_BOT_TOKEN":      "123456:token",
+		"AUTH_PASSWORD_HASH":      "$argon2id$v=19$m=65536,t=3,p=2$salt$hash",
+		"AUTH_SESSION_SECRET":     testSessionSecret,
+		"TELEGRAM_WEBHOOK_SECRET": "webhook-secret",
+		"REPO_URL":                "git@gitea.example.com:owner/site.git",
+	}
+
+	_, err := ParseConfig([]string{"app"}, envFunc(env))
+	if err == nil || !strings.Contains(err.Error(), `"repo.ssh_key"`) {
+		t.Fatalf("expected repo.ssh_key error, got %v", err)
+	}
+
+	env["REPO_SSH_KEY"] = "/etc/postpal/deploy_key"
+	cfg, err := ParseConfig([]string{"app", "--repo-ssh-key-passphrase", "secret"}, envFunc(env))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	if cfg.RepoUsername != "token" || cfg.RepoSSHKeyPassphrase != "secret" {
+		t.Errorf("expected default username and passphrase flag, got %s and %s", cfg.RepoUsername, cfg.RepoSSHKeyPassphrase)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.WriteTOML(&buf); err != nil {
+		t.Fatalf("WriteTOML failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `ssh_key_passphrase = "[redacted]"`) {
+		t.Errorf("expected redacted passphrase, got:\n%s", buf.String())
+	}
+}
+
+func TestParseConfig_BranchModeRequiresForge(t *testing.T) {
+	env := map[string]string{
+		"TELEGRAM_BOT_TOKEN":      "123456:token",
+		"AUTH_PASSWORD_HASH":      "$argon2id$v=19$m=65536,t=3,p=2$salt$hash",
+		"AUTH_SESSION_SECRET":     testSessionSecret,
+		"TELEGRAM_WEBHOOK_SECRET": "webhook-secret",
+		"REPO_TOKEN":              "repo-secret",
+		"PUBLISH_MODE":            "branch",
+		"FORGE_TYPE":              "gitea",
+	}
+
+	_, err := ParseConfig([]string{"app"}, envFunc(env))
+	for _, field := range []string{`"forge.repository"`, `"forge.api_url"`} {
+		if err == nil || !strings.Contains(err.Error(), field) {
+			t.Errorf("expected %s error, got %v", field, err)
+		}
+	}
+
+	env["FORGE_REPOSITORY"] = "owner/site"
+	cfg, err := ParseConfig([]string{"app", "--forge-api-url", "https://gitea.example.com/api/v1"}, envFunc(env))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	if cfg.ForgeToken != "repo-secret" {
+		t.Errorf("expected the forge token to default to the repo token, got %q", cfg.ForgeToken)
+	}
+
+	_, err = ParseConfig([]string{"app", "--publish-mode", "pr"}, envFunc(env))
+	if err == nil || !strings.Contains(err.Error(), `"repo.publish_mode"`) {
+		t.Errorf("expected repo.publish_mode error, got %v", err)
+	}
+}
+
+func TestParseConfig_DryRunSkipsAuth(t *testing.T) {
+	env := map[string]string{"TELEGRAM_BOT_TOKEN": "123456:token"}
+
+	_, err := ParseConfig([]string{"app"}, envFunc(env))
+	if err == nil || !strings.Contains(err.Error(), `"auth.password_hash"`) {
+		t.Fatalf("expected auth.password_hash error, got %v", err)
+	}
+
+	cfg, err := ParseConfig([]string{"app", "--dry-run", "update.json"}, envFunc(env))
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	if cfg.DryRun != "update.json" {
+		t.Errorf("expected dry run update file, got %q", cfg.DryRun)
+	}
+
+	_, err = ParseConfig([]string{"app", "--dry-run", "-"}, envFunc(nil))
+	if err == nil || !strings.Contains(err.Error(), `"telegram.token"`) {
+		t.Errorf("expected telegram.token error, got %v", err)
+	}
+}
+
+func TestParseConfig_WebhookRequiresSecret(t *testing.T) {
+	env := map[string]string{
+		"TELEGRAM_BOT_TOKEN":  "123456:token",
+		"AUTH_PASSWORD_HASH":  "$argon2id$v=19$m=65536,t=3,p=2$salt$hash",
+		"AUTH_SESSION_SECRET": testSessionSecret,
+	}
+
+	_, err := ParseConfig([]string{"app"}, envFunc(env))
+	if err == nil || !strings.Contains(err.Error(), `"telegram.webhook_secret"`) {
+		t.Fatalf("expected telegram.webhook_secret error, got %v", err)
+	}
+
+	if _, err := ParseConfig([]string{"app", "--telegram-mode", "polling"}, envFunc(env)); err != nil {
+		t.Errorf("expected polling mode without a secret to be valid, got %v", err)
+	}
+
+	env["TELEGRAM_WEBHOOK_SECRET"] = "webhook-secret"
+	if _, err := ParseConfig([]string{"app"}, envFunc(env)); err != nil {
+		t.Errorf("expected webhook mode with a secret to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/en9inerd/postpal/internal/zola"
+)
+
+// ChannelConfig routes the posts of one Telegram channel to a section of the site
+type ChannelConfig struct {
+	ChatID        int64    `toml:"chat_id"`
+	Name          string   `toml:"name"`           // Channel name used in post titles, defaults to the chat ID
+	Section       string   `toml:"section"`        // Section directory relative to the repository root
+	TitleStrategy string   `toml:"title_strategy"` // One of zola.TitleStrategies, defaults to "address"
+	Tags          []string `toml:"tags"`           // Tags added to every post
+	Publish       *bool    `toml:"publish"`        // Posts are written as drafts when false, defaults to true
+}
+
+// Publishes reports whether posts of the channel are published rather than written as drafts
+func (c ChannelConfig) Publishes() bool {
+	return c.Publish == nil || *c.Publish
+}
+
+// fileConfig is the layout of the --config file.
+// Unset values fall back to the defaults of ParseConfig.
+type fileConfig struct {
+	Port    string `toml:"port"`
+	DataDir string `toml:"data_dir"`
+	SiteURL string `toml:"site_url"`
+
+	Telegram struct {
+		Token          string        `toml:"token"`
+		WebhookSecret  string        `toml:"webhook_secret"`
+		Channel        string        `toml:"channel"`
+		Mode           string        `toml:"mode"`
+		MediaGroupWait time.Duration `toml:"media_group_wait"`
+	} `toml:"telegram"`
+
+	Auth struct {
+		PasswordHash  string `toml:"password_hash"`
+		SessionSecret string `toml:"session_secret"`
+		SessionMaxAge int    `toml:"session_max_age"`
+	} `toml:"auth"`
+
+	Repo struct {
+		Dir               string        `toml:"dir"`
+		URL               string        `toml:"url"`
+		Branch            string        `toml:"branch"`
+		Token             string        `toml:"token"`
+		Username          string        `toml:"username"`
+		SSHKey            string        `toml:"ssh_key"`
+		SSHKeyPassphrase  string        `toml:"ssh_key_passphrase"`
+		KnownHosts        string        `toml:"known_hosts"`
+		PostsDir          string        `toml:"posts_dir"`
+		BatchWait         time.Duration `toml:"batch_wait"`
+		PublishMode       string        `toml:"publish_mode"`
+		AuthorName        string        `toml:"author_name"`
+		AuthorEmail       string        `toml:"author_email"`
+		SigningKey        string        `toml:"signing_key"`
+		SigningFormat     string        `toml:"signing_format"`
+		SigningPassphrase string        `toml:"signing_passphrase"`
+	} `toml:"repo"`
+
+	Forge struct {
+		Type       string `toml:"type"`
+		APIURL     string `toml:"api_url"`
+		Repository string `toml:"repository"`
+		Token      string `toml:"token"`
+	} `toml:"forge"`
+
+	Channels []ChannelConfig `toml:"channels"`
+}
+
+func loadFile(filePath string) (*fileConfig, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file fileConfig
+	meta, err := toml.Decode(string(data), &file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("failed to parse config file %s: unknown key %q", filePath, undecoded[0].String())
+	}
+
+	for i := range file.Channels {
+		normalizeChannel(&file.Channels[i])
+	}
+
+	return &file, nil
+}
+
+// normalizeChannel fills in channel defaults; the result is checked by Config.Validate
+func normalizeChannel(channel *ChannelConfig) {
+	if channel.Section != "" {
+		channel.Section = path.Clean(strings.Trim(channel.Section, "/"))
+	}
+	if channel.TitleStrategy == "" {
+		channel.TitleStrategy = zola.TitleStrategyAddress
+	}
+	if channel.Name == "" && channel.ChatID != 0 {
+		channel.Name = strconv.FormatInt(channel.ChatID, 10)
+	}
+}
+
+// configPath finds the --config flag before the flag set is defined, so the file
+// can provide defaults for the other flags
+func configPath(args []string, getenv func(string) string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return getenv("POSTPAL_CONFIG")
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const redacted = "[redacted]"
+
+// WriteTOML writes the effective configuration in the config file format, with secrets redacted
+func (c *Config) WriteTOML(w io.Writer) error {
+	var sb strings.Builder
+
+	writeString(&sb, "port", c.Port)
+	writeString(&sb, "data_dir", c.DataDir)
+	writeString(&sb, "site_url", c.SiteURL)
+
+	sb.WriteString("\n[telegram]\n")
+	writeString(&sb, "token", redact(c.TelegramToken))
+	writeString(&sb, "webhook_secret", redact(c.TelegramWebhookSecret))
+	writeString(&sb, "channel", c.TelegramChannel)
+	writeString(&sb, "mode", c.TelegramMode)
+	writeString(&sb, "media_group_wait", c.MediaGroupWait.String())
+
+	sb.WriteString("\n[auth]\n")
+	writeString(&sb, "password_hash", redact(c.AuthPasswordHash))
+	writeString(&sb, "session_secret", redact(c.AuthSessionSecret))
+	fmt.Fprintf(&sb, "session_max_age = %d\n", c.AuthSessionMaxAge)
+
+	sb.WriteString("\n[repo]\n")
+	writeString(&sb, "dir", c.RepoDir)
+	writeString(&sb, "url", redactURL(c.RepoURL))
+	writeString(&sb, "branch", c.RepoBranch)
+	writeString(&sb, "token", redact(c.RepoToken))
+	writeString(&sb, "username", c.RepoUsername)
+	writeString(&sb, "ssh_key", c.RepoSSHKey)
+	writeString(&sb, "ssh_key_passphrase", redact(c.RepoSSHKeyPassphrase))
+	writeString(&sb, "known_hosts", c.RepoKnownHosts)
+	writeString(&sb, "posts_dir", c.PostsDir)
+	writeString(&sb, "batch_wait", c.PublishBatchWait.String())
+	writeString(&sb, "publish_mode", c.PublishMode)
+	writeString(&sb, "author_name", c.GitAuthorName)
+	writeString(&sb, "author_email", c.GitAuthorEmail)
+	writeString(&sb, "signing_key", c.GitSigningKey)
+	writeString(&sb, "signing_format", c.GitSigningFormat)
+	writeString(&sb, "signing_passphrase", redact(c.GitSigningPassphrase))
+
+	sb.WriteString("\n[forge]\n")
+	writeString(&sb, "type", c.ForgeType)
+	writeString(&sb, "api_url", c.ForgeAPIURL)
+	writeString(&sb, "repository", c.ForgeRepository)
+	writeString(&sb, "token", redact(c.ForgeToken))
+
+	for _, channel := range c.Channels {
+		sb.WriteString("\n[[channels]]\n")
+		fmt.Fprintf(&sb, "chat_id = %d\n", channel.ChatID)
+		writeString(&sb, "name", channel.Name)
+		writeString(&sb, "section", channel.Section)
+		writeString(&sb, "title_strategy", channel.TitleStrategy)
+		writeValue(&sb, "tags", append([]string{}, channel.Tags...)) // Empty rather than left out
+		fmt.Fprintf(&sb, "publish = %t\n", channel.Publishes())
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// redactURL hides a password embedded in a URL
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	} else if u.User.Username() != "" && u.Scheme != "ssh" {
+		// A bare username in an HTTPS URL is usually a token
+		u.User = url.User(redacted)
+	}
+	return u.String()
+}
+
+func writeString(sb *strings.Builder, key, value string) {
+	writeValue(sb, key, value)
+}
+
+// writeValue writes a key with a string or string slice value, quoted by the TOML encoder
+func writeValue(sb *strings.Builder, key string, value any) {
+	// Encoding strings into a strings.Builder cannot fail
+	_ = toml.NewEncoder(sb).Encode(map[string]any{key: value})
+}
+
+// redact hides a secret while still showing whether it is set
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/en9inerd/go-pkgs/validator"
+	"github.com/en9inerd/postpal/internal/forge"
+	"github.com/en9inerd/postpal/internal/git"
+	"github.com/en9inerd/postpal/internal/zola"
+)
+
+// ValidationError reports the configuration fields that failed validation.
+// Fields are named after their config file keys, e.g. "telegram.mode" or "channels[0].section".
+type ValidationError struct {
+	Validator *validator.Validator
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration: %s", e.Validator.JSON())
+}
+
+// Validate checks the configuration, returning a *ValidationError listing every invalid field
+func (c *Config) Validate() error {
+	v := &validator.Validator{}
+	c.validate(v)
+	if !v.Valid() {
+		return &ValidationError{Validator: v}
+	}
+	return nil
+}
+
+func (c *Config) validate(v *validator.Validator) {
+	port, err := strconv.Atoi(c.Port)
+	v.CheckField(err == nil && port > 0 && port <= 65535, "port", "port must be a number between 1 and 65535")
+	v.CheckField(validator.NotBlank(c.DataDir), "data_dir", "data_dir is required")
+	if c.SiteURL != "" {
+		site, err := url.Parse(c.SiteURL)
+		v.CheckField(err == nil && (site.Scheme == "http" || site.Scheme == "https") && site.Host != "", "site_url", "site_url must be an http or https URL")
+	}
+
+	// Rebuilding the index only touches the site repository
+	if !c.RebuildIndex {
+		v.CheckField(validator.NotBlank(c.TelegramToken), "telegram.token", "telegram.token is required")
+	}
+
+	// Neither rebuilding the index nor a dry run serves the web interface
+	if !c.RebuildIndex && c.DryRun == "" {
+		v.CheckField(validator.NotBlank(c.AuthPasswordHash), "auth.password_hash", "auth.password_hash is required")
+		if c.AuthPasswordHash != "" {
+			v.CheckField(strings.HasPrefix(c.AuthPasswordHash, "$argon2id$"), "auth.password_hash", "auth.password_hash must be an Argon2id hash")
+		}
+		secret, err := base64.StdEncoding.DecodeString(c.AuthSessionSecret)
+		v.CheckField(err == nil && len(secret) >= 32, "auth.session_secret", "auth.session_secret must be base64-encoded and at least 32 bytes")
+		v.CheckField(c.AuthSessionMaxAge > 0, "auth.session_max_age", "auth.session_max_age must be positive")
+	}
+
+	v.CheckField(validator.PermittedValue(c.TelegramMode, TelegramModeWebhook, TelegramModePolling), "telegram.mode", "telegram.mode must be webhook or polling")
+	// The webhook only accepts updates carrying the secret, so without one no post gets through
+	if c.TelegramMode == TelegramModeWebhook && !c.RebuildIndex && c.DryRun == "" {
+		v.CheckField(validator.NotBlank(c.TelegramWebhookSecret), "telegram.webhook_secret", "telegram.webhook_secret is required in webhook mode")
+	}
+	v.CheckField(c.MediaGroupWait >= 0, "telegram.media_group_wait", "telegram.media_group_wait must not be negative")
+
+	v.CheckField(validator.NotBlank(c.RepoDir), "repo.dir", "repo.dir is required")
+	v.CheckField(validator.NotBlank(c.RepoBranch), "repo.branch", "repo.branch is required")
+	if git.IsSSHURL(c.RepoURL) {
+		v.CheckField(validator.NotBlank(c.RepoSSHKey), "repo.ssh_key", "repo.ssh_key is required for an SSH repository URL")
+	}
+	v.CheckField(isRelativeDir(c.PostsDir), "repo.posts_dir", "repo.posts_dir must be a directory inside the repository")
+	v.CheckField(c.PublishBatchWait >= 0, "repo.batch_wait", "repo.batch_wait must not be negative")
+	v.CheckField(validator.PermittedValue(c.PublishMode, PublishModeDirect, PublishModeBranch), "repo.publish_mode", "repo.publish_mode must be direct or branch")
+	v.CheckField(validator.NotBlank(c.GitAuthorName), "repo.author_name", "repo.author_name is required")
+	v.CheckField(strings.Contains(c.GitAuthorEmail, "@"), "repo.author_email", "repo.author_email must be an email address")
+	v.CheckField(validator.PermittedValue(c.GitSigningFormat, git.SigningFormats...), "repo.signing_format",
+		"repo.signing_format must be one of "+strings.Join(git.SigningFormats, ", "))
+
+	if c.PublishMode == PublishModeBranch {
+		v.CheckField(validator.PermittedValue(c.ForgeType, forge.Types...), "forge.type",
+			"forge.type must be one of "+strings.Join(forge.Types, ", ")+" in branch mode")
+		owner, name, ok := strings.Cut(c.ForgeRepository, "/")
+		v.CheckField(ok && owner != "" && name != "", "forge.repository", "forge.repository must be owner/name in branch mode")
+		if c.ForgeType == forge.TypeGitea {
+			v.CheckField(validator.NotBlank(c.ForgeAPIURL), "forge.api_url", "forge.api_url is required for gitea")
+		}
+	}
+
+	seen := make(map[int64]int)
+	for i, channel := range c.Channels {
+		field := fmt.Sprintf("channels[%d]", i)
+
+		v.CheckField(channel.ChatID != 0, field+".chat_id", "chat_id is required")
+		if first, ok := seen[channel.ChatID]; ok && channel.ChatID != 0 {
+			v.CheckField(false, field+".chat_id", fmt.Sprintf("chat_id is already configured by channels[%d]", first))
+		} else {
+			seen[channel.ChatID] = i
+		}
+
+		v.CheckField(isRelativeDir(channel.Section), field+".section", "section must be a directory inside the repository")
+		v.CheckField(validator.PermittedValue(channel.TitleStrategy, zola.TitleStrategies...), field+".title_strategy",
+			"title_strategy must be one of "+strings.Join(zola.TitleStrategies, ", "))
+	}
+}
+
+// isRelativeDir reports whether dir is a non-empty path that stays inside the repository
+func isRelativeDir(dir string) bool {
+	if strings.TrimSpace(dir) == "" || path.IsAbs(dir) {
+		return false
+	}
+	cleaned := path.Clean(dir)
+	return cleaned != "." && cleaned != ".." && !strings.HasPrefix(cleaned, "../")
+}
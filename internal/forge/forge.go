@@ -0,0 +1,148 @@
+// Package forge opens change requests (pull or merge requests) on the code hosting
+// service of the site repository.
+package forge
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Supported forge types
+const (
+	TypeGitHub = "github"
+	TypeGitea  = "gitea"
+	TypeGitLab = "gitlab"
+)
+
+// Types lists the supported forge types
+var Types = []string{TypeGitHub, TypeGitea, TypeGitLab}
+
+// ChangeRequest describes a pull or merge request from Branch into Base
+type ChangeRequest struct {
+	Branch string
+	Base   string
+	Title  string
+	Body   string
+}
+
+// ChangeRequester opens change requests.
+// Opening a change request for a branch that already has an open one returns the existing one.
+type ChangeRequester interface {
+	OpenChangeRequest(ctx context.Context, cr ChangeRequest) (url string, err error)
+}
+
+// errExists is returned by a forge's create call when the branch already has an open change request
+var errExists = errors.New("change request already exists")
+
+// New returns the ChangeRequester for a forge type. repository is "owner/name" (a project path
+// such as "group/subgroup/name" on GitLab). apiURL may be empty for GitHub and GitLab to use their
+// public instances.
+func New(forgeType, apiURL, repository, token string) (ChangeRequester, error) {
+	owner, name, ok := strings.Cut(repository, "/")
+	if !ok || owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository %q, expected owner/name", repository)
+	}
+
+	client := &client{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		token:      token,
+	}
+
+	switch forgeType {
+	case TypeGitHub:
+		client.baseURL = strings.TrimSuffix(cmp.Or(apiURL, "https://api.github.com"), "/")
+		client.authorize = func(req *http.Request, token string) {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		client.exists = http.StatusUnprocessableEntity
+		return &GitHub{client: client, owner: owner, repo: name}, nil
+	case TypeGitea:
+		if apiURL == "" {
+			return nil, errors.New("an API URL is required for Gitea")
+		}
+		client.baseURL = strings.TrimSuffix(apiURL, "/")
+		client.authorize = func(req *http.Request, token string) {
+			req.Header.Set("Authorization", "token "+token)
+		}
+		client.exists = http.StatusConflict
+		return &Gitea{client: client, owner: owner, repo: name}, nil
+	case TypeGitLab:
+		client.baseURL = strings.TrimSuffix(cmp.Or(apiURL, "https://gitlab.com/api/v4"), "/")
+		client.authorize = func(req *http.Request, token string) {
+			req.Header.Set("PRIVATE-TOKEN", token)
+		}
+		client.exists = http.StatusConflict
+		return &GitLab{client: client, project: repository}, nil
+	default:
+		return nil, fmt.Errorf("unknown forge type %q", forgeType)
+	}
+}
+
+// client makes JSON requests to a forge API
+type client struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+	// authorize sets the forge-specific authentication header
+	authorize func(req *http.Request, token string)
+	// exists is the status code the forge answers a create call with when
+	// the branch already has an open change request
+	exists int
+}
+
+// do sends a JSON request and decodes the JSON response into out
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" && c.authorize != nil {
+		c.authorize(req, c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode == c.exists && method == http.MethodPost {
+		return errExists
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeForge is an httptest double of a forge's change request endpoints. It keeps
+// the open change requests by branch and rejects duplicates like the real API.
+type fakeForge struct {
+	t        *testing.T
+	path     string
+	header   string
+	token    string
+	exists   int
+	created  []map[string]string
+	open     map[string]string
+	newURL   func(n int) string
+	listJSON func(branch, url string) any
+	listHit  bool
+}
+
+func (f *fakeForge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.EscapedPath() != f.path {
+		http.NotFound(w, r)
+		return
+	}
+	if got := r.Header.Get(f.header); got != f.token {
+		f.t.Errorf("expected %s header %q, got %q", f.header, f.token, got)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			f.t.Errorf("failed to decode request: %v", err)
+		}
+		f.created = append(f.created, body)
+
+		branch := body["head"] + body["source_branch"]
+		if _, ok := f.open[branch]; ok {
+			w.WriteHeader(f.exists)
+			w.Write([]byte(`{"message":"already exists"}`))
+			return
+		}
+		f.open[branch] = f.newURL(len(f.open) + 1)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(f.listJSON(branch, f.open[branch]))
+	case http.MethodGet:
+		f.listHit = true
+		branch := r.URL.Query().Get("source_branch")
+		if head := r.URL.Query().Get("head"); head != "" {
+			_, branch, _ = strings.Cut(head, ":")
+		}
+		var list []any
+		for b, url := range f.open {
+			if branch == "" || b == branch {
+				list = append(list, f.listJSON(b, url))
+			}
+		}
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+func testChangeRequester(t *testing.T, requester ChangeRequester, fake *fakeForge, expectedURL string) {
+	t.Helper()
+	ctx := context.Background()
+
+	cr := ChangeRequest{Branch: "postpal/post-1", Base: "main", Title: "Add post: 1", Body: "From Telegram"}
+	url, err := requester.OpenChangeRequest(ctx, cr)
+	if err != nil {
+		t.Fatalf("OpenChangeRequest failed: %v", err)
+	}
+	if url != expectedURL {
+		t.Errorf("expected URL %s, got %s", expectedURL, url)
+	}
+	if fake.listHit {
+		t.Error("expected no lookup for a new change request")
+	}
+
+	// A later post edit pushes to the same branch; the open change request is reused
+	url, err = requester.OpenChangeRequest(ctx, cr)
+	if err != nil {
+		t.Fatalf("OpenChangeRequest for existing branch failed: %v", err)
+	}
+	if url != expectedURL {
+		t.Errorf("expected existing URL %s, got %s", expectedURL, url)
+	}
+	if !fake.listHit {
+		t.Error("expected the existing change request to be looked up")
+	}
+	if len(fake.created) != 2 {
+		t.Fatalf("expected 2 create calls, got %d", len(fake.created))
+	}
+}
+
+func TestGitHub_OpenChangeRequest(t *testing.T) {
+	fake := &fakeForge{
+		t:      t,
+		path:   "/repos/owner/site/pulls",
+		header: "Authorization",
+		token:  "Bearer secret",
+		exists: http.StatusUnprocessableEntity,
+		open:   map[string]string{},
+		newURL: func(n int) string { return fmt.Sprintf("https://github.com/owner/site/pull/%d", n) },
+		listJSON: func(branch, url string) any {
+			return map[string]any{"html_url": url}
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	requester, err := New(TypeGitHub, server.URL, "owner/site", "secret")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	testChangeRequester(t, requester, fake, "https://github.com/owner/site/pull/1")
+
+	if body := fake.created[0]; body["head"] != "postpal/post-1" || body["base"] != "main" || body["title"] != "Add post: 1" || body["body"] != "From Telegram" {
+		t.Errorf("unexpected pull request body: %v", body)
+	}
+}
+
+func TestGitea_OpenChangeRequest(t *testing.T) {
+	fake := &fakeForge{
+		t:      t,
+		path:   "/api/v1/repos/owner/site/pulls",
+		header: "Authorization",
+		token:  "token secret",
+		exists: http.StatusConflict,
+		open:   map[string]string{"postpal/post-0": "https://gitea.example.com/owner/site/pulls/0"},
+		newURL: func(n int) string { return "https://gitea.example.com/owner/site/pulls/1" },
+		listJSON: func(branch, url string) any {
+			return map[string]any{
+				"html_url": url,
+				"head":     map[string]string{"ref": branch},
+				"base":     map[string]string{"ref": "main"},
+			}
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	requester, err := New(TypeGitea, server.URL+"/api/v1/", "owner/site", "secret")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	testChangeRequester(t, requester, fake, "https://gitea.example.com/owner/site/pulls/1")
+}
+
+func TestGitLab_OpenChangeRequest(t *testing.T) {
+	fake := &fakeForge{
+		t:      t,
+		path:   "/api/v4/projects/group%2Fsub%2Fsite/merge_requests",
+		header: "PRIVATE-TOKEN",
+		token:  "secret",
+		exists: http.StatusConflict,
+		open:   map[string]string{},
+		newURL: func(n int) string { return "https://gitlab.example.com/group/sub/site/-/merge_requests/1" },
+		listJSON: func(branch, url string) any {
+			return map[string]any{"web_url": url}
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	requester, err := New(TypeGitLab, server.URL+"/api/v4", "group/sub/site", "secret")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	testChangeRequester(t, requester, fake, "https://gitlab.example.com/group/sub/site/-/merge_requests/1")
+
+	if body := fake.created[0]; body["source_branch"] != "postpal/post-1" || body["target_branch"] != "main" || body["description"] != "From Telegram" {
+		t.Errorf("unexpected merge request body: %v", body)
+	}
+}
+
+func TestOpenChangeRequest_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+	}))
+	defer server.Close()
+
+	requester, _ := New(TypeGitHub, server.URL, "owner/site", "secret")
+	_, err := requester.OpenChangeRequest(context.Background(), ChangeRequest{Branch: "postpal/post-1", Base: "main"})
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "not accessible") {
+		t.Errorf("expected the API error to be reported, got %v", err)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []struct {
+		forgeType  string
+		apiURL     string
+		repository string
+		expected   string
+	}{
+		{TypeGitHub, "", "site", "invalid repository"},
+		{TypeGitea, "", "owner/site", "an API URL is required"},
+		{"bitbucket", "", "owner/site", "unknown forge type"},
+	}
+
+	for _, tt := range tests {
+		if _, err := New(tt.forgeType, tt.apiURL, tt.repository, ""); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.forgeType, tt.expected, err)
+		}
+	}
+}
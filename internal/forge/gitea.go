@@ -0,0 +1,57 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Gitea opens pull requests through the Gitea (or Forgejo) API
+type Gitea struct {
+	client *client
+	owner  string
+	repo   string
+}
+
+type giteaPull struct {
+	HTMLURL string `json:"html_url"`
+	Head    struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// OpenChangeRequest opens a pull request, or returns the open one for the branch
+func (g *Gitea) OpenChangeRequest(ctx context.Context, cr ChangeRequest) (string, error) {
+	path := fmt.Sprintf("/repos/%s/%s/pulls", url.PathEscape(g.owner), url.PathEscape(g.repo))
+
+	var pull giteaPull
+	err := g.client.do(ctx, http.MethodPost, path, map[string]string{
+		"title": cr.Title,
+		"body":  cr.Body,
+		"head":  cr.Branch,
+		"base":  cr.Base,
+	}, &pull)
+	if err == nil {
+		return pull.HTMLURL, nil
+	}
+	if !errors.Is(err, errExists) {
+		return "", fmt.Errorf("failed to open pull request: %w", err)
+	}
+
+	// The list endpoint cannot filter by head branch on older versions
+	var pulls []giteaPull
+	if err := g.client.do(ctx, http.MethodGet, path+"?state=open&limit=50", nil, &pulls); err != nil {
+		return "", fmt.Errorf("failed to find pull request: %w", err)
+	}
+	for _, pull := range pulls {
+		if pull.Head.Ref == cr.Branch && pull.Base.Ref == cr.Base {
+			return pull.HTMLURL, nil
+		}
+	}
+	return "", fmt.Errorf("failed to open pull request for %s", cr.Branch)
+}
@@ -0,0 +1,49 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// GitHub opens pull requests through the GitHub REST API
+type GitHub struct {
+	client *client
+	owner  string
+	repo   string
+}
+
+type githubPull struct {
+	HTMLURL string `json:"html_url"`
+}
+
+// OpenChangeRequest opens a pull request, or returns the open one for the branch
+func (g *GitHub) OpenChangeRequest(ctx context.Context, cr ChangeRequest) (string, error) {
+	path := fmt.Sprintf("/repos/%s/%s/pulls", url.PathEscape(g.owner), url.PathEscape(g.repo))
+
+	var pull githubPull
+	err := g.client.do(ctx, http.MethodPost, path, map[string]string{
+		"title": cr.Title,
+		"body":  cr.Body,
+		"head":  cr.Branch,
+		"base":  cr.Base,
+	}, &pull)
+	if err == nil {
+		return pull.HTMLURL, nil
+	}
+	if !errors.Is(err, errExists) {
+		return "", fmt.Errorf("failed to open pull request: %w", err)
+	}
+
+	query := url.Values{"head": {g.owner + ":" + cr.Branch}, "base": {cr.Base}, "state": {"open"}}
+	var pulls []githubPull
+	if err := g.client.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &pulls); err != nil {
+		return "", fmt.Errorf("failed to find pull request: %w", err)
+	}
+	if len(pulls) == 0 {
+		return "", fmt.Errorf("failed to open pull request for %s", cr.Branch)
+	}
+	return pulls[0].HTMLURL, nil
+}
@@ -0,0 +1,48 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// GitLab opens merge requests through the GitLab REST API
+type GitLab struct {
+	client  *client
+	project string
+}
+
+type gitlabMergeRequest struct {
+	WebURL string `json:"web_url"`
+}
+
+// OpenChangeRequest opens a merge request, or returns the open one for the branch
+func (g *GitLab) OpenChangeRequest(ctx context.Context, cr ChangeRequest) (string, error) {
+	path := fmt.Sprintf("/projects/%s/merge_requests", url.PathEscape(g.project))
+
+	var mr gitlabMergeRequest
+	err := g.client.do(ctx, http.MethodPost, path, map[string]string{
+		"source_branch": cr.Branch,
+		"target_branch": cr.Base,
+		"title":         cr.Title,
+		"description":   cr.Body,
+	}, &mr)
+	if err == nil {
+		return mr.WebURL, nil
+	}
+	if !errors.Is(err, errExists) {
+		return "", fmt.Errorf("failed to open merge request: %w", err)
+	}
+
+	query := url.Values{"source_branch": {cr.Branch}, "target_branch": {cr.Base}, "state": {"opened"}}
+	var mrs []gitlabMergeRequest
+	if err := g.client.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &mrs); err != nil {
+		return "", fmt.Errorf("failed to find merge request: %w", err)
+	}
+	if len(mrs) == 0 {
+		return "", fmt.Errorf("failed to open merge request for %s", cr.Branch)
+	}
+	return mrs[0].WebURL, nil
+}
@@ -0,0 +1,114 @@
+package git
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/plumbing/transport/http"
+	"github.com/go-git/go-git/v6/plumbing/transport/ssh"
+)
+
+// DefaultHTTPUsername is the username sent with the access token over HTTPS.
+// GitHub and Gitea accept any username; GitLab expects "oauth2" for OAuth tokens.
+const DefaultHTTPUsername = "token"
+
+// defaultSSHUser is used when an SSH URL does not name a user
+const defaultSSHUser = "git"
+
+// WithHTTPUsername sets the username sent with the access token over HTTPS
+func (s *Service) WithHTTPUsername(username string) *Service {
+	s.httpUsername = username
+	return s
+}
+
+// WithSSHKey sets the private key file used for SSH repository URLs, and its passphrase if the key is encrypted
+func (s *Service) WithSSHKey(keyFile, passphrase string) *Service {
+	s.sshKeyFile = keyFile
+	s.sshKeyPassphrase = passphrase
+	return s
+}
+
+// WithKnownHosts sets the known_hosts file used to verify SSH host keys.
+// Without it, SSH_KNOWN_HOSTS or ~/.ssh/known_hosts and /etc/ssh/ssh_known_hosts are used.
+func (s *Service) WithKnownHosts(knownHostsFile string) *Service {
+	s.knownHostsFile = knownHostsFile
+	return s
+}
+
+// IsSSHURL reports whether a repository URL uses SSH, either as ssh://host/path
+// or in the scp-like form user@host:path
+func IsSSHURL(rawURL string) bool {
+	if scheme, _, ok := strings.Cut(rawURL, "://"); ok {
+		return scheme == "ssh" || scheme == "git+ssh"
+	}
+
+	// scp-like syntax has a colon before the first slash
+	host, _, ok := strings.Cut(rawURL, ":")
+	return ok && host != "" && !strings.Contains(host, "/")
+}
+
+// auth returns the credentials for the remote, or nil for anonymous access.
+// The method is chosen by the URL of the repository's origin, or of repoURL when cloning (repo is nil).
+func (s *Service) auth(repo *git.Repository) (transport.AuthMethod, error) {
+	remoteURL := s.repoURL
+	if repo != nil {
+		if remote, err := repo.Remote("origin"); err == nil && len(remote.Config().URLs) > 0 {
+			remoteURL = remote.Config().URLs[0]
+		}
+	}
+
+	if IsSSHURL(remoteURL) {
+		return s.sshAuth(remoteURL)
+	}
+
+	if s.authToken == "" {
+		return nil, nil
+	}
+	return &http.BasicAuth{
+		Username: cmp.Or(s.httpUsername, DefaultHTTPUsername),
+		Password: s.authToken,
+	}, nil
+}
+
+// sshAuth authenticates with the configured private key and verifies the host against known_hosts
+func (s *Service) sshAuth(remoteURL string) (transport.AuthMethod, error) {
+	if s.sshKeyFile == "" {
+		return nil, fmt.Errorf("an SSH key is required for repository URL %s", remoteURL)
+	}
+
+	auth, err := ssh.NewPublicKeysFromFile(sshUser(remoteURL), s.sshKeyFile, s.sshKeyPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SSH key %s: %w", s.sshKeyFile, err)
+	}
+
+	var knownHosts []string
+	if s.knownHostsFile != "" {
+		knownHosts = append(knownHosts, s.knownHostsFile)
+	}
+	auth.HostKeyCallback, err = ssh.NewKnownHostsCallback(knownHosts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known hosts: %w", err)
+	}
+
+	return auth, nil
+}
+
+// sshUser returns the user named by an SSH URL
+func sshUser(rawURL string) string {
+	if strings.Contains(rawURL, "://") {
+		if u, err := url.Parse(rawURL); err == nil && u.User != nil {
+			return cmp.Or(u.User.Username(), defaultSSHUser)
+		}
+		return defaultSSHUser
+	}
+
+	host, _, _ := strings.Cut(rawURL, ":")
+	if user, _, ok := strings.Cut(host, "@"); ok && user != "" {
+		return user
+	}
+	return defaultSSHUser
+}
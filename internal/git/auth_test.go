@@ -0,0 +1,191 @@
+package git
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v6/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v6/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestIsSSHURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"git@github.com:owner/site.git", true},
+		{"deploy@gitea.example.com:owner/site.git", true},
+		{"ssh://git@gitlab.example.com:2222/owner/site.git", true},
+		{"git+ssh://gitlab.example.com/owner/site.git", true},
+		{"https://github.com/owner/site.git", false},
+		{"file:///srv/git/site.git", false},
+		{"/srv/git/site.git", false},
+		{"./site.git", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsSSHURL(tt.url); got != tt.expected {
+			t.Errorf("IsSSHURL(%q): expected %t, got %t", tt.url, tt.expected, got)
+		}
+	}
+}
+
+func TestSSHUser(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"git@github.com:owner/site.git", "git"},
+		{"deploy@gitea.example.com:owner/site.git", "deploy"},
+		{"gitea.example.com:owner/site.git", "git"},
+		{"ssh://gitlab@gitlab.example.com:2222/owner/site.git", "gitlab"},
+		{"ssh://gitlab.example.com/owner/site.git", "git"},
+	}
+
+	for _, tt := range tests {
+		if got := sshUser(tt.url); got != tt.expected {
+			t.Errorf("sshUser(%q): expected %q, got %q", tt.url, tt.expected, got)
+		}
+	}
+}
+
+func TestService_Auth_HTTP(t *testing.T) {
+	service := NewService(t.TempDir(), "https://gitlab.example.com/owner/site.git", "main", "secret", Author{})
+
+	auth, err := service.auth(nil)
+	if err != nil {
+		t.Fatalf("auth failed: %v", err)
+	}
+	basic, ok := auth.(*http.BasicAuth)
+	if !ok || basic.Username != DefaultHTTPUsername || basic.Password != "secret" {
+		t.Errorf("expected basic auth with the default username, got %#v", auth)
+	}
+
+	service.WithHTTPUsername("oauth2")
+	auth, _ = service.auth(nil)
+	if basic := auth.(*http.BasicAuth); basic.Username != "oauth2" {
+		t.Errorf("expected username oauth2, got %s", basic.Username)
+	}
+
+	// Anonymous access without a token
+	anonymous := NewService(t.TempDir(), "https://github.com/owner/site.git", "main", "", Author{})
+	if auth, err := anonymous.auth(nil); auth != nil || err != nil {
+		t.Errorf("expected no auth without a token, got %v (%v)", auth, err)
+	}
+}
+
+// writeSSHKey generates an ed25519 key, optionally encrypted, and returns its path and public key
+func writeSSHKey(t *testing.T, passphrase string) (string, ssh.PublicKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(priv, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	publicKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to convert public key: %v", err)
+	}
+	return path, publicKey
+}
+
+func TestService_Auth_SSH(t *testing.T) {
+	keyFile, _ := writeSSHKey(t, "correct horse")
+	_, hostKey := writeSSHKey(t, "")
+	_, otherKey := writeSSHKey(t, "")
+
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{"gitea.example.com"}, hostKey) + "\n"
+	if err := os.WriteFile(knownHostsFile, []byte(line), 0644); err != nil {
+		t.Fatalf("failed to write known_hosts: %v", err)
+	}
+
+	service := NewService(t.TempDir(), "deploy@gitea.example.com:owner/site.git", "main", "ignored-token", Author{}).
+		WithSSHKey(keyFile, "correct horse").
+		WithKnownHosts(knownHostsFile)
+
+	auth, err := service.auth(nil)
+	if err != nil {
+		t.Fatalf("auth failed: %v", err)
+	}
+	keys, ok := auth.(*gitssh.PublicKeys)
+	if !ok {
+		t.Fatalf("expected SSH public key auth, got %T", auth)
+	}
+	if keys.User != "deploy" {
+		t.Errorf("expected user deploy from the URL, got %s", keys.User)
+	}
+
+	addr := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}
+	if err := keys.HostKeyCallback("gitea.example.com:22", addr, hostKey); err != nil {
+		t.Errorf("expected known host key to be accepted, got %v", err)
+	}
+	if err := keys.HostKeyCallback("gitea.example.com:22", addr, otherKey); err == nil {
+		t.Error("expected unknown host key to be rejected")
+	}
+}
+
+func TestService_Auth_SSHErrors(t *testing.T) {
+	keyFile, _ := writeSSHKey(t, "correct horse")
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(knownHostsFile, nil, 0644); err != nil {
+		t.Fatalf("failed to write known_hosts: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		service  *Service
+		expected string
+	}{
+		{
+			name:     "missing key",
+			service:  NewService(t.TempDir(), "git@github.com:owner/site.git", "main", "", Author{}),
+			expected: "an SSH key is required",
+		},
+		{
+			name: "wrong passphrase",
+			service: NewService(t.TempDir(), "git@github.com:owner/site.git", "main", "", Author{}).
+				WithSSHKey(keyFile, "wrong").
+				WithKnownHosts(knownHostsFile),
+			expected: "failed to load SSH key",
+		},
+		{
+			name: "missing known_hosts",
+			service: NewService(t.TempDir(), "git@github.com:owner/site.git", "main", "", Author{}).
+				WithSSHKey(keyFile, "correct horse").
+				WithKnownHosts(filepath.Join(t.TempDir(), "missing")),
+			expected: "failed to load known hosts",
+		},
+	}
+
+	for _, tt := range tests {
+		if _, err := tt.service.auth(nil); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
@@ -2,18 +2,36 @@ package git
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v6"
 	"github.com/go-git/go-git/v6/config"
 	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
 	"github.com/go-git/go-git/v6/plumbing/object"
-	"github.com/go-git/go-git/v6/plumbing/transport/http"
+	"github.com/go-git/go-git/v6/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v6/plumbing/storer"
 )
 
+// ErrNoChanges is returned by Commit when there is nothing staged to commit
+var ErrNoChanges = errors.New("no changes to commit")
+
+// ErrConflict is returned by Push when local commits and the remote branch change the same files
+var ErrConflict = errors.New("local changes conflict with the remote branch")
+
+// errNoRemoteBranch is returned by fetch when the remote does not have the branch
+var errNoRemoteBranch = errors.New("remote branch does not exist")
+
+// maxPushAttempts bounds how often Push rebases onto a remote branch that keeps moving
+const maxPushAttempts = 3
+
 // Author represents Git author information
 type Author struct {
 	Name  string
@@ -27,6 +45,15 @@ type Service struct {
 	branch    string
 	authToken string
 	author    Author
+
+	httpUsername     string
+	sshKeyFile       string
+	sshKeyPassphrase string
+	knownHostsFile   string
+	signer           git.Signer
+
+	// beforePush is called between the rebase and each push attempt, so tests can move the remote
+	beforePush func()
 }
 
 // NewService creates a new Git service
@@ -37,6 +64,8 @@ func NewService(repoDir, repoURL, branch, authToken string, author Author) *Serv
 		branch:    branch,
 		authToken: authToken,
 		author:    author,
+
+		httpUsername: DefaultHTTPUsername,
 	}
 }
 
@@ -48,12 +77,12 @@ func (s *Service) RepoExists() bool {
 
 // Clone clones the repository
 func (s *Service) Clone(ctx context.Context) error {
-	auth := &http.BasicAuth{
-		Username: "token",
-		Password: s.authToken,
+	auth, err := s.auth(nil)
+	if err != nil {
+		return err
 	}
 
-	_, err := git.PlainCloneContext(ctx, s.repoDir, &git.CloneOptions{
+	_, err = git.PlainCloneContext(ctx, s.repoDir, &git.CloneOptions{
 		URL:           s.repoURL,
 		Auth:          auth,
 		ReferenceName: plumbing.NewBranchReferenceName(s.branch),
@@ -68,6 +97,65 @@ func (s *Service) Clone(ctx context.Context) error {
 	return s.AssignAuthor()
 }
 
+// Bootstrap prepares the local repository on startup. A missing or empty repository
+// directory is cloned from the repository URL; an existing repository is pulled.
+// It fails if the directory holds something else or the configured branch is not checked out.
+func (s *Service) Bootstrap(ctx context.Context) error {
+	if _, err := git.PlainOpen(s.repoDir); errors.Is(err, git.ErrRepositoryNotExists) {
+		empty, err := isEmptyDir(s.repoDir)
+		if err != nil {
+			return fmt.Errorf("failed to inspect repository directory: %w", err)
+		}
+		if !empty {
+			return fmt.Errorf("%s exists but is not a git repository", s.repoDir)
+		}
+		if s.repoURL == "" {
+			return fmt.Errorf("repository %s does not exist and no repository URL is configured", s.repoDir)
+		}
+		return s.Clone(ctx)
+	} else if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	if err := s.checkBranch(); err != nil {
+		return err
+	}
+	if err := s.AssignAuthor(); err != nil {
+		return fmt.Errorf("failed to assign author: %w", err)
+	}
+
+	return s.Pull(ctx)
+}
+
+// checkBranch verifies that the configured branch is checked out
+func (s *Service) checkBranch() error {
+	repo, err := s.Open()
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	if head.Name() != plumbing.NewBranchReferenceName(s.branch) {
+		return fmt.Errorf("repository %s has %s checked out, expected branch %s", s.repoDir, head.Name().Short(), s.branch)
+	}
+	return nil
+}
+
+func isEmptyDir(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
+
 // Open opens an existing repository
 func (s *Service) Open() (*git.Repository, error) {
 	repo, err := git.PlainOpen(s.repoDir)
@@ -107,9 +195,9 @@ func (s *Service) Pull(ctx context.Context) error {
 		return fmt.Errorf("failed to get worktree: %w", err)
 	}
 
-	auth := &http.BasicAuth{
-		Username: "token",
-		Password: s.authToken,
+	auth, err := s.auth(repo)
+	if err != nil {
+		return err
 	}
 
 	err = wt.PullContext(ctx, &git.PullOptions{
@@ -213,7 +301,7 @@ func (s *Service) Commit(message string) error {
 	}
 
 	if status.IsClean() {
-		return fmt.Errorf("no changes to commit")
+		return ErrNoChanges
 	}
 
 	_, err = wt.Commit(message, &git.CommitOptions{
@@ -222,7 +310,12 @@ func (s *Service) Commit(message string) error {
 			Email: s.author.Email,
 			When:  time.Now(),
 		},
+		Signer: s.signer,
 	})
+	if errors.Is(err, git.ErrEmptyCommit) {
+		// Only untracked files changed
+		return ErrNoChanges
+	}
 	if err != nil {
 		return fmt.Errorf("failed to commit: %w", err)
 	}
@@ -230,29 +323,563 @@ func (s *Service) Commit(message string) error {
 	return nil
 }
 
-// Push pushes commits to the remote repository
+// Discard drops uncommitted changes to tracked files and unstages new files
+func (s *Service) Discard() error {
+	repo, err := s.Open()
+	if err != nil {
+		return err
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	if err := wt.Reset(&git.ResetOptions{Mode: git.HardReset}); err != nil {
+		return fmt.Errorf("failed to reset worktree: %w", err)
+	}
+
+	return nil
+}
+
+// Push pushes the checked out branch to the remote repository. Local commits are first rebased onto
+// the remote branch if it has moved, and the push is retried if it moves again meanwhile.
+// Other push errors are returned without retrying.
+// It returns ErrConflict, leaving the local commits in place, if the rebase is not possible.
 func (s *Service) Push(ctx context.Context) error {
 	repo, err := s.Open()
 	if err != nil {
 		return err
 	}
 
-	auth := &http.BasicAuth{
-		Username: "token",
-		Password: s.authToken,
+	branch, err := currentBranch(repo)
+	if err != nil {
+		return err
+	}
+
+	auth, err := s.auth(repo)
+	if err != nil {
+		return err
+	}
+
+	for range maxPushAttempts {
+		if err = s.rebase(ctx, repo, branch); err != nil {
+			return err
+		}
+		if s.beforePush != nil {
+			s.beforePush()
+		}
+
+		err = repo.PushContext(ctx, &git.PushOptions{
+			RemoteName: "origin",
+			Auth:       auth,
+			RefSpecs: []config.RefSpec{
+				config.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", branch, branch)),
+			},
+		})
+		if err == nil || err == git.NoErrAlreadyUpToDate {
+			return nil
+		}
+		if !isNonFastForward(err) {
+			// Authentication failures and the like do not go away by rebasing again
+			return err
+		}
+	}
+
+	return fmt.Errorf("failed to push after %d attempts: %w", maxPushAttempts, err)
+}
+
+// isNonFastForward reports whether a push was rejected because the remote branch moved on.
+// go-git rejects such a push before sending it, a server after receiving it.
+func isNonFastForward(err error) bool {
+	var statusErr packp.CommandStatusErr
+	if errors.As(err, &statusErr) {
+		return strings.Contains(statusErr.Status, "non-fast-forward") || strings.Contains(statusErr.Status, "fetch first")
+	}
+	return strings.Contains(err.Error(), "non-fast-forward update")
+}
+
+// SaveBranch pushes the checked out commit to a new branch name on the remote, e.g. to keep local
+// commits that conflict with the remote branch before ResetToRemote drops them
+func (s *Service) SaveBranch(ctx context.Context, name string) error {
+	repo, err := s.Open()
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	ref := plumbing.NewBranchReferenceName(name)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(ref, head.Hash())); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", name, err)
+	}
+
+	auth, err := s.auth(repo)
+	if err != nil {
+		return err
 	}
 
 	err = repo.PushContext(ctx, &git.PushOptions{
 		RemoteName: "origin",
 		Auth:       auth,
 		RefSpecs: []config.RefSpec{
-			config.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", s.branch, s.branch)),
+			config.RefSpec(fmt.Sprintf("%s:%s", ref, ref)),
+		},
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to push branch %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// ResetToRemote drops the local commits and changes the remote counterpart of the checked out
+// branch does not have, e.g. after Push returned ErrConflict and SaveBranch kept the commits
+func (s *Service) ResetToRemote(ctx context.Context) error {
+	repo, err := s.Open()
+	if err != nil {
+		return err
+	}
+
+	branch, err := currentBranch(repo)
+	if err != nil {
+		return err
+	}
+
+	remote, err := s.fetch(ctx, repo, branch)
+	if err != nil {
+		return err
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	if err := wt.Reset(&git.ResetOptions{Commit: remote.Hash, Mode: git.HardReset}); err != nil {
+		return fmt.Errorf("failed to reset to remote branch: %w", err)
+	}
+
+	return nil
+}
+
+// StartBranch checks out a branch for publishing on its own, e.g. for a change request.
+// It continues the remote branch of the same name if there is one, otherwise it starts
+// at the base branch of the remote. Uncommitted changes are dropped.
+func (s *Service) StartBranch(ctx context.Context, name string) error {
+	repo, err := s.Open()
+	if err != nil {
+		return err
+	}
+
+	start, err := s.fetch(ctx, repo, name)
+	if errors.Is(err, errNoRemoteBranch) {
+		start, err = s.fetch(ctx, repo, s.branch)
+	}
+	if err != nil {
+		return err
+	}
+
+	ref := plumbing.NewBranchReferenceName(name)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(ref, start.Hash)); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", name, err)
+	}
+
+	return s.checkout(repo, ref)
+}
+
+// CheckoutBase switches back to the base branch, dropping uncommitted changes
+func (s *Service) CheckoutBase() error {
+	repo, err := s.Open()
+	if err != nil {
+		return err
+	}
+	return s.checkout(repo, plumbing.NewBranchReferenceName(s.branch))
+}
+
+// Branch returns the base branch posts are published to
+func (s *Service) Branch() string {
+	return s.branch
+}
+
+func (s *Service) checkout(repo *git.Repository, ref plumbing.ReferenceName) error {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	if err := wt.Checkout(&git.CheckoutOptions{Branch: ref, Force: true}); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", ref.Short(), err)
+	}
+	return nil
+}
+
+// currentBranch returns the name of the checked out branch
+func currentBranch(repo *git.Repository) (string, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return "", fmt.Errorf("HEAD is not on a branch")
+	}
+	return head.Name().Short(), nil
+}
+
+// fetch updates the remote-tracking branch of branch and returns its head commit.
+// It returns errNoRemoteBranch if the remote does not have the branch.
+func (s *Service) fetch(ctx context.Context, repo *git.Repository, branch string) (*object.Commit, error) {
+	remoteRef := plumbing.NewRemoteReferenceName("origin", branch)
+
+	auth, err := s.auth(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+refs/heads/%s:%s", branch, remoteRef)),
 		},
 	})
+	if errors.Is(err, git.ErrRemoteRefNotFound) {
+		return nil, fmt.Errorf("%w: %s", errNoRemoteBranch, branch)
+	}
 	if err != nil && err != git.NoErrAlreadyUpToDate {
-		return fmt.Errorf("failed to push: %w", err)
+		return nil, fmt.Errorf("failed to fetch: %w", err)
+	}
+
+	ref, err := repo.Reference(remoteRef, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", remoteRef.Short(), err)
+	}
+
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote commit: %w", err)
+	}
+
+	return commit, nil
+}
+
+// rebase replays the local commits the remote branch does not have on top of it.
+// Files changed by both sides must end up identical, otherwise ErrConflict is returned
+// before anything is changed.
+func (s *Service) rebase(ctx context.Context, repo *git.Repository, branch string) error {
+	remote, err := s.fetch(ctx, repo, branch)
+	if errors.Is(err, errNoRemoteBranch) {
+		// A new branch, there is nothing to rebase onto
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	local, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	bases, err := local.MergeBase(remote)
+	if err != nil {
+		return fmt.Errorf("failed to find merge base: %w", err)
+	}
+	if len(bases) == 0 {
+		return fmt.Errorf("%w: no common history", ErrConflict)
+	}
+	base := bases[0]
+
+	if base.Hash == remote.Hash {
+		// The remote branch has not moved
+		return nil
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+	if !status.IsClean() {
+		return fmt.Errorf("cannot rebase onto remote branch with uncommitted changes")
 	}
 
+	if base.Hash == local.Hash {
+		// Nothing to replay, fast-forward to the remote branch
+		return wt.Reset(&git.ResetOptions{Commit: remote.Hash, Mode: git.HardReset})
+	}
+
+	commits, err := commitsSince(local, base)
+	if err != nil {
+		return err
+	}
+	if err := checkConflicts(base, local, remote); err != nil {
+		return err
+	}
+
+	// The commits are replayed as objects only; the branch and worktree move once all of them succeeded
+	replayed := remote
+	for _, commit := range commits {
+		replayed, err = s.replay(repo, replayed, commit)
+		if err != nil {
+			return fmt.Errorf("failed to replay commit %s: %w", commit.Hash.String()[:7], err)
+		}
+	}
+
+	if err := wt.Reset(&git.ResetOptions{Commit: replayed.Hash, Mode: git.HardReset}); err != nil {
+		return fmt.Errorf("failed to move branch onto the remote branch: %w", err)
+	}
+
+	return nil
+}
+
+// commitsSince returns the first-parent history from base (exclusive) to head, oldest first
+func commitsSince(head, base *object.Commit) ([]*object.Commit, error) {
+	var commits []*object.Commit
+	for commit := head; commit.Hash != base.Hash; {
+		commits = append(commits, commit)
+		if commit.NumParents() == 0 {
+			return nil, fmt.Errorf("commit %s does not descend from the merge base", head.Hash)
+		}
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent commit: %w", err)
+		}
+		commit = parent
+	}
+	slices.Reverse(commits)
+	return commits, nil
+}
+
+// checkConflicts fails with ErrConflict if local and remote changed the same file since base
+// and disagree on its contents
+func checkConflicts(base, local, remote *object.Commit) error {
+	baseTree, err := base.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get tree: %w", err)
+	}
+	localTree, err := local.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get tree: %w", err)
+	}
+	remoteTree, err := remote.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get tree: %w", err)
+	}
+
+	localChanges, err := object.DiffTree(baseTree, localTree)
+	if err != nil {
+		return fmt.Errorf("failed to diff local changes: %w", err)
+	}
+	remoteChanges, err := object.DiffTree(baseTree, remoteTree)
+	if err != nil {
+		return fmt.Errorf("failed to diff remote changes: %w", err)
+	}
+
+	remotePaths := make(map[string]bool)
+	for _, change := range remoteChanges {
+		remotePaths[changePath(change)] = true
+	}
+
+	var conflicts []string
+	for _, change := range localChanges {
+		path := changePath(change)
+		if remotePaths[path] && entryHash(localTree, path) != entryHash(remoteTree, path) {
+			conflicts = append(conflicts, path)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: %s", ErrConflict, strings.Join(conflicts, ", "))
+	}
+
+	return nil
+}
+
+func changePath(change *object.Change) string {
+	if change.To.Name != "" {
+		return change.To.Name
+	}
+	return change.From.Name
+}
+
+// entryHash returns the blob hash of a file in tree, or the zero hash if it does not exist
+func entryHash(tree *object.Tree, path string) plumbing.Hash {
+	entry, err := tree.FindEntry(path)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return entry.Hash
+}
+
+// replay commits the changes of commit on top of onto with the original message and author.
+// The new tree is built from the tree objects, so file modes, symlinks and submodules are kept.
+// It returns onto if it already has the same changes.
+func (s *Service) replay(repo *git.Repository, onto, commit *object.Commit) (*object.Commit, error) {
+	parent, err := commit.Parent(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent commit: %w", err)
+	}
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+	ontoTree, err := onto.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff commit: %w", err)
+	}
+
+	entries, err := treeEntries(ontoTree)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		if change.From.Name != "" {
+			delete(entries, change.From.Name)
+		}
+		if change.To.Name != "" {
+			entries[change.To.Name] = change.To.TreeEntry
+		}
+	}
+
+	treeHash, err := writeTree(repo.Storer, entries)
+	if err != nil {
+		return nil, err
+	}
+	if treeHash == ontoTree.Hash {
+		// The remote branch already has the same changes
+		return onto, nil
+	}
+
+	replayed := &object.Commit{
+		Author: commit.Author,
+		Committer: object.Signature{
+			Name:  s.author.Name,
+			Email: s.author.Email,
+			When:  time.Now(),
+		},
+		Message:      commit.Message,
+		TreeHash:     treeHash,
+		ParentHashes: []plumbing.Hash{onto.Hash},
+	}
+	if s.signer != nil {
+		if err := signCommit(s.signer, replayed); err != nil {
+			return nil, err
+		}
+	}
+
+	obj := repo.Storer.NewEncodedObject()
+	if err := replayed.Encode(obj); err != nil {
+		return nil, fmt.Errorf("failed to encode commit: %w", err)
+	}
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store commit: %w", err)
+	}
+
+	return repo.CommitObject(hash)
+}
+
+// treeEntries returns the non-directory entries of tree and its subtrees by path
+func treeEntries(tree *object.Tree) (map[string]object.TreeEntry, error) {
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	entries := make(map[string]object.TreeEntry)
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tree: %w", err)
+		}
+		if entry.Mode != filemode.Dir {
+			entries[name] = entry
+		}
+	}
+}
+
+// writeTree stores the tree holding entries, keyed by path, and its subtrees, returning its hash
+func writeTree(s storer.EncodedObjectStorer, entries map[string]object.TreeEntry) (plumbing.Hash, error) {
+	tree := &object.Tree{}
+	subtrees := make(map[string]map[string]object.TreeEntry)
+	for path, entry := range entries {
+		dir, rest, nested := strings.Cut(path, "/")
+		if !nested {
+			entry.Name = path
+			tree.Entries = append(tree.Entries, entry)
+			continue
+		}
+		if subtrees[dir] == nil {
+			subtrees[dir] = make(map[string]object.TreeEntry)
+		}
+		subtrees[dir][rest] = entry
+	}
+
+	for dir, subtree := range subtrees {
+		hash, err := writeTree(s, subtree)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: dir, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// Git orders tree entries as if directory names ended with a slash
+	sortName := func(entry object.TreeEntry) string {
+		if entry.Mode == filemode.Dir {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	slices.SortFunc(tree.Entries, func(a, b object.TreeEntry) int {
+		return strings.Compare(sortName(a), sortName(b))
+	})
+
+	obj := s.NewEncodedObject()
+	if err := tree.Encode(obj); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to encode tree: %w", err)
+	}
+	hash, err := s.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store tree: %w", err)
+	}
+	return hash, nil
+}
+
+// signCommit signs commit with signer the way go-git signs the commits it creates
+func signCommit(signer git.Signer, commit *object.Commit) error {
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		return fmt.Errorf("failed to encode commit: %w", err)
+	}
+	r, err := encoded.Reader()
+	if err != nil {
+		return fmt.Errorf("failed to encode commit: %w", err)
+	}
+	signature, err := signer.Sign(r)
+	if err != nil {
+		return fmt.Errorf("failed to sign commit: %w", err)
+	}
+	commit.PGPSignature = string(signature)
 	return nil
 }
 
@@ -2,12 +2,17 @@ package git
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
 	"github.com/go-git/go-git/v6/plumbing/object"
 )
 
@@ -577,3 +582,487 @@ func TestService_NewService(t *testing.T) {
 		t.Errorf("expected author email to be 'test@example.com', got '%s'", service.author.Email)
 	}
 }
+
+// newRemoteRepo creates a repository with a commit on main to clone and pull from
+func newRemoteRepo(t *testing.T) (string, *git.Repository) {
+	t.Helper()
+
+	remoteDir := t.TempDir()
+	repo, err := git.PlainInit(remoteDir, false, git.WithDefaultBranch(plumbing.NewBranchReferenceName("main")))
+	if err != nil {
+		t.Fatalf("failed to init remote repo: %v", err)
+	}
+
+	commitFile(t, repo, remoteDir, "content/posts/_index.md", "+++\n+++\n")
+	return remoteDir, repo
+}
+
+func commitFile(t *testing.T, repo *git.Repository, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	if _, err := wt.Add(name); err != nil {
+		t.Fatalf("failed to add file: %v", err)
+	}
+	_, err = wt.Commit("add "+name, &git.CommitOptions{
+		Author: &object.Signature{Name: "Remote", Email: "remote@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+}
+
+func TestService_Bootstrap_ClonesMissingRepo(t *testing.T) {
+	remoteDir, _ := newRemoteRepo(t)
+	repoDir := filepath.Join(t.TempDir(), "site")
+
+	service := NewService(repoDir, remoteDir, "main", "", Author{Name: "Test User", Email: "test@example.com"})
+	if err := service.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(repoDir, "content", "posts", "_index.md")); err != nil {
+		t.Errorf("expected cloned file to exist: %v", err)
+	}
+
+	repo, err := service.Open()
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+	if cfg.User.Name != "Test User" {
+		t.Errorf("expected author to be assigned, got '%s'", cfg.User.Name)
+	}
+}
+
+func TestService_Bootstrap_PullsExistingRepo(t *testing.T) {
+	remoteDir, remote := newRemoteRepo(t)
+	repoDir := filepath.Join(t.TempDir(), "site")
+
+	service := NewService(repoDir, remoteDir, "main", "", Author{Name: "Test", Email: "test@example.com"})
+	if err := service.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("first Bootstrap failed: %v", err)
+	}
+
+	commitFile(t, remote, remoteDir, "content/posts/1.md", "new post")
+
+	if err := service.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("second Bootstrap failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, "content", "posts", "1.md")); err != nil {
+		t.Errorf("expected pulled file to exist: %v", err)
+	}
+}
+
+func TestService_Bootstrap_WrongBranch(t *testing.T) {
+	remoteDir, _ := newRemoteRepo(t)
+	repoDir := filepath.Join(t.TempDir(), "site")
+
+	if err := NewService(repoDir, remoteDir, "main", "", Author{}).Bootstrap(context.Background()); err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+
+	err := NewService(repoDir, remoteDir, "gh-pages", "", Author{}).Bootstrap(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "expected branch gh-pages") {
+		t.Errorf("expected branch mismatch error, got %v", err)
+	}
+}
+
+func TestService_Bootstrap_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	// Missing repository without a URL to clone from
+	missing := filepath.Join(t.TempDir(), "site")
+	err := NewService(missing, "", "main", "", Author{}).Bootstrap(ctx)
+	if err == nil || !strings.Contains(err.Error(), "no repository URL") {
+		t.Errorf("expected missing URL error, got %v", err)
+	}
+
+	// Directory with files that is not a repository
+	notRepo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(notRepo, "file.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	err = NewService(notRepo, "https://github.com/test/repo.git", "main", "", Author{}).Bootstrap(ctx)
+	if err == nil || !strings.Contains(err.Error(), "not a git repository") {
+		t.Errorf("expected not a repository error, got %v", err)
+	}
+}
+
+// newBareRemote creates a bare repository with an initial commit and returns its path
+func newBareRemote(t *testing.T) string {
+	t.Helper()
+
+	seedDir, _ := newRemoteRepo(t)
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	if _, err := git.PlainClone(remoteDir, &git.CloneOptions{URL: seedDir, Bare: true}); err != nil {
+		t.Fatalf("failed to create bare remote: %v", err)
+	}
+	return remoteDir
+}
+
+// newClone bootstraps a service with its own clone of remoteDir
+func newClone(t *testing.T, remoteDir, name string) *Service {
+	t.Helper()
+
+	service := NewService(filepath.Join(t.TempDir(), name), remoteDir, "main", "", Author{Name: name, Email: name + "@example.com"})
+	if err := service.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("Bootstrap of %s failed: %v", name, err)
+	}
+	return service
+}
+
+func writeAndCommit(t *testing.T, service *Service, name, content, message string) {
+	t.Helper()
+
+	path := filepath.Join(service.repoDir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := service.Add(name); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := service.Commit(message); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+}
+
+func remoteHead(t *testing.T, remoteDir string) *object.Commit {
+	t.Helper()
+	return remoteBranchHead(t, remoteDir, "main")
+}
+
+func remoteBranchHead(t *testing.T, remoteDir, branch string) *object.Commit {
+	t.Helper()
+
+	repo, err := git.PlainOpen(remoteDir)
+	if err != nil {
+		t.Fatalf("failed to open remote: %v", err)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		t.Fatalf("failed to resolve %s: %v", branch, err)
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatalf("failed to get commit: %v", err)
+	}
+	return commit
+}
+
+func fileAt(t *testing.T, commit *object.Commit, name string) string {
+	t.Helper()
+
+	file, err := commit.File(name)
+	if err != nil {
+		t.Fatalf("expected %s in commit %s: %v", name, commit.Hash, err)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return content
+}
+
+func TestService_Push_RebasesOntoRemoteChanges(t *testing.T) {
+	remoteDir := newBareRemote(t)
+	ctx := context.Background()
+
+	service := newClone(t, remoteDir, "postpal")
+	writer := newClone(t, remoteDir, "writer")
+
+	writeAndCommit(t, service, "content/posts/1.md", "post 1", "Add post: 1")
+	writeAndCommit(t, service, "content/posts/2.md", "post 2", "Add post: 2")
+
+	// Someone edits the site by hand before our push, including a file we also add identically
+	writeAndCommit(t, writer, "content/about.md", "about", "Edit about page")
+	writeAndCommit(t, writer, "content/posts/2.md", "post 2", "Copy post 2")
+	if err := writer.Push(ctx); err != nil {
+		t.Fatalf("Push of writer failed: %v", err)
+	}
+
+	if err := service.Push(ctx); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	head := remoteHead(t, remoteDir)
+	if fileAt(t, head, "content/about.md") != "about" || fileAt(t, head, "content/posts/1.md") != "post 1" {
+		t.Error("expected remote head to contain both sides' changes")
+	}
+
+	// Our first commit is replayed on top of the writer's; the second is already on the remote
+	if head.Message != "Add post: 1" || head.Author.Name != "postpal" {
+		t.Errorf("expected replayed commit 'Add post: 1' by postpal, got %q by %s", head.Message, head.Author.Name)
+	}
+	parent, err := head.Parent(0)
+	if err != nil {
+		t.Fatalf("failed to get parent: %v", err)
+	}
+	if parent.Message != "Copy post 2" {
+		t.Errorf("expected history to be linear on top of the remote, got parent %q", parent.Message)
+	}
+}
+
+func TestService_Push_Conflict(t *testing.T) {
+	remoteDir := newBareRemote(t)
+	ctx := context.Background()
+
+	service := newClone(t, remoteDir, "postpal")
+	writer := newClone(t, remoteDir, "writer")
+
+	writeAndCommit(t, service, "content/posts/1.md", "ours", "Edit post: 1")
+	writeAndCommit(t, writer, "content/posts/1.md", "theirs", "Fix typo")
+	if err := writer.Push(ctx); err != nil {
+		t.Fatalf("Push of writer failed: %v", err)
+	}
+
+	repo, err := service.Open()
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	before, _ := repo.Head()
+
+	err = service.Push(ctx)
+	if !errors.Is(err, ErrConflict) || !strings.Contains(err.Error(), "content/posts/1.md") {
+		t.Fatalf("expected ErrConflict naming the file, got %v", err)
+	}
+
+	after, _ := repo.Head()
+	if after.Hash() != before.Hash() {
+		t.Error("expected local commits to stay in place after a conflict")
+	}
+	if msg := remoteHead(t, remoteDir).Message; msg != "Fix typo" {
+		t.Errorf("expected remote to keep the writer's commit, got %q", msg)
+	}
+
+	if err := service.SaveBranch(ctx, "postpal/conflict-1"); err != nil {
+		t.Fatalf("SaveBranch failed: %v", err)
+	}
+	if got := fileAt(t, remoteBranchHead(t, remoteDir, "postpal/conflict-1"), "content/posts/1.md"); got != "ours" {
+		t.Errorf("expected the saved branch to keep our commit, got %q", got)
+	}
+
+	if err := service.ResetToRemote(ctx); err != nil {
+		t.Fatalf("ResetToRemote failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(service.repoDir, "content", "posts", "1.md"))
+	if err != nil || string(content) != "theirs" {
+		t.Errorf("expected worktree to match the remote after reset, got %q (%v)", content, err)
+	}
+	if err := service.Push(ctx); err != nil {
+		t.Errorf("expected push after reset to succeed, got %v", err)
+	}
+}
+
+func TestService_Push_FastForwardsWithoutLocalCommits(t *testing.T) {
+	remoteDir := newBareRemote(t)
+	ctx := context.Background()
+
+	service := newClone(t, remoteDir, "postpal")
+	writer := newClone(t, remoteDir, "writer")
+
+	writeAndCommit(t, writer, "content/about.md", "about", "Edit about page")
+	if err := writer.Push(ctx); err != nil {
+		t.Fatalf("Push of writer failed: %v", err)
+	}
+
+	if err := service.Push(ctx); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(service.repoDir, "content", "about.md")); err != nil {
+		t.Errorf("expected local branch to fast-forward to the remote: %v", err)
+	}
+}
+
+func TestService_StartBranch(t *testing.T) {
+	remoteDir := newBareRemote(t)
+	ctx := context.Background()
+	service := newClone(t, remoteDir, "postpal")
+
+	if err := service.StartBranch(ctx, "postpal/post-1"); err != nil {
+		t.Fatalf("StartBranch failed: %v", err)
+	}
+	writeAndCommit(t, service, "content/posts/first.md", "first", "Add post: 1")
+	if err := service.Push(ctx); err != nil {
+		t.Fatalf("Push of new branch failed: %v", err)
+	}
+	if err := service.CheckoutBase(); err != nil {
+		t.Fatalf("CheckoutBase failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(service.repoDir, "content", "posts", "first.md")); !os.IsNotExist(err) {
+		t.Errorf("expected the post to stay off the base branch, got %v", err)
+	}
+	if _, err := remoteHead(t, remoteDir).File("content/posts/first.md"); err == nil {
+		t.Error("expected the remote base branch to be unchanged")
+	}
+
+	// Starting the branch again continues from the pushed branch, even from a fresh clone
+	other := newClone(t, remoteDir, "other")
+	if err := other.StartBranch(ctx, "postpal/post-1"); err != nil {
+		t.Fatalf("StartBranch of existing branch failed: %v", err)
+	}
+	writeAndCommit(t, other, "content/posts/first.md", "first, edited", "Edit post: 1")
+	if err := other.Push(ctx); err != nil {
+		t.Fatalf("Push of existing branch failed: %v", err)
+	}
+
+	head := remoteBranchHead(t, remoteDir, "postpal/post-1")
+	if got := fileAt(t, head, "content/posts/first.md"); got != "first, edited" {
+		t.Errorf("expected edited post on the branch, got %q", got)
+	}
+	if head.NumParents() != 1 || head.ParentHashes[0] == remoteHead(t, remoteDir).Hash {
+		t.Error("expected the edit to follow the first commit of the branch")
+	}
+}
+
+func TestService_Push_KeepsFileModes(t *testing.T) {
+	remoteDir := newBareRemote(t)
+	ctx := context.Background()
+
+	service := newClone(t, remoteDir, "postpal")
+	writer := newClone(t, remoteDir, "writer")
+
+	if err := os.WriteFile(filepath.Join(service.repoDir, "build.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	if err := os.Symlink("build.sh", filepath.Join(service.repoDir, "latest")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	if err := service.Add("build.sh", "latest"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := service.Commit("Add build script"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	writeAndCommit(t, writer, "content/about.md", "about", "Edit about page")
+	if err := writer.Push(ctx); err != nil {
+		t.Fatalf("Push of writer failed: %v", err)
+	}
+
+	if err := service.Push(ctx); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	tree, err := remoteHead(t, remoteDir).Tree()
+	if err != nil {
+		t.Fatalf("failed to get tree: %v", err)
+	}
+	for name, mode := range map[string]filemode.FileMode{"build.sh": filemode.Executable, "latest": filemode.Symlink} {
+		entry, err := tree.FindEntry(name)
+		if err != nil {
+			t.Fatalf("expected %s on the remote: %v", name, err)
+		}
+		if entry.Mode != mode {
+			t.Errorf("expected %s to keep mode %s, got %s", name, mode, entry.Mode)
+		}
+	}
+	if target, err := os.Readlink(filepath.Join(service.repoDir, "latest")); err != nil || target != "build.sh" {
+		t.Errorf("expected the worktree to keep the symlink, got %q (%v)", target, err)
+	}
+}
+
+func TestService_Push_RetriesWhenRemoteMovesAgain(t *testing.T) {
+	remoteDir := newBareRemote(t)
+	ctx := context.Background()
+
+	service := newClone(t, remoteDir, "postpal")
+	writer := newClone(t, remoteDir, "writer")
+
+	// The writer pushes between our rebase and our push, once
+	pushes := 0
+	service.beforePush = func() {
+		pushes++
+		if pushes == 1 {
+			writeAndCommit(t, writer, "content/about.md", "about", "Edit about page")
+			if err := writer.Push(ctx); err != nil {
+				t.Fatalf("Push of writer failed: %v", err)
+			}
+		}
+	}
+
+	writeAndCommit(t, service, "content/posts/1.md", "post 1", "Add post: 1")
+	if err := service.Push(ctx); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	if pushes != 2 {
+		t.Errorf("expected a second push attempt after the remote moved, got %d", pushes)
+	}
+	head := remoteHead(t, remoteDir)
+	if head.Message != "Add post: 1" || fileAt(t, head, "content/about.md") != "about" {
+		t.Errorf("expected our commit on top of the writer's, got %q", head.Message)
+	}
+}
+
+func TestService_Push_GivesUpWhenRemoteKeepsMoving(t *testing.T) {
+	remoteDir := newBareRemote(t)
+	ctx := context.Background()
+
+	service := newClone(t, remoteDir, "postpal")
+	writer := newClone(t, remoteDir, "writer")
+
+	pushes := 0
+	service.beforePush = func() {
+		pushes++
+		writeAndCommit(t, writer, fmt.Sprintf("content/page-%d.md", pushes), "page", "Add page")
+		if err := writer.Push(ctx); err != nil {
+			t.Fatalf("Push of writer failed: %v", err)
+		}
+	}
+
+	writeAndCommit(t, service, "content/posts/1.md", "post 1", "Add post: 1")
+	err := service.Push(ctx)
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("after %d attempts", maxPushAttempts)) {
+		t.Fatalf("expected Push to give up after %d attempts, got %v", maxPushAttempts, err)
+	}
+	if pushes != maxPushAttempts {
+		t.Errorf("expected %d push attempts, got %d", maxPushAttempts, pushes)
+	}
+	if _, err := remoteHead(t, remoteDir).File("content/posts/1.md"); err == nil {
+		t.Error("expected the rejected commit to stay off the remote")
+	}
+}
+
+func TestService_Push_DoesNotRetryOtherErrors(t *testing.T) {
+	remoteDir := newBareRemote(t)
+	ctx := context.Background()
+
+	service := newClone(t, remoteDir, "postpal")
+
+	// The remote goes away between our rebase and our push
+	pushes := 0
+	service.beforePush = func() {
+		pushes++
+		if err := os.Rename(remoteDir, remoteDir+".gone"); err != nil {
+			t.Fatalf("failed to move remote: %v", err)
+		}
+	}
+
+	writeAndCommit(t, service, "content/posts/1.md", "post 1", "Add post: 1")
+	err := service.Push(ctx)
+	if err == nil || strings.Contains(err.Error(), "attempts") {
+		t.Fatalf("expected the push error without retries, got %v", err)
+	}
+	if pushes != 1 {
+		t.Errorf("expected a single push attempt, got %d", pushes)
+	}
+}
@@ -0,0 +1,164 @@
+package git
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v6"
+	"golang.org/x/crypto/ssh"
+)
+
+// Commit signature formats
+const (
+	SigningFormatOpenPGP = "openpgp"
+	SigningFormatSSH     = "ssh"
+)
+
+// SigningFormats lists the supported commit signature formats
+var SigningFormats = []string{SigningFormatOpenPGP, SigningFormatSSH}
+
+// sshSigNamespace is the namespace git uses for SSH commit signatures
+const sshSigNamespace = "git"
+
+// WithSigner signs the commits made by the service, see NewSigner
+func (s *Service) WithSigner(signer git.Signer) *Service {
+	s.signer = signer
+	return s
+}
+
+// NewSigner loads a commit signing key from keyFile: an armored or binary OpenPGP secret key,
+// or an OpenSSH private key for the ssh format. passphrase decrypts an encrypted key.
+func NewSigner(format, keyFile, passphrase string) (git.Signer, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	switch format {
+	case SigningFormatOpenPGP:
+		return newOpenPGPSigner(data, passphrase)
+	case SigningFormatSSH:
+		return newSSHSigner(data, passphrase)
+	default:
+		return nil, fmt.Errorf("unknown signing format %q", format)
+	}
+}
+
+// openPGPSigner creates armored detached OpenPGP signatures
+type openPGPSigner struct {
+	entity *openpgp.Entity
+}
+
+func newOpenPGPSigner(data []byte, passphrase string) (*openPGPSigner, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		entities, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenPGP key: %w", err)
+	}
+
+	for _, entity := range entities {
+		if entity.PrivateKey == nil {
+			continue
+		}
+		if entity.PrivateKey.Encrypted {
+			if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+				return nil, fmt.Errorf("failed to decrypt OpenPGP key: %w", err)
+			}
+		}
+		return &openPGPSigner{entity: entity}, nil
+	}
+
+	return nil, errors.New("OpenPGP key file contains no secret key")
+}
+
+func (s *openPGPSigner) Sign(message io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&buf, s.entity, message, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// sshSigner creates SSH signatures in the SSHSIG format that git verifies with gpg.format=ssh
+type sshSigner struct {
+	signer ssh.Signer
+}
+
+func newSSHSigner(data []byte, passphrase string) (*sshSigner, error) {
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key: %w", err)
+	}
+	return &sshSigner{signer: signer}, nil
+}
+
+func (s *sshSigner) Sign(message io.Reader) ([]byte, error) {
+	h := sha512.New()
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, fmt.Errorf("failed to hash message: %w", err)
+	}
+
+	signedData := sshSigData(sshSigNamespace, h.Sum(nil))
+
+	var sig *ssh.Signature
+	var err error
+	if algorithmSigner, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// SHA-1 RSA signatures are rejected by ssh-keygen
+		sig, err = algorithmSigner.SignWithAlgorithm(rand.Reader, signedData, ssh.KeyAlgoRSASHA512)
+	} else {
+		sig, err = s.signer.Sign(rand.Reader, signedData)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	blob := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}{1, s.signer.PublicKey().Marshal(), sshSigNamespace, "", "sha512", ssh.Marshal(sig)})...)
+
+	return armorSSHSig(blob), nil
+}
+
+// sshSigData is the data an SSHSIG signature is computed over
+func sshSigData(namespace string, hash []byte) []byte {
+	return append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{namespace, "", "sha512", hash})...)
+}
+
+// armorSSHSig wraps a signature blob the way ssh-keygen -Y sign does
+func armorSSHSig(blob []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(blob)
+
+	var buf bytes.Buffer
+	buf.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 70 {
+		buf.WriteString(encoded[:70])
+		buf.WriteByte('\n')
+		encoded = encoded[70:]
+	}
+	buf.WriteString(encoded)
+	buf.WriteString("\n-----END SSH SIGNATURE-----\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,180 @@
+package git
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+// signedCommit makes a commit with signer in a new repository and returns it
+func signedCommit(t *testing.T, signer git.Signer) *object.Commit {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	if _, err := git.PlainInit(tempDir, false); err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+
+	service := NewService(tempDir, "", "main", "", Author{Name: "PostPal", Email: "postpal@example.com"}).
+		WithSigner(signer)
+
+	if err := os.WriteFile(filepath.Join(tempDir, "post.md"), []byte("post"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := service.Add("post.md"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := service.Commit("Add post: 1"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	repo, err := service.Open()
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get head: %v", err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("failed to get commit: %v", err)
+	}
+	return commit
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestNewSigner_OpenPGP(t *testing.T) {
+	entity, err := openpgp.NewEntity("PostPal", "", "postpal@example.com", nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	var publicKey bytes.Buffer
+	w, _ := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("failed to serialize public key: %v", err)
+	}
+	w.Close()
+
+	if err := entity.EncryptPrivateKeys([]byte("correct horse"), nil); err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	var privateKey bytes.Buffer
+	w, _ = armor.Encode(&privateKey, openpgp.PrivateKeyType, nil)
+	if err := entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatalf("failed to serialize private key: %v", err)
+	}
+	w.Close()
+	keyFile := writeFile(t, "key.asc", privateKey.Bytes())
+
+	if _, err := NewSigner(SigningFormatOpenPGP, keyFile, "wrong"); err == nil {
+		t.Error("expected a wrong passphrase to be rejected")
+	}
+
+	signer, err := NewSigner(SigningFormatOpenPGP, keyFile, "correct horse")
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+
+	commit := signedCommit(t, signer)
+	if !strings.HasPrefix(commit.PGPSignature, "-----BEGIN PGP SIGNATURE-----") {
+		t.Fatalf("expected an OpenPGP signature, got %q", commit.PGPSignature)
+	}
+	if _, err := commit.Verify(publicKey.String()); err != nil {
+		t.Errorf("expected signature to verify: %v", err)
+	}
+}
+
+func TestNewSigner_SSH(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	keyFile := writeFile(t, "id_ed25519", pem.EncodeToMemory(block))
+
+	signer, err := NewSigner(SigningFormatSSH, keyFile, "correct horse")
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+
+	commit := signedCommit(t, signer)
+	if !strings.HasPrefix(commit.PGPSignature, "-----BEGIN SSH SIGNATURE-----") {
+		t.Fatalf("expected an SSH signature, got %q", commit.PGPSignature)
+	}
+
+	publicKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to convert public key: %v", err)
+	}
+
+	// Verify the way git does with gpg.format=ssh
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is not installed")
+	}
+
+	payload := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(payload); err != nil {
+		t.Fatalf("failed to encode commit: %v", err)
+	}
+	reader, err := payload.Reader()
+	if err != nil {
+		t.Fatalf("failed to read commit: %v", err)
+	}
+
+	allowedSigners := writeFile(t, "allowed_signers", []byte("postpal@example.com "+string(ssh.MarshalAuthorizedKey(publicKey))))
+	sigFile := writeFile(t, "commit.sig", []byte(commit.PGPSignature))
+
+	cmd := exec.Command("ssh-keygen", "-Y", "verify", "-f", allowedSigners, "-I", "postpal@example.com", "-n", "git", "-s", sigFile)
+	cmd.Stdin = reader
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("expected ssh-keygen to verify the signature: %v\n%s", err, output)
+	}
+}
+
+func TestNewSigner_Errors(t *testing.T) {
+	keyFile := writeFile(t, "key", []byte("not a key"))
+
+	tests := []struct {
+		format   string
+		keyFile  string
+		expected string
+	}{
+		{SigningFormatOpenPGP, filepath.Join(t.TempDir(), "missing"), "failed to read signing key"},
+		{SigningFormatOpenPGP, keyFile, "failed to parse OpenPGP key"},
+		{SigningFormatSSH, keyFile, "failed to parse SSH key"},
+		{"x509", keyFile, "unknown signing format"},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewSigner(tt.format, tt.keyFile, ""); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.format, tt.expected, err)
+		}
+	}
+}
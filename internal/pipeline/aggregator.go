@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/en9inerd/postpal/internal/telegram"
+)
+
+// Aggregator collects the messages of a media group (album), which Telegram delivers
+// as separate updates sharing a media_group_id. A group is flushed once no new message
+// for it has arrived within the debounce window.
+type Aggregator struct {
+	window    time.Duration
+	flush     func(messages []*telegram.Message)
+	afterFunc func(d time.Duration, f func()) timer // time.AfterFunc, replaced in tests
+
+	mu     sync.Mutex
+	groups map[string]*mediaGroup
+	wg     sync.WaitGroup
+}
+
+type mediaGroup struct {
+	messages []*telegram.Message
+	timer    timer
+}
+
+// timer is the part of *time.Timer used for the debounce window
+type timer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// NewAggregator creates an Aggregator that calls flush with the messages of each
+// completed group, sorted by message ID. flush runs on its own goroutine.
+func NewAggregator(window time.Duration, flush func(messages []*telegram.Message)) *Aggregator {
+	return &Aggregator{
+		window:    window,
+		flush:     flush,
+		afterFunc: func(d time.Duration, f func()) timer { return time.AfterFunc(d, f) },
+		groups:    make(map[string]*mediaGroup),
+	}
+}
+
+// Add buffers a message that belongs to a media group and restarts the group's debounce timer
+func (a *Aggregator) Add(msg *telegram.Message) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	id := msg.MediaGroupID
+	group, ok := a.groups[id]
+	if !ok {
+		group = &mediaGroup{}
+		a.groups[id] = group
+		a.wg.Add(1)
+		group.timer = a.afterFunc(a.window, func() { a.flushGroup(id, group) })
+	} else {
+		group.timer.Reset(a.window)
+	}
+
+	// Telegram may redeliver an update, keep one copy per message
+	if !slices.ContainsFunc(group.messages, func(m *telegram.Message) bool { return m.MessageID == msg.MessageID }) {
+		group.messages = append(group.messages, msg)
+	}
+}
+
+// Pending returns the number of groups waiting to be flushed
+func (a *Aggregator) Pending() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.groups)
+}
+
+// Close flushes all pending groups immediately and waits for every flush to finish
+func (a *Aggregator) Close() {
+	a.mu.Lock()
+	stopped := make(map[string]*mediaGroup)
+	for id, group := range a.groups {
+		if group.timer.Stop() {
+			stopped[id] = group
+		}
+	}
+	a.mu.Unlock()
+
+	for id, group := range stopped {
+		a.flushGroup(id, group)
+	}
+	a.wg.Wait()
+}
+
+// flushGroup hands the group to flush unless it was already flushed by an earlier timer or Close
+func (a *Aggregator) flushGroup(id string, group *mediaGroup) {
+	a.mu.Lock()
+	if a.groups[id] != group {
+		a.mu.Unlock()
+		return
+	}
+	delete(a.groups, id)
+	messages := group.messages
+	a.mu.Unlock()
+
+	defer a.wg.Done()
+
+	slices.SortFunc(messages, func(x, y *telegram.Message) int {
+		return cmp.Compare(x.MessageID, y.MessageID)
+	})
+	a.flush(messages)
+}
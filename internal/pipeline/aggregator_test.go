@@ -0,0 +1,208 @@
+package pipeline
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/en9inerd/postpal/internal/telegram"
+)
+
+// fakeClock fires the debounce timers of an aggregator when the test advances it
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Duration
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock    *fakeClock
+	deadline time.Duration
+	fn       func()
+	active   bool
+}
+
+func (c *fakeClock) afterFunc(d time.Duration, fn func()) timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, deadline: c.now + d, fn: fn, active: true}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// advance moves the clock forward and runs the timers that are due, in the calling goroutine
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now += d
+	var due []*fakeTimer
+	for _, t := range c.timers {
+		if t.active && t.deadline <= c.now {
+			t.active = false
+			due = append(due, t)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, t := range due {
+		t.fn()
+	}
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := t.active
+	t.active = false
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := t.active
+	t.active = true
+	t.deadline = t.clock.now + d
+	return active
+}
+
+type flushRecorder struct {
+	mu      sync.Mutex
+	flushes [][]*telegram.Message
+}
+
+func (r *flushRecorder) flush(messages []*telegram.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushes = append(r.flushes, messages)
+}
+
+func (r *flushRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.flushes)
+}
+
+// newTestAggregator returns an aggregator whose debounce windows only end when clock is advanced
+func newTestAggregator(window time.Duration) (*Aggregator, *flushRecorder, *fakeClock) {
+	rec := &flushRecorder{}
+	clock := &fakeClock{}
+	agg := NewAggregator(window, rec.flush)
+	agg.afterFunc = clock.afterFunc
+	return agg, rec, clock
+}
+
+func albumMessage(id int64, groupID, caption string) *telegram.Message {
+	return &telegram.Message{
+		MessageID:    id,
+		MediaGroupID: groupID,
+		Caption:      caption,
+		Photo:        []telegram.PhotoSize{{FileID: "photo"}},
+	}
+}
+
+func TestAggregator_FlushesGroupOnceInOrder(t *testing.T) {
+	agg, rec, clock := newTestAggregator(50 * time.Millisecond)
+
+	agg.Add(albumMessage(12, "g1", ""))
+	agg.Add(albumMessage(10, "g1", ""))
+	agg.Add(albumMessage(11, "g1", "caption on the second photo"))
+
+	clock.advance(50 * time.Millisecond)
+	clock.advance(time.Second)
+
+	if rec.count() != 1 {
+		t.Fatalf("expected 1 flush, got %d", rec.count())
+	}
+
+	messages := rec.flushes[0]
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	for i, want := range []int64{10, 11, 12} {
+		if messages[i].MessageID != want {
+			t.Errorf("expected message %d at position %d, got %d", want, i, messages[i].MessageID)
+		}
+	}
+
+	if agg.Pending() != 0 {
+		t.Errorf("expected no pending groups, got %d", agg.Pending())
+	}
+}
+
+func TestAggregator_DebounceRestartsOnNewMessage(t *testing.T) {
+	agg, rec, clock := newTestAggregator(100 * time.Millisecond)
+
+	agg.Add(albumMessage(1, "g1", ""))
+	clock.advance(60 * time.Millisecond)
+	agg.Add(albumMessage(2, "g1", ""))
+	clock.advance(60 * time.Millisecond)
+
+	// 120ms after the first message, but only 60ms after the last one
+	if rec.count() != 0 {
+		t.Fatal("expected group to still be buffered")
+	}
+
+	clock.advance(40 * time.Millisecond)
+	if rec.count() != 1 || len(rec.flushes[0]) != 2 {
+		t.Fatalf("expected 1 flush of 2 messages 100ms after the last message, got %v", rec.flushes)
+	}
+}
+
+func TestAggregator_SeparateGroups(t *testing.T) {
+	agg, rec, clock := newTestAggregator(30 * time.Millisecond)
+
+	agg.Add(albumMessage(1, "g1", ""))
+	agg.Add(albumMessage(5, "g2", ""))
+	agg.Add(albumMessage(2, "g1", ""))
+
+	clock.advance(30 * time.Millisecond)
+
+	if rec.count() != 2 {
+		t.Fatalf("expected 2 flushes, got %d", rec.count())
+	}
+
+	sizes := map[string]int{}
+	for _, messages := range rec.flushes {
+		sizes[messages[0].MediaGroupID] = len(messages)
+	}
+	if sizes["g1"] != 2 || sizes["g2"] != 1 {
+		t.Errorf("unexpected group sizes: %v", sizes)
+	}
+}
+
+func TestAggregator_IgnoresRedeliveredMessage(t *testing.T) {
+	agg, rec, clock := newTestAggregator(30 * time.Millisecond)
+
+	agg.Add(albumMessage(1, "g1", ""))
+	agg.Add(albumMessage(1, "g1", ""))
+
+	clock.advance(30 * time.Millisecond)
+	if rec.count() != 1 || len(rec.flushes[0]) != 1 {
+		t.Errorf("expected duplicate message to be dropped, got %v", rec.flushes)
+	}
+}
+
+func TestAggregator_CloseFlushesPending(t *testing.T) {
+	agg, rec, clock := newTestAggregator(time.Hour)
+
+	agg.Add(albumMessage(1, "g1", ""))
+	agg.Add(albumMessage(2, "g2", ""))
+
+	agg.Close()
+
+	if rec.count() != 2 {
+		t.Fatalf("expected Close to flush 2 groups, got %d", rec.count())
+	}
+	if agg.Pending() != 0 {
+		t.Errorf("expected no pending groups, got %d", agg.Pending())
+	}
+
+	// The stopped timers don't flush the groups again
+	clock.advance(time.Hour)
+	if rec.count() != 2 {
+		t.Errorf("expected no more flushes after Close, got %d", rec.count())
+	}
+}
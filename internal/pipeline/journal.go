@@ -0,0 +1,236 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/en9inerd/postpal/internal/telegram"
+)
+
+const (
+	// journalUpdateHistory is the number of Telegram update IDs remembered for deduplication
+	journalUpdateHistory = 1000
+	// journalFailedHistory is the number of failed jobs kept for inspection
+	journalFailedHistory = 100
+)
+
+// ErrDuplicateUpdate is returned when a job is enqueued for a Telegram update that was already queued
+var ErrDuplicateUpdate = errors.New("update was already queued")
+
+// Journal persists the jobs of the publish queue until their changes are committed,
+// so a restart replays them instead of losing them. Album messages are kept from the moment
+// they arrive until their album is queued. It also remembers recently queued Telegram
+// updates so that redelivered updates are not published twice.
+type Journal struct {
+	path string
+	mu   sync.Mutex
+
+	NextID    int64   `json:"next_id"`
+	Jobs      []Job   `json:"jobs"`       // Jobs that are not committed yet, in queue order
+	UpdateIDs []int64 `json:"update_ids"` // Recently queued updates, oldest first
+	Unpushed  bool    `json:"unpushed"`   // A commit failed to push and goes out with the next push
+	Failed    []Job   `json:"failed"`     // Jobs that could not be published and need attention, oldest first
+
+	Albums []AlbumMessage `json:"albums"` // Messages of albums that are not queued yet, in arrival order
+}
+
+// AlbumMessage is a message of an album waiting for the rest of the album
+type AlbumMessage struct {
+	UpdateID int64             `json:"update_id"`
+	Message  *telegram.Message `json:"message"`
+}
+
+// NewJournal creates an empty journal. An empty path keeps the journal in memory only.
+func NewJournal(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// LoadJournal reads the journal stored at path, returning an empty journal if the file does not exist
+func LoadJournal(path string) (*Journal, error) {
+	journal := NewJournal(path)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return journal, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read publish journal: %w", err)
+	}
+
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("failed to parse publish journal %s: %w", path, err)
+	}
+
+	return journal, nil
+}
+
+// Pending returns the jobs that were queued but not committed, in queue order
+func (j *Journal) Pending() []Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return slices.Clone(j.Jobs)
+}
+
+// FailedJobs returns the jobs that could not be published, oldest first
+func (j *Journal) FailedJobs() []Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return slices.Clone(j.Failed)
+}
+
+// PendingAlbums returns the album messages that were not queued yet, in arrival order
+func (j *Journal) PendingAlbums() []AlbumMessage {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return slices.Clone(j.Albums)
+}
+
+// HasUpdate reports whether a job was already queued or an album message buffered for the Telegram update
+func (j *Journal) HasUpdate(updateID int64) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return updateID != 0 && (slices.Contains(j.UpdateIDs, updateID) || j.hasAlbumUpdate(updateID))
+}
+
+// hasAlbumUpdate reports whether an album message was buffered for the update. j.mu must be held.
+func (j *Journal) hasAlbumUpdate(updateID int64) bool {
+	return slices.ContainsFunc(j.Albums, func(m AlbumMessage) bool { return m.UpdateID == updateID })
+}
+
+// addAlbumMessage records a message of an album before it is buffered.
+// Nothing is recorded if the journal cannot be saved.
+func (j *Journal) addAlbumMessage(updateID int64, msg *telegram.Message) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if updateID != 0 && (slices.Contains(j.UpdateIDs, updateID) || j.hasAlbumUpdate(updateID)) {
+		return fmt.Errorf("%w: %d", ErrDuplicateUpdate, updateID)
+	}
+
+	albums := j.Albums
+	j.Albums = append(slices.Clip(j.Albums), AlbumMessage{UpdateID: updateID, Message: msg})
+
+	if err := j.save(); err != nil {
+		j.Albums = albums
+		return err
+	}
+	return nil
+}
+
+// removeAlbumMessages drops the buffered album messages of the updates, which are part of a job now.
+// j.mu must be held.
+func (j *Journal) removeAlbumMessages(updateIDs []int64) {
+	j.Albums = slices.DeleteFunc(slices.Clone(j.Albums), func(m AlbumMessage) bool {
+		return slices.Contains(updateIDs, m.UpdateID)
+	})
+}
+
+// add records a queued job and its updates. Nothing is recorded if the journal cannot be saved.
+func (j *Journal) add(job Job) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var added []int64
+	for _, id := range job.UpdateIDs {
+		if id == 0 {
+			continue
+		}
+		if slices.Contains(j.UpdateIDs, id) {
+			return fmt.Errorf("%w: %d", ErrDuplicateUpdate, id)
+		}
+		added = append(added, id)
+	}
+
+	// Clipping makes append copy, so the previous state can be restored
+	nextID, jobs, updateIDs, albums := j.NextID, j.Jobs, j.UpdateIDs, j.Albums
+
+	j.NextID = max(j.NextID, job.ID)
+	j.Jobs = append(slices.Clip(j.Jobs), job)
+	j.UpdateIDs = append(slices.Clip(j.UpdateIDs), added...)
+	if len(j.UpdateIDs) > journalUpdateHistory {
+		j.UpdateIDs = j.UpdateIDs[len(j.UpdateIDs)-journalUpdateHistory:]
+	}
+	j.removeAlbumMessages(added)
+
+	if err := j.save(); err != nil {
+		j.NextID, j.Jobs, j.UpdateIDs, j.Albums = nextID, jobs, updateIDs, albums
+		return err
+	}
+	return nil
+}
+
+// fail records jobs that could not be published, taking them out of the pending jobs.
+// Their updates are remembered as queued, so they are not published if Telegram delivers them again.
+func (j *Journal) fail(jobs ...Job) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	nextID, pending, failed, updateIDs, albums := j.NextID, j.Jobs, j.Failed, j.UpdateIDs, j.Albums
+
+	j.UpdateIDs = slices.Clip(j.UpdateIDs)
+	for _, job := range jobs {
+		j.NextID = max(j.NextID, job.ID)
+		j.Jobs = slices.DeleteFunc(slices.Clone(j.Jobs), func(pending Job) bool { return pending.ID == job.ID })
+		j.Failed = append(slices.Clip(j.Failed), job)
+		for _, id := range job.UpdateIDs {
+			if id != 0 && !slices.Contains(j.UpdateIDs, id) {
+				j.UpdateIDs = append(j.UpdateIDs, id)
+			}
+		}
+		j.removeAlbumMessages(job.UpdateIDs)
+	}
+	if len(j.Failed) > journalFailedHistory {
+		j.Failed = j.Failed[len(j.Failed)-journalFailedHistory:]
+	}
+	if len(j.UpdateIDs) > journalUpdateHistory {
+		j.UpdateIDs = j.UpdateIDs[len(j.UpdateIDs)-journalUpdateHistory:]
+	}
+
+	if err := j.save(); err != nil {
+		j.NextID, j.Jobs, j.Failed, j.UpdateIDs, j.Albums = nextID, pending, failed, updateIDs, albums
+		return err
+	}
+	return nil
+}
+
+// remove forgets jobs whose changes are committed or that failed for good
+func (j *Journal) remove(ids []int64, unpushed bool) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.Jobs = slices.DeleteFunc(j.Jobs, func(job Job) bool { return slices.Contains(ids, job.ID) })
+	j.Unpushed = unpushed
+	return j.save()
+}
+
+// save writes the journal to disk atomically. j.mu must be held.
+func (j *Journal) save() error {
+	if j.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to encode publish journal: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	tmpPath := j.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write publish journal: %w", err)
+	}
+
+	return os.Rename(tmpPath, j.path)
+}
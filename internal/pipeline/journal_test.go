@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal_AddAndRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "journal.json")
+	journal := NewJournal(path)
+
+	if err := journal.add(Job{ID: 1, Kind: JobCreate, UpdateIDs: []int64{10, 11}}); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if err := journal.add(Job{ID: 2, Kind: JobDelete, PostIDs: "5"}); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if err := journal.remove([]int64{1}, true); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+
+	loaded, err := LoadJournal(path)
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	pending := loaded.Pending()
+	if len(pending) != 1 || pending[0].ID != 2 || pending[0].PostIDs != "5" {
+		t.Errorf("expected only job 2 to be pending, got %+v", pending)
+	}
+	if loaded.NextID != 2 || !loaded.Unpushed {
+		t.Errorf("expected next_id 2 and unpushed, got %d and %t", loaded.NextID, loaded.Unpushed)
+	}
+	if !loaded.HasUpdate(11) || loaded.HasUpdate(12) || loaded.HasUpdate(0) {
+		t.Error("expected only queued updates to be remembered")
+	}
+}
+
+func TestJournal_RejectsDuplicateUpdates(t *testing.T) {
+	journal := NewJournal("")
+
+	if err := journal.add(Job{ID: 1, UpdateIDs: []int64{10}}); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if err := journal.add(Job{ID: 2, UpdateIDs: []int64{11, 10}}); !errors.Is(err, ErrDuplicateUpdate) {
+		t.Fatalf("expected ErrDuplicateUpdate, got %v", err)
+	}
+	if journal.HasUpdate(11) || len(journal.Pending()) != 1 {
+		t.Error("expected a rejected job to leave the journal unchanged")
+	}
+}
+
+func TestJournal_ForgetsOldUpdates(t *testing.T) {
+	journal := NewJournal("")
+
+	for i := range journalUpdateHistory + 10 {
+		if err := journal.add(Job{ID: int64(i + 1), UpdateIDs: []int64{int64(i + 1)}}); err != nil {
+			t.Fatalf("add failed: %v", err)
+		}
+	}
+
+	if journal.HasUpdate(1) {
+		t.Error("expected the oldest update to be forgotten")
+	}
+	if !journal.HasUpdate(journalUpdateHistory + 10) {
+		t.Error("expected the latest update to be remembered")
+	}
+}
+
+func TestLoadJournal_MissingFile(t *testing.T) {
+	journal, err := LoadJournal(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	if len(journal.Pending()) != 0 || journal.NextID != 0 {
+		t.Errorf("expected an empty journal, got %+v", journal)
+	}
+}
+
+func TestJournal_BuffersAlbumMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	journal := NewJournal(path)
+
+	for i, id := range []int64{7, 8} {
+		if err := journal.addAlbumMessage(int64(100+i), albumMessage(id, "g1", "")); err != nil {
+			t.Fatalf("addAlbumMessage failed: %v", err)
+		}
+	}
+	if err := journal.addAlbumMessage(101, albumMessage(8, "g1", "")); !errors.Is(err, ErrDuplicateUpdate) {
+		t.Fatalf("expected ErrDuplicateUpdate, got %v", err)
+	}
+
+	loaded, err := LoadJournal(path)
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	albums := loaded.PendingAlbums()
+	if len(albums) != 2 || albums[1].UpdateID != 101 || albums[1].Message.MessageID != 8 {
+		t.Fatalf("expected both album messages to be journaled, got %+v", albums)
+	}
+	if !loaded.HasUpdate(100) {
+		t.Error("expected a buffered update to be remembered")
+	}
+
+	// Queuing the album takes its messages out of the buffer
+	if err := loaded.add(Job{ID: 1, Kind: JobCreate, UpdateIDs: []int64{100, 101}}); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if albums := loaded.PendingAlbums(); len(albums) != 0 {
+		t.Errorf("expected no buffered album messages, got %+v", albums)
+	}
+}
@@ -0,0 +1,507 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/en9inerd/postpal/internal/forge"
+	"github.com/en9inerd/postpal/internal/git"
+	"github.com/en9inerd/postpal/internal/telegram"
+	"github.com/en9inerd/postpal/internal/zola"
+)
+
+// branchPrefix starts the names of the branches posts are published on in branch mode
+const branchPrefix = "postpal/"
+
+// maxBranchIDsLength bounds the post IDs listed in the name of a delete branch
+const maxBranchIDsLength = 40
+
+// DefaultMediaGroupWait is how long an album is buffered after its latest message
+const DefaultMediaGroupWait = 2 * time.Second
+
+// Pipeline turns Telegram channel posts into Zola posts and publishes them to the site repository
+type Pipeline struct {
+	telegram   *telegram.Client
+	gitService *git.Service
+	channels   []Channel
+	albums     *Aggregator
+	queue      *Queue
+	logger     *slog.Logger
+
+	albumMu      sync.Mutex
+	albumUpdates map[string][]int64 // Updates of the albums being buffered, by media group
+}
+
+// Channel routes the posts of a Telegram chat to the Zola service of a site section.
+// A channel with neither ChatID nor Username accepts posts from any chat.
+type Channel struct {
+	ChatID   int64  // Chat ID of the channel
+	Username string // Channel username ("@channel"), matched when ChatID is not set
+	Zola     *zola.Service
+}
+
+// New creates a new Pipeline. Changes are published by a single worker, started with Start.
+// Posts are routed to the first channel matching their chat; posts from other chats are ignored.
+func New(telegramClient *telegram.Client, gitService *git.Service, channels []Channel, logger *slog.Logger) *Pipeline {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	p := &Pipeline{
+		telegram:     telegramClient,
+		gitService:   gitService,
+		channels:     channels,
+		logger:       logger,
+		albumUpdates: make(map[string][]int64),
+	}
+	p.albums = NewAggregator(DefaultMediaGroupWait, p.createAlbum)
+	p.queue = NewQueue(p.execute, gitService, logger)
+	for _, channel := range channels {
+		if channel.Zola != nil {
+			p.queue.WithIndexes(channel.Zola)
+		}
+	}
+	return p
+}
+
+// WithMediaGroupWait sets how long album messages are buffered before the album is published.
+// It must be called before the pipeline handles any update.
+func (p *Pipeline) WithMediaGroupWait(wait time.Duration) *Pipeline {
+	p.albums = NewAggregator(wait, p.createAlbum)
+	return p
+}
+
+// WithBatchWait sets how long the publish queue waits for more changes before committing.
+// It must be called before Start.
+func (p *Pipeline) WithBatchWait(wait time.Duration) *Pipeline {
+	p.queue.WithBatchWait(wait)
+	return p
+}
+
+// WithBranchPublishing publishes every change on its own branch and opens a change request
+// for it with requester. Later edits of a post go to the branch of the post.
+func (p *Pipeline) WithBranchPublishing(requester forge.ChangeRequester) *Pipeline {
+	p.queue.WithBranchPublishing(p.branchName, requester)
+	return p
+}
+
+// WithJournal persists queued changes in journal and replays the changes a restart interrupted.
+// Albums whose messages were journaled but not queued yet are buffered again.
+// It must be called before Start.
+func (p *Pipeline) WithJournal(journal *Journal) *Pipeline {
+	p.queue.WithJournal(journal)
+	for _, album := range p.queue.PendingAlbums() {
+		p.bufferAlbumMessage(album.UpdateID, album.Message)
+	}
+	return p
+}
+
+// Start runs the worker that publishes queued changes
+func (p *Pipeline) Start() {
+	p.queue.Start()
+}
+
+// Close publishes albums that are still being buffered and the changes already queued
+func (p *Pipeline) Close() {
+	p.albums.Close()
+	p.queue.Close()
+}
+
+// HandleUpdate queues the changes of a single update received from Telegram.
+// Updates that were already queued are ignored, so redelivered webhooks are published once.
+func (p *Pipeline) HandleUpdate(ctx context.Context, update *telegram.Update) error {
+	if p.queue.HasUpdate(update.UpdateID) {
+		p.logger.Debug("ignoring redelivered update", "update_id", update.UpdateID)
+		return nil
+	}
+
+	var err error
+	switch {
+	case update.ChannelPost != nil:
+		err = p.createPost(update.UpdateID, update.ChannelPost)
+	case update.EditedChannelPost != nil:
+		err = p.editPost(update.UpdateID, update.EditedChannelPost)
+	default:
+		p.logger.Debug("ignoring unsupported update", "update_id", update.UpdateID)
+	}
+
+	if errors.Is(err, ErrDuplicateUpdate) {
+		p.logger.Debug("ignoring redelivered update", "update_id", update.UpdateID)
+		return nil
+	}
+	return err
+}
+
+// FailUpdate records an update that could not be handled as a failed job, so it shows up
+// with the other jobs instead of being dropped silently
+func (p *Pipeline) FailUpdate(update *telegram.Update, cause error) {
+	job := Job{UpdateIDs: []int64{update.UpdateID}}
+	switch {
+	case update.ChannelPost != nil:
+		job.Kind = JobCreate
+		job.Summary = fmt.Sprintf("Add post: %d", update.ChannelPost.MessageID)
+		job.Messages = []*telegram.Message{update.ChannelPost}
+	case update.EditedChannelPost != nil:
+		job.Kind = JobEdit
+		job.Summary = fmt.Sprintf("Edit post: %d", update.EditedChannelPost.MessageID)
+		job.Messages = []*telegram.Message{update.EditedChannelPost}
+	default:
+		return
+	}
+
+	if _, err := p.queue.Fail(job, cause); err != nil {
+		p.logger.Error("failed to record failed update", "update_id", update.UpdateID, "error", err)
+	}
+}
+
+// DeletePosts queues the deletion of posts (comma-separated IDs) of a channel.
+// chatID may be 0 when a single channel is configured.
+func (p *Pipeline) DeletePosts(chatID int64, ids string) (Job, error) {
+	channel := p.channelByID(chatID)
+	if channel == nil {
+		return Job{}, fmt.Errorf("no channel configured for chat %d", chatID)
+	}
+
+	// The index keys messages by the chat of the channel, also when the caller left it out
+	if channel.ChatID != 0 {
+		chatID = channel.ChatID
+	}
+	return p.queue.Enqueue(Job{
+		Kind:    JobDelete,
+		Summary: fmt.Sprintf("Delete post(s): %s", ids),
+		ChatID:  chatID,
+		PostIDs: ids,
+	})
+}
+
+// Preview renders the post an update would create or edit and returns the files it would
+// change in the site repository, without writing them or queueing a job. Photos are downloaded.
+func (p *Pipeline) Preview(ctx context.Context, update *telegram.Update) ([]zola.FileChange, error) {
+	msg := update.ChannelPost
+	if msg == nil {
+		msg = update.EditedChannelPost
+	}
+	if msg == nil {
+		return nil, fmt.Errorf("update %d has no channel post", update.UpdateID)
+	}
+
+	channel := p.route(msg)
+	if channel == nil {
+		return nil, fmt.Errorf("no channel configured for chat of message %d", msg.MessageID)
+	}
+	dryRun := *channel
+	dryRun.Zola = channel.Zola.DryRun()
+
+	preview := &Pipeline{telegram: p.telegram, channels: []Channel{dryRun}, logger: p.logger}
+	var err error
+	if update.ChannelPost != nil {
+		err = preview.executeCreate(ctx, []*telegram.Message{msg})
+	} else {
+		err = preview.executeEdit(ctx, msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dryRun.Zola.Changes(), nil
+}
+
+// Job returns the status of a recent publish job
+func (p *Pipeline) Job(id int64) (Job, bool) {
+	return p.queue.Job(id)
+}
+
+// Jobs returns the status of recent publish jobs, oldest first
+func (p *Pipeline) Jobs() []Job {
+	return p.queue.Jobs()
+}
+
+func (p *Pipeline) createPost(updateID int64, msg *telegram.Message) error {
+	if p.route(msg) == nil {
+		p.logger.Debug("ignoring post from unknown chat", "message_id", msg.MessageID)
+		return nil
+	}
+
+	if msg.MediaGroupID != "" {
+		// Journal the message first, so the album survives a restart after Telegram got its answer
+		if err := p.queue.AddAlbumMessage(updateID, msg); err != nil {
+			return err
+		}
+		p.bufferAlbumMessage(updateID, msg)
+		return nil
+	}
+
+	_, err := p.queue.Enqueue(Job{
+		Kind:      JobCreate,
+		Summary:   fmt.Sprintf("Add post: %d", msg.MessageID),
+		UpdateIDs: []int64{updateID},
+		Messages:  []*telegram.Message{msg},
+	})
+	return err
+}
+
+// bufferAlbumMessage adds a journaled album message to its album
+func (p *Pipeline) bufferAlbumMessage(updateID int64, msg *telegram.Message) {
+	p.logger.Debug("buffering album message", "message_id", msg.MessageID, "media_group_id", msg.MediaGroupID)
+	p.albumMu.Lock()
+	if !slices.Contains(p.albumUpdates[msg.MediaGroupID], updateID) {
+		p.albumUpdates[msg.MediaGroupID] = append(p.albumUpdates[msg.MediaGroupID], updateID)
+	}
+	p.albumMu.Unlock()
+
+	p.albums.Add(msg)
+}
+
+// createAlbum queues all messages of a media group as a single post.
+// It runs after the group's debounce window, when Telegram already got its answer, so an album
+// that cannot be queued is recorded as a failed job instead of being dropped.
+func (p *Pipeline) createAlbum(messages []*telegram.Message) {
+	p.albumMu.Lock()
+	updateIDs := p.albumUpdates[messages[0].MediaGroupID]
+	delete(p.albumUpdates, messages[0].MediaGroupID)
+	p.albumMu.Unlock()
+
+	job := Job{
+		Kind:      JobCreate,
+		Summary:   fmt.Sprintf("Add post: %d", messages[0].MessageID),
+		UpdateIDs: updateIDs,
+		Messages:  messages,
+	}
+	_, err := p.queue.Enqueue(job)
+	if errors.Is(err, ErrDuplicateUpdate) {
+		p.logger.Debug("ignoring redelivered album", "media_group_id", messages[0].MediaGroupID)
+		return
+	}
+	if err == nil {
+		return
+	}
+
+	p.logger.Error("failed to queue album post", "message_id", messages[0].MessageID, "media_group_id", messages[0].MediaGroupID, "error", err)
+	if _, err := p.queue.Fail(job, fmt.Errorf("failed to queue album: %w", err)); err != nil {
+		p.logger.Error("failed to record failed album", "media_group_id", messages[0].MediaGroupID, "error", err)
+	}
+}
+
+func (p *Pipeline) editPost(updateID int64, msg *telegram.Message) error {
+	if p.route(msg) == nil {
+		p.logger.Debug("ignoring edit from unknown chat", "message_id", msg.MessageID)
+		return nil
+	}
+
+	_, err := p.queue.Enqueue(Job{
+		Kind:      JobEdit,
+		Summary:   fmt.Sprintf("Edit post: %d", msg.MessageID),
+		UpdateIDs: []int64{updateID},
+		Messages:  []*telegram.Message{msg},
+	})
+	return err
+}
+
+// branchName names the branch of a job after the chat and the post it changes, so that the edits
+// of a post update the change request that was opened for it
+func (p *Pipeline) branchName(job *Job) string {
+	switch job.Kind {
+	case JobCreate:
+		msg := job.Messages[0]
+		return fmt.Sprintf("%s%s-post-%d", branchPrefix, branchChat(chatID(msg)), msg.MessageID)
+	case JobEdit:
+		msg := job.Messages[0]
+		postID := msg.MessageID
+		if channel := p.route(msg); channel != nil && msg.Chat != nil {
+			if id, ok := channel.Zola.PostID(msg.Chat.ID, msg.MessageID); ok {
+				postID = id
+			}
+		}
+		return fmt.Sprintf("%s%s-post-%d", branchPrefix, branchChat(chatID(msg)), postID)
+	default:
+		ids := strings.Join(strings.Fields(strings.ReplaceAll(job.PostIDs, ",", " ")), "-")
+		if len(ids) > maxBranchIDsLength {
+			// Long lists of posts are named by their hash, so the branch name stays short
+			sum := sha256.Sum256([]byte(ids))
+			ids = fmt.Sprintf("%d-posts-%x", strings.Count(ids, "-")+1, sum[:6])
+		}
+		return fmt.Sprintf("%s%s-delete-%s", branchPrefix, branchChat(job.ChatID), ids)
+	}
+}
+
+// branchChat formats a chat ID for a branch name. Channel IDs are negative; the sign is dropped
+// so that the name does not look like an option to git.
+func branchChat(chatID int64) string {
+	return strings.TrimPrefix(strconv.FormatInt(chatID, 10), "-")
+}
+
+func chatID(msg *telegram.Message) int64 {
+	if msg.Chat == nil {
+		return 0
+	}
+	return msg.Chat.ID
+}
+
+// execute stages the changes of a job in the site repository. It runs on the queue worker.
+func (p *Pipeline) execute(ctx context.Context, job *Job) error {
+	switch job.Kind {
+	case JobCreate:
+		return p.executeCreate(ctx, job.Messages)
+	case JobEdit:
+		return p.executeEdit(ctx, job.Messages[0])
+	case JobDelete:
+		channel := p.channelByID(job.ChatID)
+		if channel == nil {
+			return fmt.Errorf("no channel configured for chat %d", job.ChatID)
+		}
+		return channel.Zola.DeletePost(ctx, job.ChatID, job.PostIDs)
+	default:
+		return fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+}
+
+// executeCreate writes a post from a single message or from all messages of an album
+func (p *Pipeline) executeCreate(ctx context.Context, messages []*telegram.Message) error {
+	first := messages[0]
+	channel := p.route(first)
+	if channel == nil {
+		return fmt.Errorf("no channel configured for chat of message %d", first.MessageID)
+	}
+
+	post := buildPost(first)
+	if first.MediaGroupID != "" {
+		post.MessageIDs = nil
+		post.MediaGroupID = first.MediaGroupID
+	}
+
+	var mediaFiles [][]byte
+	for _, msg := range messages {
+		if post.Content == "" && msg.Caption != "" {
+			post.Content = msg.Caption
+			post.Entities = msg.CaptionEntities
+		}
+
+		photo := largestPhoto(msg.Photo)
+		if photo == nil {
+			if first.MediaGroupID != "" {
+				p.logger.Warn("skipping album item without photo", "message_id", msg.MessageID, "media_group_id", msg.MediaGroupID)
+			}
+			continue
+		}
+		data, err := p.download(ctx, photo.FileID)
+		if err != nil {
+			return fmt.Errorf("failed to download photo of message %d: %w", msg.MessageID, err)
+		}
+		mediaFiles = append(mediaFiles, data)
+		if first.MediaGroupID != "" {
+			post.MessageIDs = append(post.MessageIDs, msg.MessageID)
+		}
+	}
+
+	if err := channel.Zola.CreatePost(ctx, post, mediaFiles); err != nil {
+		return fmt.Errorf("failed to create post %d: %w", first.MessageID, err)
+	}
+	return nil
+}
+
+func (p *Pipeline) executeEdit(ctx context.Context, msg *telegram.Message) error {
+	channel := p.route(msg)
+	if channel == nil {
+		return fmt.Errorf("no channel configured for chat of message %d", msg.MessageID)
+	}
+
+	var mediaFile []byte
+	if photo := largestPhoto(msg.Photo); photo != nil {
+		data, err := p.download(ctx, photo.FileID)
+		if err != nil {
+			return fmt.Errorf("failed to download photo of message %d: %w", msg.MessageID, err)
+		}
+		mediaFile = data
+	}
+
+	err := channel.Zola.EditPost(ctx, buildPost(msg), mediaFile)
+	if errors.Is(err, zola.ErrPostNotFound) {
+		// The message was never published, there is nothing to edit
+		p.logger.Warn("ignoring edit of unknown post", "message_id", msg.MessageID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to edit post %d: %w", msg.MessageID, err)
+	}
+	return nil
+}
+
+func (p *Pipeline) download(ctx context.Context, fileID string) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := p.telegram.DownloadFile(ctx, fileID, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// channelByID returns the channel with the given chat ID, or the only channel when chatID is 0
+func (p *Pipeline) channelByID(chatID int64) *Channel {
+	if chatID == 0 && len(p.channels) == 1 {
+		return &p.channels[0]
+	}
+	for i := range p.channels {
+		if chatID != 0 && p.channels[i].ChatID == chatID {
+			return &p.channels[i]
+		}
+	}
+	return nil
+}
+
+// route returns the channel the message was posted to, or nil if no channel accepts it
+func (p *Pipeline) route(msg *telegram.Message) *Channel {
+	for i := range p.channels {
+		if p.channels[i].matches(msg.Chat) {
+			return &p.channels[i]
+		}
+	}
+	return nil
+}
+
+func (c *Channel) matches(chat *telegram.Chat) bool {
+	if c.ChatID == 0 && c.Username == "" {
+		return true
+	}
+	if chat == nil {
+		return false
+	}
+	if c.ChatID != 0 {
+		return c.ChatID == chat.ID
+	}
+	return strings.EqualFold(strings.TrimPrefix(c.Username, "@"), chat.Username)
+}
+
+func buildPost(msg *telegram.Message) zola.Post {
+	content, entities := msg.Text, msg.Entities
+	if content == "" {
+		content, entities = msg.Caption, msg.CaptionEntities
+	}
+
+	post := zola.Post{
+		ID:         msg.MessageID,
+		Content:    content,
+		Entities:   entities,
+		Date:       time.Unix(msg.Date, 0).UTC(),
+		MessageIDs: []int64{msg.MessageID},
+	}
+	if msg.Chat != nil {
+		post.ChatID = msg.Chat.ID
+	}
+	return post
+}
+
+// largestPhoto returns the highest resolution size of a photo, or nil if there is none
+func largestPhoto(sizes []telegram.PhotoSize) *telegram.PhotoSize {
+	if len(sizes) == 0 {
+		return nil
+	}
+	return &sizes[len(sizes)-1]
+}
@@ -0,0 +1,417 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/en9inerd/postpal/internal/telegram"
+	"github.com/en9inerd/postpal/internal/zola"
+)
+
+func TestPipeline_RoutesByChat(t *testing.T) {
+	p := New(nil, nil, []Channel{
+		{ChatID: -100123},
+		{Username: "@Notes"},
+		{ChatID: -100456},
+	}, nil)
+	defer p.Close()
+
+	tests := []struct {
+		chat     *telegram.Chat
+		expected int // index of the matched channel, -1 if none
+	}{
+		{&telegram.Chat{ID: -100123}, 0},
+		{&telegram.Chat{ID: -100456}, 2},
+		{&telegram.Chat{ID: -100789, Username: "notes"}, 1},
+		{&telegram.Chat{ID: -100789, Username: "other"}, -1},
+		{nil, -1},
+	}
+
+	for _, tt := range tests {
+		channel := p.route(&telegram.Message{MessageID: 1, Chat: tt.chat})
+		if tt.expected == -1 {
+			if channel != nil {
+				t.Errorf("expected chat %+v to be ignored, got channel %+v", tt.chat, channel)
+			}
+			continue
+		}
+		if channel != &p.channels[tt.expected] {
+			t.Errorf("expected chat %+v to route to channel %d, got %+v", tt.chat, tt.expected, channel)
+		}
+	}
+}
+
+func TestPipeline_CatchAllChannel(t *testing.T) {
+	p := New(nil, nil, []Channel{{}}, nil)
+	defer p.Close()
+
+	if p.route(&telegram.Message{MessageID: 1, Chat: &telegram.Chat{ID: -1}}) == nil {
+		t.Error("expected a channel without chat ID or username to accept any chat")
+	}
+}
+
+func TestPipeline_IgnoresRedeliveredUpdates(t *testing.T) {
+	p := New(nil, nil, []Channel{{}}, nil)
+	defer p.Close()
+
+	update := &telegram.Update{
+		UpdateID:    42,
+		ChannelPost: &telegram.Message{MessageID: 7, Chat: &telegram.Chat{ID: -1}, Text: "hello"},
+	}
+	for range 2 {
+		if err := p.HandleUpdate(context.Background(), update); err != nil {
+			t.Fatalf("HandleUpdate failed: %v", err)
+		}
+	}
+
+	if jobs := p.Jobs(); len(jobs) != 1 {
+		t.Errorf("expected a single job for a redelivered update, got %d", len(jobs))
+	}
+}
+
+func TestPipeline_BranchName(t *testing.T) {
+	index := zola.NewIndex("")
+	index.Put(-1, 8, zola.IndexEntry{PostID: 7, Slot: 2}) // Second photo of an album published as post 7
+	channel := Channel{ChatID: -1, Zola: zola.NewService(t.TempDir(), "content/posts", t.TempDir(), "", nil, "").WithIndex(index)}
+
+	p := New(nil, nil, []Channel{channel}, nil)
+	defer p.Close()
+
+	chat := &telegram.Chat{ID: -1}
+	tests := []struct {
+		job      Job
+		expected string
+	}{
+		{Job{Kind: JobCreate, Messages: []*telegram.Message{{MessageID: 7, Chat: chat}, {MessageID: 8, Chat: chat}}}, "postpal/1-post-7"},
+		{Job{Kind: JobCreate, Messages: []*telegram.Message{{MessageID: 7, Chat: &telegram.Chat{ID: -1002}}}}, "postpal/1002-post-7"},
+		{Job{Kind: JobEdit, Messages: []*telegram.Message{{MessageID: 8, Chat: chat}}}, "postpal/1-post-7"},
+		{Job{Kind: JobEdit, Messages: []*telegram.Message{{MessageID: 9, Chat: chat}}}, "postpal/1-post-9"},
+		{Job{Kind: JobDelete, ChatID: -1, PostIDs: "7, 9"}, "postpal/1-delete-7-9"},
+	}
+
+	for _, tt := range tests {
+		if got := p.branchName(&tt.job); got != tt.expected {
+			t.Errorf("%s: expected branch %s, got %s", tt.job.Kind, tt.expected, got)
+		}
+	}
+
+	// A long list of posts is named by its hash
+	long := &Job{Kind: JobDelete, ChatID: -1, PostIDs: "1000 1001 1002 1003 1004 1005 1006 1007 1008 1009"}
+	other := &Job{Kind: JobDelete, ChatID: -1, PostIDs: "1000 1001 1002 1003 1004 1005 1006 1007 1008 1010"}
+	got := p.branchName(long)
+	if !strings.HasPrefix(got, "postpal/1-delete-10-posts-") || len(got) > 40 {
+		t.Errorf("expected a short hashed branch name, got %s", got)
+	}
+	if got == p.branchName(other) {
+		t.Errorf("expected different posts to get different branches, got %s for both", got)
+	}
+}
+
+func TestPipeline_Preview(t *testing.T) {
+	repoDir := t.TempDir()
+	postsDir := filepath.Join(repoDir, "content", "posts")
+	channel := Channel{ChatID: -1, Zola: zola.NewService(postsDir, "content/posts", repoDir, "@channel", nil, "")}
+
+	p := New(nil, nil, []Channel{channel}, nil)
+	defer p.Close()
+
+	update := &telegram.Update{
+		UpdateID: 43,
+		ChannelPost: &telegram.Message{
+			MessageID: 7,
+			Chat:      &telegram.Chat{ID: -1},
+			Text:      "hello",
+			Entities:  []telegram.MessageEntity{{Type: telegram.EntityBold, Offset: 0, Length: 5}},
+		},
+	}
+	changes, err := p.Preview(context.Background(), update)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	if len(changes) != 1 || changes[0].Path != "content/posts/7.md" || changes[0].Action != zola.ChangeAdd {
+		t.Fatalf("expected the post file to be added, got %+v", changes)
+	}
+	if changes[0].Content != "**hello**\n" {
+		t.Errorf("expected rendered content, got %q", changes[0].Content)
+	}
+	if entries, _ := os.ReadDir(postsDir); len(entries) != 0 {
+		t.Errorf("expected preview not to write posts, found %d files", len(entries))
+	}
+	if jobs := p.Jobs(); len(jobs) != 0 {
+		t.Errorf("expected preview not to queue jobs, got %d", len(jobs))
+	}
+
+	update.ChannelPost.Chat = &telegram.Chat{ID: -2}
+	if _, err := p.Preview(context.Background(), update); err == nil {
+		t.Error("expected an error for a chat without channel")
+	}
+}
+
+// newFakeTelegram serves getFile and the file downloads of a Bot API with the given files
+func newFakeTelegram(t *testing.T, files map[string][]byte) *telegram.Client {
+	t.Helper()
+
+	const token = "123456:test-token"
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /bot"+token+"/getFile", func(w http.ResponseWriter, r *http.Request) {
+		var req telegram.GetFileRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if _, ok := files[req.FileID]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"ok":false,"error_code":400,"description":"Bad Request: invalid file_id"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"ok":true,"result":{"file_id":%q,"file_unique_id":"u","file_size":%d,"file_path":"photos/%s"}}`,
+			req.FileID, len(files[req.FileID]), req.FileID)
+	})
+	mux.HandleFunc("GET /file/bot"+token+"/photos/{fileID}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(files[r.PathValue("fileID")])
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return telegram.NewClient(token, nil).WithAPIURL(server.URL)
+}
+
+func TestPipeline_PublishesPhotoPostAndEdit(t *testing.T) {
+	service, repoDir, remoteDir := newTestRepo(t)
+	png := []byte("\x89PNG\r\n\x1a\nimage")
+	client := newFakeTelegram(t, map[string][]byte{"small": []byte("thumb"), "large": png})
+
+	channel := Channel{ChatID: -1, Zola: zola.NewService(filepath.Join(repoDir, "content", "posts"), "content/posts", repoDir, "@channel", service, "")}
+	p := New(client, service, []Channel{channel}, nil).WithBatchWait(10 * time.Millisecond)
+	p.Start()
+	defer p.Close()
+
+	chat := &telegram.Chat{ID: -1, Type: "channel"}
+	err := p.HandleUpdate(context.Background(), &telegram.Update{
+		UpdateID: 1,
+		ChannelPost: &telegram.Message{
+			MessageID: 7,
+			Chat:      chat,
+			Caption:   "A photo",
+			Photo:     []telegram.PhotoSize{{FileID: "small", Width: 90}, {FileID: "large", Width: 1280}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+	waitForStatus(t, p.queue, 1, JobPublished)
+
+	files := remoteFiles(t, remoteDir)
+	if files["content/posts/7/image_0.png"] != string(png) {
+		t.Errorf("expected the largest photo size to be published, got files %v", slices.Collect(maps.Keys(files)))
+	}
+	if !strings.Contains(files["content/posts/7/index.md"], "A photo") {
+		t.Errorf("expected the caption in the post, got %q", files["content/posts/7/index.md"])
+	}
+
+	err = p.HandleUpdate(context.Background(), &telegram.Update{
+		UpdateID:          2,
+		EditedChannelPost: &telegram.Message{MessageID: 7, Chat: chat, Caption: "An edited photo"},
+	})
+	if err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+	waitForStatus(t, p.queue, 2, JobPublished)
+
+	files = remoteFiles(t, remoteDir)
+	if !strings.Contains(files["content/posts/7/index.md"], "An edited photo") {
+		t.Errorf("expected the edited caption in the post, got %q", files["content/posts/7/index.md"])
+	}
+	if files["content/posts/7/image_0.png"] != string(png) {
+		t.Error("expected an edit without photo to keep the image")
+	}
+}
+
+func TestPipeline_DeletesAlbumByLaterMessageWithoutChat(t *testing.T) {
+	service, repoDir, remoteDir := newTestRepo(t)
+	client := newFakeTelegram(t, map[string][]byte{"large": []byte("\x89PNG\r\n\x1a\nimage")})
+
+	channel := Channel{ChatID: -1, Zola: zola.NewService(filepath.Join(repoDir, "content", "posts"), "content/posts", repoDir, "@channel", service, "")}
+	p := New(client, service, []Channel{channel}, nil).
+		WithBatchWait(10 * time.Millisecond).
+		WithMediaGroupWait(10 * time.Millisecond)
+	p.Start()
+	defer p.Close()
+
+	chat := &telegram.Chat{ID: -1, Type: "channel"}
+	for i, id := range []int64{7, 8} {
+		err := p.HandleUpdate(context.Background(), &telegram.Update{
+			UpdateID: int64(i + 1),
+			ChannelPost: &telegram.Message{
+				MessageID:    id,
+				Chat:         chat,
+				MediaGroupID: "album",
+				Photo:        []telegram.PhotoSize{{FileID: "large"}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("HandleUpdate failed: %v", err)
+		}
+	}
+	waitForStatus(t, p.queue, 1, JobPublished)
+	if files := remoteFiles(t, remoteDir); files["content/posts/7/image_1.png"] == "" {
+		t.Fatalf("expected the album to be published as post 7, got files %v", slices.Collect(maps.Keys(files)))
+	}
+
+	// Without chat_id the single channel is used, and its chat finds the post of message 8
+	job, err := p.DeletePosts(0, "8")
+	if err != nil {
+		t.Fatalf("DeletePosts failed: %v", err)
+	}
+	if job.ChatID != -1 {
+		t.Errorf("expected the job to record the chat of the channel, got %d", job.ChatID)
+	}
+	waitForStatus(t, p.queue, job.ID, JobPublished)
+	for name := range remoteFiles(t, remoteDir) {
+		if strings.HasPrefix(name, "content/posts/7/") {
+			t.Errorf("expected the album to be deleted, found %s", name)
+		}
+	}
+
+	unknown, err := p.DeletePosts(0, "99")
+	if err != nil {
+		t.Fatalf("DeletePosts failed: %v", err)
+	}
+	waitForStatus(t, p.queue, unknown.ID, JobFailed)
+	if job, _ := p.Job(unknown.ID); !strings.Contains(job.Error, "post not found") {
+		t.Errorf("expected an unknown post to fail the job, got %q", job.Error)
+	}
+}
+
+func TestPipeline_PhotoDownloadFails(t *testing.T) {
+	service, repoDir, remoteDir := newTestRepo(t)
+	client := newFakeTelegram(t, nil)
+
+	channel := Channel{ChatID: -1, Zola: zola.NewService(filepath.Join(repoDir, "content", "posts"), "content/posts", repoDir, "@channel", service, "")}
+	p := New(client, service, []Channel{channel}, nil).WithBatchWait(10 * time.Millisecond)
+	p.Start()
+	defer p.Close()
+
+	err := p.HandleUpdate(context.Background(), &telegram.Update{
+		UpdateID:    1,
+		ChannelPost: &telegram.Message{MessageID: 7, Chat: &telegram.Chat{ID: -1}, Photo: []telegram.PhotoSize{{FileID: "missing"}}},
+	})
+	if err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+	waitForStatus(t, p.queue, 1, JobFailed)
+
+	job, _ := p.Job(1)
+	if !strings.Contains(job.Error, "failed to download photo of message 7") {
+		t.Errorf("expected the download error, got %q", job.Error)
+	}
+	if len(remoteCommits(t, remoteDir)) != 1 {
+		t.Error("expected nothing to be published")
+	}
+}
+
+func TestPipeline_FailUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	p := New(nil, nil, []Channel{{}}, nil).WithJournal(NewJournal(path))
+	defer p.Close()
+
+	update := &telegram.Update{UpdateID: 42, ChannelPost: &telegram.Message{MessageID: 7, Chat: &telegram.Chat{ID: -1}}}
+	p.FailUpdate(update, errors.New("disk full"))
+
+	jobs := p.Jobs()
+	if len(jobs) != 1 || jobs[0].Status != JobFailed || jobs[0].Error != "disk full" || jobs[0].Summary != "Add post: 7" {
+		t.Fatalf("expected a failed job for the update, got %+v", jobs)
+	}
+
+	// The failed job survives a restart and the update is not published if it comes again
+	journal, err := LoadJournal(path)
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	restarted := New(nil, nil, []Channel{{}}, nil).WithJournal(journal)
+	defer restarted.Close()
+
+	if err := restarted.HandleUpdate(context.Background(), update); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+	jobs = restarted.Jobs()
+	if len(jobs) != 1 || jobs[0].ID != 1 || jobs[0].Status != JobFailed {
+		t.Errorf("expected only the failed job after the restart, got %+v", jobs)
+	}
+}
+
+func TestPipeline_AlbumThatCannotBeQueuedFails(t *testing.T) {
+	p := New(nil, nil, []Channel{{}}, nil)
+
+	for i, id := range []int64{7, 8} {
+		msg := albumMessage(id, "g1", "")
+		msg.Chat = &telegram.Chat{ID: -1}
+		if err := p.HandleUpdate(context.Background(), &telegram.Update{UpdateID: int64(100 + i), ChannelPost: msg}); err != nil {
+			t.Fatalf("HandleUpdate failed: %v", err)
+		}
+	}
+	// The queue is closed before the album is flushed, so it cannot be queued
+	p.queue.Close()
+	p.albums.Close()
+
+	jobs := p.Jobs()
+	if len(jobs) != 1 || jobs[0].Status != JobFailed || len(jobs[0].Messages) != 2 || !slices.Equal(jobs[0].UpdateIDs, []int64{100, 101}) {
+		t.Fatalf("expected the album to be listed as a failed job, got %+v", jobs)
+	}
+	if !strings.Contains(jobs[0].Error, "failed to queue album") {
+		t.Errorf("expected the queue error, got %q", jobs[0].Error)
+	}
+}
+
+func TestPipeline_RestoresBufferedAlbum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	clock := &fakeClock{}
+	p := New(nil, nil, []Channel{{}}, nil)
+	p.albums.afterFunc = clock.afterFunc
+	p.WithJournal(NewJournal(path))
+
+	for i, id := range []int64{7, 8} {
+		msg := albumMessage(id, "g1", "")
+		msg.Chat = &telegram.Chat{ID: -1}
+		if err := p.HandleUpdate(context.Background(), &telegram.Update{UpdateID: int64(100 + i), ChannelPost: msg}); err != nil {
+			t.Fatalf("HandleUpdate failed: %v", err)
+		}
+	}
+	// The process stops before the album is flushed
+
+	journal, err := LoadJournal(path)
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	clock = &fakeClock{}
+	restarted := New(nil, nil, []Channel{{}}, nil)
+	restarted.albums.afterFunc = clock.afterFunc
+	restarted.WithJournal(journal)
+
+	// Telegram redelivers an update it got no answer for; it is buffered once
+	msg := albumMessage(8, "g1", "")
+	msg.Chat = &telegram.Chat{ID: -1}
+	if err := restarted.HandleUpdate(context.Background(), &telegram.Update{UpdateID: 101, ChannelPost: msg}); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+
+	clock.advance(DefaultMediaGroupWait)
+
+	jobs := restarted.Jobs()
+	if len(jobs) != 1 || jobs[0].Status != JobQueued || len(jobs[0].Messages) != 2 || !slices.Equal(jobs[0].UpdateIDs, []int64{100, 101}) {
+		t.Fatalf("expected the restored album to be queued, got %+v", jobs)
+	}
+	if albums := journal.PendingAlbums(); len(albums) != 0 {
+		t.Errorf("expected the queued album to leave the journal's buffer, got %+v", albums)
+	}
+}
@@ -0,0 +1,652 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/en9inerd/postpal/internal/forge"
+	"github.com/en9inerd/postpal/internal/git"
+	"github.com/en9inerd/postpal/internal/telegram"
+)
+
+// Job kinds
+const (
+	JobCreate = "create"
+	JobEdit   = "edit"
+	JobDelete = "delete"
+)
+
+// Job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobStaged    = "staged" // Changes are staged and wait for the batch commit
+	JobPublished = "published"
+	JobFailed    = "failed"
+)
+
+const (
+	// DefaultBatchWait is how long the queue waits for another job before committing staged changes
+	DefaultBatchWait = time.Second
+	// DefaultMaxBatch is the maximum number of jobs in a single commit
+	DefaultMaxBatch = 20
+
+	jobHistorySize = 100
+
+	// conflictBranchPrefix names the branches that keep commits conflicting with the remote branch
+	conflictBranchPrefix = "postpal/conflict-"
+)
+
+// ErrQueueClosed is returned when a job is enqueued after the queue was closed
+var ErrQueueClosed = errors.New("publish queue is closed")
+
+// Job is a change to the site repository
+type Job struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	Summary   string    `json:"summary"` // Commit message line, e.g. "Add post: 42"
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UpdateIDs []int64             `json:"update_ids,omitempty"` // Telegram updates the job was queued for
+	Messages  []*telegram.Message `json:"messages,omitempty"`   // Messages to create or edit a post from
+	ChatID    int64               `json:"chat_id,omitempty"`    // Chat of the posts to delete
+	PostIDs   string              `json:"post_ids,omitempty"`   // Comma-separated posts to delete
+
+	Branch           string `json:"branch,omitempty"`             // Branch the job was published on in branch mode
+	ChangeRequestURL string `json:"change_request_url,omitempty"` // Change request opened for the branch
+}
+
+// repository is the part of git.Service used by the queue
+type repository interface {
+	Commit(message string) error
+	Push(ctx context.Context) error
+	Discard() error
+	SaveBranch(ctx context.Context, name string) error
+	ResetToRemote(ctx context.Context) error
+	StartBranch(ctx context.Context, name string) error
+	CheckoutBase() error
+	Branch() string
+}
+
+// postIndex is a post index kept in step with the commits of the queue. Its changes are saved
+// once they are committed and reverted when the changes of their posts are discarded.
+type postIndex interface {
+	SaveIndex() error
+	RevertIndex()
+	RebuildIndex() (int, error)
+}
+
+// Queue is a single-writer job queue for the site repository.
+// Jobs run one at a time; the changes of jobs that arrive within the batch window
+// of each other are published with a single commit.
+type Queue struct {
+	exec      func(ctx context.Context, job *Job) error
+	repo      repository
+	batchWait time.Duration
+	maxBatch  int
+	journal   *Journal
+	logger    *slog.Logger
+
+	branchName func(job *Job) string // Set in branch mode
+	requester  forge.ChangeRequester
+	indexes    []postIndex
+
+	mu       sync.Mutex
+	nextID   int64
+	pending  []*Job
+	jobs     map[int64]*Job
+	history  []int64
+	started  bool
+	closed   bool
+	unpushed bool // a commit failed to push and goes out with the next push
+	wake     chan struct{}
+	done     chan struct{}
+}
+
+// NewQueue creates a Queue that runs jobs with exec and commits to repo.
+// Start must be called before jobs are processed.
+func NewQueue(exec func(ctx context.Context, job *Job) error, repo repository, logger *slog.Logger) *Queue {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Queue{
+		exec:      exec,
+		repo:      repo,
+		batchWait: DefaultBatchWait,
+		maxBatch:  DefaultMaxBatch,
+		journal:   NewJournal(""),
+		logger:    logger,
+		jobs:      make(map[int64]*Job),
+		wake:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+// WithBatchWait sets how long to wait for more jobs before committing
+func (q *Queue) WithBatchWait(wait time.Duration) *Queue {
+	q.batchWait = wait
+	return q
+}
+
+// WithMaxBatch sets the maximum number of jobs in a single commit
+func (q *Queue) WithMaxBatch(max int) *Queue {
+	q.maxBatch = max
+	return q
+}
+
+// WithBranchPublishing publishes every job on its own branch, named by branchName, and opens
+// a change request for the branch with requester instead of committing to the base branch.
+// Jobs are not batched in this mode.
+func (q *Queue) WithBranchPublishing(branchName func(job *Job) string, requester forge.ChangeRequester) *Queue {
+	q.branchName = branchName
+	q.requester = requester
+	return q
+}
+
+// WithIndexes keeps the post indexes the jobs change in step with the repository
+func (q *Queue) WithIndexes(indexes ...postIndex) *Queue {
+	q.indexes = append(q.indexes, indexes...)
+	return q
+}
+
+// WithJournal persists jobs in journal and queues the jobs it holds from before a restart.
+// The worktree must not contain partial changes of the interrupted jobs. It must be called before Start.
+func (q *Queue) WithJournal(journal *Journal) *Queue {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.journal = journal
+	q.nextID = max(q.nextID, journal.NextID)
+	q.unpushed = journal.Unpushed
+	for _, job := range journal.FailedJobs() {
+		failed := &job
+		q.remember(failed)
+	}
+	for _, job := range journal.Pending() {
+		job.Status = JobQueued
+		job.Error = ""
+		job.UpdatedAt = time.Now()
+
+		recovered := &job
+		q.pending = append(q.pending, recovered)
+		q.remember(recovered)
+	}
+	return q
+}
+
+// Start runs the worker that processes jobs
+func (q *Queue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.started {
+		q.started = true
+		go q.run()
+	}
+}
+
+// Close stops accepting jobs, publishes the jobs already queued and waits for the worker to exit
+func (q *Queue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	started := q.started
+	q.mu.Unlock()
+
+	if started {
+		q.notify()
+		<-q.done
+	}
+}
+
+// Enqueue journals a job and returns a snapshot of it with its assigned ID.
+// It returns ErrDuplicateUpdate if one of the job's updates was already queued.
+func (q *Queue) Enqueue(job Job) (Job, error) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return Job{}, ErrQueueClosed
+	}
+
+	now := time.Now()
+	job.ID = q.nextID + 1
+	job.Status = JobQueued
+	job.Error = ""
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	if err := q.journal.add(job); err != nil {
+		q.mu.Unlock()
+		if errors.Is(err, ErrDuplicateUpdate) {
+			return Job{}, err
+		}
+		return Job{}, fmt.Errorf("failed to journal job: %w", err)
+	}
+	q.nextID = job.ID
+
+	queued := &job
+	q.pending = append(q.pending, queued)
+	q.remember(queued)
+	snapshot := *queued
+	q.mu.Unlock()
+
+	q.notify()
+	return snapshot, nil
+}
+
+// Fail records a job that could not be queued, e.g. for an update that kept failing,
+// and returns a snapshot of it. The job is kept in the journal and never run. It is listed
+// with the recent jobs even if the journal cannot be written.
+func (q *Queue) Fail(job Job, cause error) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	job.ID = q.nextID + 1
+	job.Status = JobFailed
+	job.Error = cause.Error()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	journalErr := q.journal.fail(job)
+	q.nextID = job.ID
+
+	failed := &job
+	q.remember(failed)
+	if journalErr != nil {
+		return *failed, fmt.Errorf("failed to journal job: %w", journalErr)
+	}
+	return *failed, nil
+}
+
+// AddAlbumMessage journals a message of an album that is buffered until the album is complete.
+// It returns ErrDuplicateUpdate if the update was already queued or buffered.
+func (q *Queue) AddAlbumMessage(updateID int64, msg *telegram.Message) error {
+	q.mu.Lock()
+	closed := q.closed
+	q.mu.Unlock()
+	if closed {
+		return ErrQueueClosed
+	}
+
+	err := q.journal.addAlbumMessage(updateID, msg)
+	if err != nil && !errors.Is(err, ErrDuplicateUpdate) {
+		return fmt.Errorf("failed to journal album message: %w", err)
+	}
+	return err
+}
+
+// PendingAlbums returns the journaled album messages whose album is not queued yet
+func (q *Queue) PendingAlbums() []AlbumMessage {
+	return q.journal.PendingAlbums()
+}
+
+// HasUpdate reports whether a job was already queued for the Telegram update
+func (q *Queue) HasUpdate(updateID int64) bool {
+	return q.journal.HasUpdate(updateID)
+}
+
+// Job returns a snapshot of a recent job
+func (q *Queue) Job(id int64) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Jobs returns snapshots of recent jobs, oldest first
+func (q *Queue) Jobs() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]Job, 0, len(q.history))
+	for _, id := range q.history {
+		jobs = append(jobs, *q.jobs[id])
+	}
+	return jobs
+}
+
+// remember records a job for status lookups, forgetting the oldest finished jobs. q.mu must be held.
+func (q *Queue) remember(job *Job) {
+	q.jobs[job.ID] = job
+	q.history = append(q.history, job.ID)
+
+	for len(q.history) > jobHistorySize {
+		oldest := q.jobs[q.history[0]]
+		if oldest.Status != JobPublished && oldest.Status != JobFailed {
+			break
+		}
+		delete(q.jobs, oldest.ID)
+		q.history = q.history[1:]
+	}
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the next queued job. With a batch in progress it gives up after the batch window.
+// It returns false once the queue is closed and drained.
+func (q *Queue) next(batching bool) (*Job, bool) {
+	var timeout <-chan time.Time
+	if batching {
+		timer := time.NewTimer(q.batchWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		q.mu.Lock()
+		if len(q.pending) > 0 {
+			job := q.pending[0]
+			q.pending = q.pending[1:]
+			q.mu.Unlock()
+			return job, true
+		}
+		closed := q.closed
+		q.mu.Unlock()
+
+		if closed {
+			return nil, false
+		}
+
+		select {
+		case <-q.wake:
+		case <-timeout:
+			return nil, true
+		}
+	}
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+
+	// Jobs run to completion on shutdown, so they do not share a cancellable context
+	ctx := context.Background()
+
+	var batch []*Job
+	for {
+		job, ok := q.next(len(batch) > 0)
+		if job == nil {
+			if len(batch) > 0 {
+				q.publish(ctx, batch)
+				batch = nil
+			}
+			if !ok {
+				return
+			}
+			continue
+		}
+
+		if q.branchName != nil {
+			q.publishBranch(ctx, job)
+			continue
+		}
+
+		q.setStatus(job, JobRunning, nil)
+		if err := q.exec(ctx, job); err != nil {
+			q.logger.Error("publish job failed", "job_id", job.ID, "kind", job.Kind, "summary", job.Summary, "error", err)
+			q.setStatus(job, JobFailed, err)
+			q.keepFailed([]*Job{job})
+			q.forget([]*Job{job})
+			batch = q.discard(batch)
+			continue
+		}
+
+		q.setStatus(job, JobStaged, nil)
+		batch = append(batch, job)
+		if len(batch) >= q.maxBatch {
+			q.publish(ctx, batch)
+			batch = nil
+		}
+	}
+}
+
+// discard drops the partial changes of a failed job. The staged changes of the batch
+// are dropped with them, so its jobs are queued again to be redone.
+func (q *Queue) discard(batch []*Job) []*Job {
+	if err := q.repo.Discard(); err != nil {
+		q.logger.Error("failed to discard changes of failed job", "error", err)
+	}
+	q.revertIndexes()
+
+	q.mu.Lock()
+	for _, job := range batch {
+		job.Status = JobQueued
+		job.UpdatedAt = time.Now()
+	}
+	q.pending = append(batch, q.pending...)
+	q.mu.Unlock()
+
+	return nil
+}
+
+// publish commits the staged changes of the batch and pushes them
+func (q *Queue) publish(ctx context.Context, batch []*Job) {
+	message := commitMessage(batch)
+
+	err := q.repo.Commit(message)
+	if errors.Is(err, git.ErrNoChanges) {
+		q.logger.Info("nothing to publish", "message", message)
+		q.saveIndexes()
+		if !q.unpushed {
+			q.forget(batch)
+			q.finish(batch, nil)
+			return
+		}
+	} else if err != nil {
+		q.finish(batch, fmt.Errorf("failed to commit: %w", err))
+		q.keepFailed(batch)
+		q.forget(batch)
+		q.discard(nil)
+		return
+	} else {
+		q.saveIndexes()
+	}
+
+	err = q.repo.Push(ctx)
+	if errors.Is(err, git.ErrConflict) {
+		// The remote branch was edited by hand. Our commits are kept on a branch of their own to be
+		// merged by hand, and the base branch follows the remote again so later pushes go through.
+		err = fmt.Errorf("failed to push: %w", err)
+		branch := fmt.Sprintf("%s%d", conflictBranchPrefix, time.Now().Unix())
+		if saveErr := q.repo.SaveBranch(ctx, branch); saveErr != nil {
+			q.logger.Error("failed to save conflicting commits", "branch", branch, "error", saveErr)
+			q.unpushed = true
+		} else if resetErr := q.repo.ResetToRemote(ctx); resetErr != nil {
+			q.logger.Error("failed to reset to remote branch", "error", resetErr)
+			q.unpushed = true
+			err = fmt.Errorf("%w; the changes were saved to branch %s", err, branch)
+		} else {
+			q.unpushed = false
+			q.rebuildIndexes()
+			err = fmt.Errorf("%w; the changes were saved to branch %s", err, branch)
+		}
+		q.finish(batch, err)
+		q.keepFailed(batch)
+		q.forget(batch)
+		return
+	}
+	if err != nil {
+		// The commit stays in the local repository and is pushed with the next batch
+		q.unpushed = true
+		q.finish(batch, fmt.Errorf("failed to push: %w", err))
+		q.keepFailed(batch)
+		q.forget(batch)
+		return
+	}
+	q.unpushed = false
+	q.forget(batch)
+
+	q.logger.Info("published changes", "message", message, "jobs", len(batch))
+	q.finish(batch, nil)
+}
+
+// publishBranch runs a job on its own branch, pushes the branch and opens a change request for it.
+// The base branch is checked out again afterwards.
+func (q *Queue) publishBranch(ctx context.Context, job *Job) {
+	branch := q.branchName(job)
+	q.mu.Lock()
+	job.Branch = branch
+	q.mu.Unlock()
+	q.setStatus(job, JobRunning, nil)
+
+	err := q.runOnBranch(ctx, job, branch)
+	if checkoutErr := q.repo.CheckoutBase(); checkoutErr != nil {
+		q.logger.Error("failed to check out base branch", "error", checkoutErr)
+	}
+	if err != nil {
+		q.logger.Error("publish job failed", "job_id", job.ID, "kind", job.Kind, "branch", branch, "error", err)
+		q.revertIndexes()
+		q.setStatus(job, JobFailed, err)
+		q.keepFailed([]*Job{job})
+		return
+	}
+	q.forget([]*Job{job})
+	q.setStatus(job, JobPublished, nil)
+}
+
+func (q *Queue) runOnBranch(ctx context.Context, job *Job, branch string) error {
+	if err := q.repo.StartBranch(ctx, branch); err != nil {
+		return err
+	}
+	if err := q.exec(ctx, job); err != nil {
+		return err
+	}
+
+	err := q.repo.Commit(job.Summary)
+	if errors.Is(err, git.ErrNoChanges) {
+		q.logger.Info("nothing to publish", "message", job.Summary, "branch", branch)
+		q.saveIndexes()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	if err := q.repo.Push(ctx); err != nil {
+		return fmt.Errorf("failed to push: %w", err)
+	}
+	q.saveIndexes()
+
+	url, err := q.requester.OpenChangeRequest(ctx, forge.ChangeRequest{
+		Branch: branch,
+		Base:   q.repo.Branch(),
+		Title:  job.Summary,
+		Body:   "Published from Telegram by PostPal.",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open change request: %w", err)
+	}
+
+	q.mu.Lock()
+	job.ChangeRequestURL = url
+	q.mu.Unlock()
+
+	q.logger.Info("published change request", "message", job.Summary, "branch", branch, "url", url)
+	return nil
+}
+
+// saveIndexes persists the index changes of committed jobs
+func (q *Queue) saveIndexes() {
+	for _, index := range q.indexes {
+		if err := index.SaveIndex(); err != nil {
+			q.logger.Error("failed to save post index", "error", err)
+		}
+	}
+}
+
+// revertIndexes drops the index changes of discarded jobs
+func (q *Queue) revertIndexes() {
+	for _, index := range q.indexes {
+		index.RevertIndex()
+	}
+}
+
+// rebuildIndexes reads the indexes from the posts after the repository was reset to the remote,
+// which drops committed posts as well
+func (q *Queue) rebuildIndexes() {
+	for _, index := range q.indexes {
+		if _, err := index.RebuildIndex(); err != nil {
+			q.logger.Error("failed to rebuild post index", "error", err)
+		}
+	}
+}
+
+// forget removes jobs that need no replay after a restart from the journal
+func (q *Queue) forget(jobs []*Job) {
+	ids := make([]int64, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID
+	}
+	if err := q.journal.remove(ids, q.unpushed); err != nil {
+		q.logger.Error("failed to update publish journal", "error", err)
+	}
+}
+
+// keepFailed moves failed jobs from the pending to the failed jobs of the journal,
+// so they are still listed after a restart
+func (q *Queue) keepFailed(jobs []*Job) {
+	q.mu.Lock()
+	failed := make([]Job, len(jobs))
+	for i, job := range jobs {
+		failed[i] = *job
+	}
+	q.mu.Unlock()
+
+	if err := q.journal.fail(failed...); err != nil {
+		q.logger.Error("failed to update publish journal", "error", err)
+	}
+}
+
+func (q *Queue) finish(batch []*Job, err error) {
+	status := JobPublished
+	if err != nil {
+		status = JobFailed
+		q.logger.Error("failed to publish changes", "jobs", len(batch), "error", err)
+	}
+	for _, job := range batch {
+		q.setStatus(job, status, err)
+	}
+}
+
+func (q *Queue) setStatus(job *Job, status string, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job.Status = status
+	job.UpdatedAt = time.Now()
+	job.Error = ""
+	if err != nil {
+		job.Error = err.Error()
+	}
+}
+
+// commitMessage uses the summary of a single job, or lists the summaries of a batch
+func commitMessage(batch []*Job) string {
+	if len(batch) == 1 {
+		return batch[0].Summary
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Publish %d changes\n", len(batch))
+	for _, job := range batch {
+		sb.WriteString("\n- ")
+		sb.WriteString(job.Summary)
+	}
+	return sb.String()
+}
@@ -0,0 +1,615 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/en9inerd/postpal/internal/forge"
+	"github.com/en9inerd/postpal/internal/git"
+	gogit "github.com/go-git/go-git/v6"
+	gitconfig "github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// newTestRepo clones a local bare repository and returns the clone's git service and both paths
+func newTestRepo(t *testing.T) (*git.Service, string, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	remoteDir := filepath.Join(root, "remote.git")
+	seedDir := filepath.Join(root, "seed")
+	repoDir := filepath.Join(root, "site")
+
+	if _, err := gogit.PlainInit(remoteDir, true, gogit.WithDefaultBranch(plumbing.NewBranchReferenceName("main"))); err != nil {
+		t.Fatalf("failed to init bare repo: %v", err)
+	}
+
+	seed, err := gogit.PlainInit(seedDir, false, gogit.WithDefaultBranch(plumbing.NewBranchReferenceName("main")))
+	if err != nil {
+		t.Fatalf("failed to init seed repo: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(seedDir, "README.md"), []byte("site"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	wt, _ := seed.Worktree()
+	if _, err := wt.Add("README.md"); err != nil {
+		t.Fatalf("failed to add file: %v", err)
+	}
+	if _, err := wt.Commit("init", &gogit.CommitOptions{Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if _, err := seed.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteDir}}); err != nil {
+		t.Fatalf("failed to create remote: %v", err)
+	}
+	if err := seed.Push(&gogit.PushOptions{RemoteName: "origin"}); err != nil {
+		t.Fatalf("failed to push seed: %v", err)
+	}
+
+	service := git.NewService(repoDir, remoteDir, "main", "", git.Author{Name: "PostPal", Email: "postpal@localhost"})
+	if err := service.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	return service, repoDir, remoteDir
+}
+
+// writeFileJob returns an exec func that writes and stages the file named by each job's summary
+func writeFileJob(service *git.Service, repoDir string) func(ctx context.Context, job *Job) error {
+	return func(ctx context.Context, job *Job) error {
+		if strings.HasPrefix(job.Summary, "fail") {
+			// Leave a partial change behind, as a job failing halfway would
+			if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("partial"), 0644); err != nil {
+				return err
+			}
+			if err := service.Add("README.md"); err != nil {
+				return err
+			}
+			return errors.New("job failed")
+		}
+
+		name := job.Summary + ".md"
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(job.Summary), 0644); err != nil {
+			return err
+		}
+		return service.Add(name)
+	}
+}
+
+func remoteCommits(t *testing.T, remoteDir string) []*object.Commit {
+	t.Helper()
+	return remoteBranchCommits(t, remoteDir, "main")
+}
+
+func remoteBranchCommits(t *testing.T, remoteDir, branch string) []*object.Commit {
+	t.Helper()
+
+	remote, err := gogit.PlainOpen(remoteDir)
+	if err != nil {
+		t.Fatalf("failed to open remote: %v", err)
+	}
+	ref, err := remote.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		t.Fatalf("failed to resolve %s: %v", branch, err)
+	}
+	iter, err := remote.Log(&gogit.LogOptions{From: ref.Hash()})
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+
+	var commits []*object.Commit
+	iter.ForEach(func(c *object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	return commits
+}
+
+func remoteFiles(t *testing.T, remoteDir string) map[string]string {
+	t.Helper()
+	return remoteBranchFiles(t, remoteDir, "main")
+}
+
+func remoteBranchFiles(t *testing.T, remoteDir, branch string) map[string]string {
+	t.Helper()
+
+	commit := remoteBranchCommits(t, remoteDir, branch)[0]
+	tree, err := commit.Tree()
+	if err != nil {
+		t.Fatalf("failed to get tree: %v", err)
+	}
+
+	files := make(map[string]string)
+	tree.Files().ForEach(func(f *object.File) error {
+		content, _ := f.Contents()
+		files[f.Name] = content
+		return nil
+	})
+	return files
+}
+
+func TestQueue_SerializesConcurrentJobs(t *testing.T) {
+	service, repoDir, remoteDir := newTestRepo(t)
+
+	queue := NewQueue(writeFileJob(service, repoDir), service, nil).
+		WithBatchWait(200 * time.Millisecond)
+	queue.Start()
+
+	const workers, perWorker = 8, 5
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Go(func() {
+			for i := range perWorker {
+				if _, err := queue.Enqueue(Job{Kind: JobCreate, Summary: fmt.Sprintf("post-%d-%d", w, i)}); err != nil {
+					t.Errorf("Enqueue failed: %v", err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+	queue.Close()
+
+	files := remoteFiles(t, remoteDir)
+	for w := range workers {
+		for i := range perWorker {
+			name := fmt.Sprintf("post-%d-%d", w, i)
+			if files[name+".md"] != name {
+				t.Errorf("expected %s.md to be published, got %q", name, files[name+".md"])
+			}
+		}
+	}
+
+	// 40 jobs enqueued at once fit in two batches of DefaultMaxBatch
+	if commits := remoteCommits(t, remoteDir); len(commits) != 3 {
+		t.Errorf("expected 2 batch commits after the initial commit, got %d commits", len(commits))
+	}
+
+	for _, job := range queue.Jobs() {
+		if job.Status != JobPublished {
+			t.Errorf("expected job %d to be published, got %s (%s)", job.ID, job.Status, job.Error)
+		}
+	}
+}
+
+func TestQueue_BatchesJobsArrivingTogether(t *testing.T) {
+	service, repoDir, remoteDir := newTestRepo(t)
+
+	queue := NewQueue(writeFileJob(service, repoDir), service, nil).
+		WithBatchWait(300 * time.Millisecond)
+	queue.Start()
+	defer queue.Close()
+
+	first, _ := queue.Enqueue(Job{Kind: JobCreate, Summary: "a"})
+	second, _ := queue.Enqueue(Job{Kind: JobEdit, Summary: "b"})
+
+	waitForStatus(t, queue, second.ID, JobPublished)
+
+	commits := remoteCommits(t, remoteDir)
+	if len(commits) != 2 {
+		t.Fatalf("expected a single batch commit, got %d commits", len(commits))
+	}
+	if msg := commits[0].Message; msg != "Publish 2 changes\n\n- a\n- b" {
+		t.Errorf("unexpected commit message: %q", msg)
+	}
+
+	if job, _ := queue.Job(first.ID); job.Status != JobPublished {
+		t.Errorf("expected first job to be published, got %s", job.Status)
+	}
+
+	// A job arriving after the batch window gets its own commit
+	third, _ := queue.Enqueue(Job{Kind: JobCreate, Summary: "c"})
+	waitForStatus(t, queue, third.ID, JobPublished)
+
+	commits = remoteCommits(t, remoteDir)
+	if len(commits) != 3 || commits[0].Message != "c" {
+		t.Errorf("expected a separate commit 'c', got %d commits, latest %q", len(commits), commits[0].Message)
+	}
+}
+
+func TestQueue_FailedJobDoesNotLeakIntoCommit(t *testing.T) {
+	service, repoDir, remoteDir := newTestRepo(t)
+
+	queue := NewQueue(writeFileJob(service, repoDir), service, nil).
+		WithBatchWait(300 * time.Millisecond)
+	queue.Start()
+
+	queue.Enqueue(Job{Kind: JobCreate, Summary: "before"})
+	failed, _ := queue.Enqueue(Job{Kind: JobEdit, Summary: "fail"})
+	queue.Enqueue(Job{Kind: JobCreate, Summary: "after"})
+	queue.Close()
+
+	job, _ := queue.Job(failed.ID)
+	if job.Status != JobFailed || job.Error != "job failed" {
+		t.Errorf("expected failed job with error, got %s (%s)", job.Status, job.Error)
+	}
+
+	files := remoteFiles(t, remoteDir)
+	if files["README.md"] != "site" {
+		t.Errorf("expected partial change of the failed job to be discarded, got %q", files["README.md"])
+	}
+	if files["before.md"] != "before" || files["after.md"] != "after" {
+		t.Errorf("expected other jobs to be published, got %v", files)
+	}
+
+	for _, job := range queue.Jobs() {
+		if job.ID != failed.ID && job.Status != JobPublished {
+			t.Errorf("expected job %d to be published, got %s", job.ID, job.Status)
+		}
+	}
+}
+
+// fakeIndex counts how often the queue saves, reverts and rebuilds it
+type fakeIndex struct {
+	mu                       sync.Mutex
+	saves, reverts, rebuilds int
+}
+
+func (f *fakeIndex) SaveIndex() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.saves++
+	return nil
+}
+
+func (f *fakeIndex) RevertIndex() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reverts++
+}
+
+func (f *fakeIndex) RebuildIndex() (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rebuilds++
+	return 0, nil
+}
+
+func (f *fakeIndex) counts() (saves, reverts, rebuilds int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.saves, f.reverts, f.rebuilds
+}
+
+func TestQueue_KeepsIndexInStepWithCommits(t *testing.T) {
+	service, repoDir, _ := newTestRepo(t)
+
+	index := &fakeIndex{}
+	queue := NewQueue(writeFileJob(service, repoDir), service, nil).
+		WithBatchWait(50 * time.Millisecond).
+		WithIndexes(index)
+	queue.Start()
+	defer queue.Close()
+
+	failed, _ := queue.Enqueue(Job{Kind: JobEdit, Summary: "fail"})
+	waitForStatus(t, queue, failed.ID, JobFailed)
+	if saves, reverts, _ := index.counts(); saves != 0 || reverts != 1 {
+		t.Errorf("expected the failed job to revert the index, got %d saves and %d reverts", saves, reverts)
+	}
+
+	published, _ := queue.Enqueue(Job{Kind: JobCreate, Summary: "published"})
+	waitForStatus(t, queue, published.ID, JobPublished)
+	if saves, reverts, rebuilds := index.counts(); saves != 1 || reverts != 1 || rebuilds != 0 {
+		t.Errorf("expected the committed job to save the index, got %d saves, %d reverts and %d rebuilds", saves, reverts, rebuilds)
+	}
+}
+
+func TestQueue_PushFailureIsRetried(t *testing.T) {
+	service, repoDir, remoteDir := newTestRepo(t)
+
+	// Make the remote unreachable for the first batch
+	hidden := remoteDir + ".hidden"
+	if err := os.Rename(remoteDir, hidden); err != nil {
+		t.Fatalf("failed to hide remote: %v", err)
+	}
+
+	queue := NewQueue(writeFileJob(service, repoDir), service, nil).
+		WithBatchWait(50 * time.Millisecond)
+	queue.Start()
+	defer queue.Close()
+
+	first, _ := queue.Enqueue(Job{Kind: JobCreate, Summary: "first"})
+	waitForStatus(t, queue, first.ID, JobFailed)
+
+	if err := os.Rename(hidden, remoteDir); err != nil {
+		t.Fatalf("failed to restore remote: %v", err)
+	}
+
+	second, _ := queue.Enqueue(Job{Kind: JobCreate, Summary: "second"})
+	waitForStatus(t, queue, second.ID, JobPublished)
+
+	files := remoteFiles(t, remoteDir)
+	if files["first.md"] != "first" || files["second.md"] != "second" {
+		t.Errorf("expected the unpushed commit to go out with the next push, got %v", files)
+	}
+}
+
+func TestQueue_ConflictKeepsBatchOnBranch(t *testing.T) {
+	service, repoDir, remoteDir := newTestRepo(t)
+
+	// Someone edits README.md by hand after our clone
+	writerDir := filepath.Join(t.TempDir(), "writer")
+	writer := git.NewService(writerDir, remoteDir, "main", "", git.Author{Name: "Writer", Email: "writer@example.com"})
+	if err := writer.Bootstrap(context.Background()); err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(writerDir, "README.md"), []byte("theirs"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := writer.Add("README.md"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := writer.CommitAndPush(context.Background(), "Edit README"); err != nil {
+		t.Fatalf("CommitAndPush failed: %v", err)
+	}
+
+	exec := func(ctx context.Context, job *Job) error {
+		name := job.Summary + ".md"
+		if job.Summary == "conflict" {
+			name = "README.md"
+		}
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(job.Summary), 0644); err != nil {
+			return err
+		}
+		return service.Add(name)
+	}
+	journal := NewJournal(filepath.Join(t.TempDir(), "journal.json"))
+	index := &fakeIndex{}
+	queue := NewQueue(exec, service, nil).WithBatchWait(50 * time.Millisecond).WithJournal(journal).WithIndexes(index)
+	queue.Start()
+	defer queue.Close()
+
+	conflict, _ := queue.Enqueue(Job{Kind: JobEdit, Summary: "conflict", UpdateIDs: []int64{7}})
+	waitForStatus(t, queue, conflict.ID, JobFailed)
+	if _, _, rebuilds := index.counts(); rebuilds != 1 {
+		t.Errorf("expected the index to be rebuilt after the reset to the remote, got %d rebuilds", rebuilds)
+	}
+	job, _ := queue.Job(conflict.ID)
+	if !strings.Contains(job.Error, "conflict") {
+		t.Errorf("expected a conflict error, got %q", job.Error)
+	}
+
+	// The conflicting commit is kept on a branch of its own, named in the error
+	_, branch, ok := strings.Cut(job.Error, "saved to branch ")
+	if !ok || !strings.HasPrefix(branch, conflictBranchPrefix) {
+		t.Fatalf("expected the error to name the conflict branch, got %q", job.Error)
+	}
+	if files := remoteBranchFiles(t, remoteDir, branch); files["README.md"] != "conflict" {
+		t.Errorf("expected the conflicting change on %s, got %v", branch, files)
+	}
+
+	// The job stays journaled as failed, so it is still listed after a restart
+	loaded, err := LoadJournal(journal.path)
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	if failed := loaded.FailedJobs(); len(failed) != 1 || failed[0].ID != conflict.ID || failed[0].Error != job.Error {
+		t.Errorf("expected the conflicting job to be journaled as failed, got %+v", failed)
+	}
+	if pending := loaded.Pending(); len(pending) != 0 {
+		t.Errorf("expected no pending jobs, got %+v", pending)
+	}
+
+	next, _ := queue.Enqueue(Job{Kind: JobCreate, Summary: "next"})
+	waitForStatus(t, queue, next.ID, JobPublished)
+
+	files := remoteFiles(t, remoteDir)
+	if files["README.md"] != "theirs" || files["next.md"] != "next" {
+		t.Errorf("expected the remote edit to be kept and later jobs to be published, got %v", files)
+	}
+}
+
+func TestQueue_JournalsFailedJob(t *testing.T) {
+	service, repoDir, _ := newTestRepo(t)
+	journal := NewJournal(filepath.Join(t.TempDir(), "journal.json"))
+
+	queue := NewQueue(writeFileJob(service, repoDir), service, nil).
+		WithBatchWait(50 * time.Millisecond).
+		WithJournal(journal)
+	queue.Start()
+
+	failed, _ := queue.Enqueue(Job{Kind: JobEdit, Summary: "fail", UpdateIDs: []int64{7}})
+	waitForStatus(t, queue, failed.ID, JobFailed)
+	queue.Close()
+
+	// The failed job is still listed after a restart, like a job that failed to push
+	loaded, err := LoadJournal(journal.path)
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	if pending := loaded.Pending(); len(pending) != 0 {
+		t.Errorf("expected no pending jobs, got %+v", pending)
+	}
+	restarted := NewQueue(writeFileJob(service, repoDir), service, nil).WithJournal(loaded)
+	jobs := restarted.Jobs()
+	if len(jobs) != 1 || jobs[0].ID != failed.ID || jobs[0].Status != JobFailed || jobs[0].Error != "job failed" {
+		t.Errorf("expected the failed job after the restart, got %+v", jobs)
+	}
+	if !loaded.HasUpdate(7) {
+		t.Error("expected the update of the failed job to be remembered")
+	}
+}
+
+func TestQueue_EnqueueAfterClose(t *testing.T) {
+	queue := NewQueue(func(ctx context.Context, job *Job) error { return nil }, nil, nil)
+	queue.Start()
+	queue.Close()
+
+	if _, err := queue.Enqueue(Job{Kind: JobCreate}); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("expected ErrQueueClosed, got %v", err)
+	}
+}
+
+func TestQueue_RecoversJournaledJobs(t *testing.T) {
+	service, repoDir, remoteDir := newTestRepo(t)
+	journalPath := filepath.Join(t.TempDir(), "journal.json")
+
+	// A queue that is stopped before its worker runs leaves its jobs in the journal
+	journal, err := LoadJournal(journalPath)
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	stopped := NewQueue(writeFileJob(service, repoDir), service, nil).WithJournal(journal)
+	stopped.Enqueue(Job{Kind: JobCreate, Summary: "a", UpdateIDs: []int64{101}})
+	stopped.Enqueue(Job{Kind: JobCreate, Summary: "b", UpdateIDs: []int64{102}})
+
+	journal, err = LoadJournal(journalPath)
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	if pending := journal.Pending(); len(pending) != 2 {
+		t.Fatalf("expected 2 journaled jobs, got %d", len(pending))
+	}
+
+	queue := NewQueue(writeFileJob(service, repoDir), service, nil).
+		WithBatchWait(50 * time.Millisecond).
+		WithJournal(journal)
+
+	if _, err := queue.Enqueue(Job{Kind: JobCreate, Summary: "a", UpdateIDs: []int64{101}}); !errors.Is(err, ErrDuplicateUpdate) {
+		t.Errorf("expected ErrDuplicateUpdate for a redelivered update, got %v", err)
+	}
+	third, err := queue.Enqueue(Job{Kind: JobCreate, Summary: "c", UpdateIDs: []int64{103}})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if third.ID != 3 {
+		t.Errorf("expected job IDs to continue after the journaled jobs, got %d", third.ID)
+	}
+
+	queue.Start()
+	queue.Close()
+
+	files := remoteFiles(t, remoteDir)
+	for _, name := range []string{"a", "b", "c"} {
+		if files[name+".md"] != name {
+			t.Errorf("expected %s.md to be published, got %q", name, files[name+".md"])
+		}
+	}
+	if commits := remoteCommits(t, remoteDir); len(commits) != 2 {
+		t.Errorf("expected recovered jobs to be published in one commit, got %d commits", len(commits))
+	}
+
+	journal, err = LoadJournal(journalPath)
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	if pending := journal.Pending(); len(pending) != 0 {
+		t.Errorf("expected published jobs to leave the journal, got %d", len(pending))
+	}
+	if !journal.HasUpdate(102) {
+		t.Error("expected published updates to be remembered")
+	}
+}
+
+// fakeRequester records the change requests opened in branch mode
+type fakeRequester struct {
+	mu      sync.Mutex
+	opened  []forge.ChangeRequest
+	failing bool
+}
+
+func (f *fakeRequester) OpenChangeRequest(ctx context.Context, cr forge.ChangeRequest) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failing {
+		return "", errors.New("forge unavailable")
+	}
+	f.opened = append(f.opened, cr)
+	return "https://forge.example.com/pulls/" + cr.Branch, nil
+}
+
+func TestQueue_BranchPublishing(t *testing.T) {
+	service, repoDir, remoteDir := newTestRepo(t)
+	requester := &fakeRequester{}
+
+	// Jobs write the file named by their summary, on the branch named by its prefix
+	exec := func(ctx context.Context, job *Job) error {
+		name, content, _ := strings.Cut(job.Summary, ":")
+		if err := os.WriteFile(filepath.Join(repoDir, name+".md"), []byte(content), 0644); err != nil {
+			return err
+		}
+		return service.Add(name + ".md")
+	}
+	branchName := func(job *Job) string {
+		name, _, _ := strings.Cut(job.Summary, ":")
+		return "postpal/post-" + name
+	}
+
+	queue := NewQueue(exec, service, nil).
+		WithBatchWait(50*time.Millisecond).
+		WithBranchPublishing(branchName, requester)
+	queue.Start()
+
+	first, _ := queue.Enqueue(Job{Kind: JobCreate, Summary: "a:first"})
+	edit, _ := queue.Enqueue(Job{Kind: JobEdit, Summary: "a:edited"})
+	other, _ := queue.Enqueue(Job{Kind: JobCreate, Summary: "b:other"})
+	queue.Close()
+
+	for _, id := range []int64{first.ID, edit.ID, other.ID} {
+		job, _ := queue.Job(id)
+		if job.Status != JobPublished {
+			t.Fatalf("expected job %d to be published, got %s (%s)", id, job.Status, job.Error)
+		}
+	}
+
+	if files := remoteFiles(t, remoteDir); len(files) != 1 {
+		t.Errorf("expected the base branch to be unchanged, got %v", files)
+	}
+	if files := remoteBranchFiles(t, remoteDir, "postpal/post-a"); files["a.md"] != "edited" || files["b.md"] != "" {
+		t.Errorf("expected the edit on the branch of its post, got %v", files)
+	}
+	if commits := remoteBranchCommits(t, remoteDir, "postpal/post-a"); len(commits) != 3 {
+		t.Errorf("expected a commit per job on the branch, got %d commits", len(commits))
+	}
+	if files := remoteBranchFiles(t, remoteDir, "postpal/post-b"); files["b.md"] != "other" || files["a.md"] != "" {
+		t.Errorf("expected the other post on its own branch, got %v", files)
+	}
+
+	if len(requester.opened) != 3 || requester.opened[0].Base != "main" || requester.opened[0].Title != "a:first" {
+		t.Errorf("expected a change request into main per job, got %+v", requester.opened)
+	}
+	job, _ := queue.Job(edit.ID)
+	if job.Branch != "postpal/post-a" || job.ChangeRequestURL != "https://forge.example.com/pulls/postpal/post-a" {
+		t.Errorf("expected branch and change request on the job, got %q and %q", job.Branch, job.ChangeRequestURL)
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, "a.md")); !os.IsNotExist(err) {
+		t.Errorf("expected the base branch to be checked out again, got %v", err)
+	}
+}
+
+func TestQueue_BranchPublishingRequestFails(t *testing.T) {
+	service, repoDir, _ := newTestRepo(t)
+	requester := &fakeRequester{failing: true}
+
+	queue := NewQueue(writeFileJob(service, repoDir), service, nil).
+		WithBranchPublishing(func(job *Job) string { return "postpal/post-1" }, requester)
+	queue.Start()
+
+	job, _ := queue.Enqueue(Job{Kind: JobCreate, Summary: "first"})
+	waitForStatus(t, queue, job.ID, JobFailed)
+	queue.Close()
+
+	if job, _ := queue.Job(job.ID); !strings.Contains(job.Error, "forge unavailable") {
+		t.Errorf("expected the change request error, got %q", job.Error)
+	}
+}
+
+func waitForStatus(t *testing.T, queue *Queue, id int64, status string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, ok := queue.Job(id); ok && job.Status == status {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	job, _ := queue.Job(id)
+	t.Fatalf("timed out waiting for job %d to be %s, got %s (%s)", id, status, job.Status, job.Error)
+}
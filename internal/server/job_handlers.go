@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/en9inerd/go-pkgs/httperrors"
+	"github.com/en9inerd/postpal/internal/pipeline"
+)
+
+// jobResponse is the status of a publish job without its Telegram payload
+type jobResponse struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	Summary   string    `json:"summary"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Branch           string `json:"branch,omitempty"`
+	ChangeRequestURL string `json:"change_request_url,omitempty"`
+}
+
+func newJobResponse(job pipeline.Job) jobResponse {
+	return jobResponse{
+		ID:        job.ID,
+		Kind:      job.Kind,
+		Summary:   job.Summary,
+		Status:    job.Status,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+
+		Branch:           job.Branch,
+		ChangeRequestURL: job.ChangeRequestURL,
+	}
+}
+
+func listJobsHandler(logger *slog.Logger, p *pipeline.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobs := p.Jobs()
+		response := make([]jobResponse, len(jobs))
+		for i, job := range jobs {
+			response[i] = newJobResponse(job)
+		}
+		writeJSON(w, logger, http.StatusOK, response)
+	}
+}
+
+func getJobHandler(logger *slog.Logger, p *pipeline.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			httperrors.NewError(http.StatusBadRequest, "Invalid job ID").WriteJSON(w)
+			return
+		}
+
+		job, ok := p.Job(id)
+		if !ok {
+			httperrors.NewError(http.StatusNotFound, "Job not found").WriteJSON(w)
+			return
+		}
+		writeJSON(w, logger, http.StatusOK, newJobResponse(job))
+	}
+}
+
+func deletePostsHandler(logger *slog.Logger, p *pipeline.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var chatID int64
+		if v := r.URL.Query().Get("chat_id"); v != "" {
+			var err error
+			if chatID, err = strconv.ParseInt(v, 10, 64); err != nil {
+				httperrors.NewError(http.StatusBadRequest, "Invalid chat_id").WriteJSON(w)
+				return
+			}
+		}
+
+		job, err := p.DeletePosts(chatID, r.PathValue("ids"))
+		if err != nil {
+			logger.Warn("failed to queue post deletion", "ids", r.PathValue("ids"), "error", err)
+			httperrors.NewError(http.StatusBadRequest, err.Error()).WriteJSON(w)
+			return
+		}
+		writeJSON(w, logger, http.StatusAccepted, newJobResponse(job))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, logger *slog.Logger, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("failed to write response", "error", err)
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"crypto/subtle"
 	"log/slog"
 	"net"
 	"net/http"
@@ -69,6 +70,23 @@ func RequireAuth(authService *auth.Service, logger *slog.Logger) func(http.Handl
 	}
 }
 
+// RequireTelegramSecret rejects requests that do not carry the webhook secret
+// configured via setWebhook in the X-Telegram-Bot-Api-Secret-Token header.
+func RequireTelegramSecret(secret string, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+				logger.Warn("rejected telegram webhook request", "ip", r.RemoteAddr)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func redirectToLogin(w http.ResponseWriter, r *http.Request, logger *slog.Logger) {
 	returnURL := sanitizeReturnURL(r.URL.Path)
 	if r.URL.RawQuery != "" {
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/en9inerd/go-pkgs/httperrors"
+	"github.com/en9inerd/postpal/internal/pipeline"
+	"github.com/en9inerd/postpal/internal/telegram"
+	"github.com/en9inerd/postpal/internal/zola"
+)
+
+// previewResponse lists the files a Telegram update would change in the site repository
+type previewResponse struct {
+	Changes []zola.FileChange `json:"changes"`
+}
+
+func previewPostHandler(logger *slog.Logger, p *pipeline.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var update telegram.Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			httperrors.NewError(http.StatusBadRequest, "Invalid Telegram update").WriteJSON(w)
+			return
+		}
+
+		changes, err := p.Preview(r.Context(), &update)
+		if err != nil {
+			logger.Warn("failed to preview post", "update_id", update.UpdateID, "error", err)
+			httperrors.NewError(http.StatusUnprocessableEntity, err.Error()).WriteJSON(w)
+			return
+		}
+		writeJSON(w, logger, http.StatusOK, previewResponse{Changes: changes})
+	}
+}
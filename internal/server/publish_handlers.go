@@ -0,0 +1,101 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/en9inerd/go-pkgs/httperrors"
+	"github.com/en9inerd/postpal/internal/announce"
+	"github.com/en9inerd/postpal/internal/config"
+	"github.com/en9inerd/postpal/internal/telegram"
+	"github.com/en9inerd/postpal/internal/zola"
+)
+
+// publishRequest names a page of the site repository to announce in a Telegram channel
+type publishRequest struct {
+	Path      string `json:"path"`       // Page file relative to the repository root, e.g. "content/posts/hello.md"
+	Channel   string `json:"channel"`    // Defaults to the configured channel
+	ParseMode string `json:"parse_mode"` // HTML (default) or MarkdownV2
+	MessageID int64  `json:"message_id"` // Earlier announcement to update instead of sending a new one
+}
+
+// publishResponse is the message announcing the page
+type publishResponse struct {
+	MessageID int64  `json:"message_id"`
+	Channel   string `json:"channel"`
+	Permalink string `json:"permalink,omitempty"`
+}
+
+func publishPageHandler(logger *slog.Logger, cfg *config.Config, announcer *announce.Announcer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req publishRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httperrors.NewError(http.StatusBadRequest, "Invalid request body").WriteJSON(w)
+			return
+		}
+
+		pagePath := filepath.ToSlash(filepath.Clean(req.Path))
+		contentPath, ok := strings.CutPrefix(pagePath, "content/")
+		if !filepath.IsLocal(req.Path) || !ok || !strings.HasSuffix(pagePath, ".md") {
+			httperrors.NewError(http.StatusBadRequest, "path must be a Markdown file in the content directory").WriteJSON(w)
+			return
+		}
+		channel := cmp.Or(req.Channel, cfg.TelegramChannel)
+		if channel == "" {
+			httperrors.NewError(http.StatusBadRequest, "channel is required").WriteJSON(w)
+			return
+		}
+		parseMode := cmp.Or(req.ParseMode, announce.ParseModeHTML)
+
+		data, err := os.ReadFile(filepath.Join(cfg.RepoDir, filepath.FromSlash(pagePath)))
+		if err != nil {
+			logger.Warn("failed to read page", "path", pagePath, "error", err)
+			httperrors.NewError(http.StatusNotFound, "Page not found").WriteJSON(w)
+			return
+		}
+		page, err := zola.ParsePage(contentPath, data)
+		if err != nil {
+			httperrors.NewError(http.StatusUnprocessableEntity, err.Error()).WriteJSON(w)
+			return
+		}
+		if page.Draft {
+			httperrors.NewError(http.StatusUnprocessableEntity, "Drafts are not published").WriteJSON(w)
+			return
+		}
+		if _, err := announcer.Render(page, parseMode); err != nil {
+			httperrors.NewError(http.StatusUnprocessableEntity, err.Error()).WriteJSON(w)
+			return
+		}
+
+		var msg *telegram.Message
+		if req.MessageID > 0 {
+			msg, err = announcer.Update(r.Context(), channel, req.MessageID, page, parseMode)
+		} else {
+			msg, err = announcer.Publish(r.Context(), channel, page, parseMode)
+		}
+		switch {
+		case errors.Is(err, telegram.ErrChatNotFound):
+			httperrors.NewError(http.StatusBadRequest, "Channel not found").WriteJSON(w)
+			return
+		case errors.Is(err, telegram.ErrBotKicked):
+			httperrors.NewError(http.StatusBadRequest, "The bot is not a member of the channel").WriteJSON(w)
+			return
+		case err != nil:
+			logger.Error("failed to publish page", "path", pagePath, "channel", channel, "error", err)
+			httperrors.NewError(http.StatusBadGateway, err.Error()).WriteJSON(w)
+			return
+		}
+
+		response := publishResponse{MessageID: msg.MessageID, Channel: channel}
+		if cfg.SiteURL != "" {
+			response.Permalink = page.Permalink(cfg.SiteURL)
+		}
+		writeJSON(w, logger, http.StatusOK, response)
+	}
+}
@@ -4,11 +4,22 @@ import (
 	"log/slog"
 
 	"github.com/en9inerd/go-pkgs/router"
+	"github.com/en9inerd/postpal/internal/announce"
 	"github.com/en9inerd/postpal/internal/auth"
 	"github.com/en9inerd/postpal/internal/config"
+	"github.com/en9inerd/postpal/internal/pipeline"
 )
 
-func registerAPIRoutes(apiGroup *router.Group, logger *slog.Logger, cfg *config.Config) {
+func registerAPIRoutes(apiGroup *router.Group, logger *slog.Logger, cfg *config.Config, p *pipeline.Pipeline, announcer *announce.Announcer) {
+	apiGroup.HandleFunc("GET /jobs", listJobsHandler(logger, p))
+	apiGroup.HandleFunc("GET /jobs/{id}", getJobHandler(logger, p))
+	apiGroup.HandleFunc("DELETE /posts/{ids}", deletePostsHandler(logger, p))
+	apiGroup.HandleFunc("POST /posts/preview", previewPostHandler(logger, p))
+	apiGroup.HandleFunc("POST /publish", publishPageHandler(logger, cfg, announcer))
+}
+
+func registerTelegramRoutes(telegramGroup *router.Group, logger *slog.Logger, p *pipeline.Pipeline) {
+	telegramGroup.HandleFunc("POST /webhook", telegramWebhookHandler(logger, p))
 }
 
 func registerWebRoutes(webGroup *router.Group, logger *slog.Logger, cfg *config.Config, templates *templateCache) {
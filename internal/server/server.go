@@ -10,8 +10,10 @@ import (
 	"github.com/en9inerd/go-pkgs/httperrors"
 	"github.com/en9inerd/go-pkgs/middleware"
 	"github.com/en9inerd/go-pkgs/router"
+	"github.com/en9inerd/postpal/internal/announce"
 	"github.com/en9inerd/postpal/internal/auth"
 	"github.com/en9inerd/postpal/internal/config"
+	"github.com/en9inerd/postpal/internal/pipeline"
 	"github.com/en9inerd/postpal/ui"
 )
 
@@ -25,7 +27,7 @@ func SecurityHeaders(next http.Handler) http.Handler {
 	})
 }
 
-func NewServer(logger *slog.Logger, cfg *config.Config) (http.Handler, error) {
+func NewServer(logger *slog.Logger, cfg *config.Config, p *pipeline.Pipeline, announcer *announce.Announcer) (http.Handler, error) {
 	authService, err := auth.NewService(
 		cfg.AuthPasswordHash,
 		cfg.AuthSessionSecret,
@@ -62,9 +64,18 @@ func NewServer(logger *slog.Logger, cfg *config.Config) (http.Handler, error) {
 		registerPublicRoutes(publicGroup, logger, cfg, templates, authService)
 	})
 
+	if cfg.TelegramMode == config.TelegramModeWebhook {
+		r.Mount("/api/telegram").Route(func(telegramGroup *router.Group) {
+			telegramGroup.Use(Logger(logger), RequireTelegramSecret(cfg.TelegramWebhookSecret, logger))
+			registerTelegramRoutes(telegramGroup, logger, p)
+		})
+	} else {
+		logger.Info("telegram webhook disabled", "mode", cfg.TelegramMode)
+	}
+
 	r.Mount("/api").Route(func(apiGroup *router.Group) {
 		apiGroup.Use(Logger(logger), RequireAuth(authService, logger))
-		registerAPIRoutes(apiGroup, logger, cfg)
+		registerAPIRoutes(apiGroup, logger, cfg, p, announcer)
 	})
 
 	r.Group().Route(func(webGroup *router.Group) {
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/en9inerd/postpal/internal/pipeline"
+	"github.com/en9inerd/postpal/internal/telegram"
+)
+
+func telegramWebhookHandler(logger *slog.Logger, p *pipeline.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var update telegram.Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			logger.Warn("failed to decode telegram update", "error", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		if err := p.HandleUpdate(r.Context(), &update); err != nil {
+			// A non-2xx response makes Telegram redeliver the update later
+			logger.Error("failed to handle telegram update", "update_id", update.UpdateID, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
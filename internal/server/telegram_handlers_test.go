@@ -0,0 +1,109 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/en9inerd/postpal/internal/pipeline"
+)
+
+const testWebhookSecret = "webhook-secret"
+
+// newWebhookServer serves the webhook the way NewServer mounts it. The pipeline is not started,
+// so handled updates stay queued and can be inspected through its jobs.
+func newWebhookServer(t *testing.T) (*pipeline.Pipeline, *httptest.Server) {
+	t.Helper()
+
+	logger := slog.New(slog.DiscardHandler)
+	p := pipeline.New(nil, nil, []pipeline.Channel{{ChatID: -100123}}, logger)
+	t.Cleanup(p.Close)
+
+	handler := RequireTelegramSecret(testWebhookSecret, logger)(telegramWebhookHandler(logger, p))
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return p, server
+}
+
+func postUpdate(t *testing.T, url, secret, body string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestTelegramWebhook_RejectsWrongSecret(t *testing.T) {
+	p, server := newWebhookServer(t)
+	body := `{"update_id":1,"channel_post":{"message_id":7,"date":0,"chat":{"id":-100123,"type":"channel"},"text":"hello"}}`
+
+	for _, secret := range []string{"", "wrong-secret", testWebhookSecret + "x"} {
+		if status := postUpdate(t, server.URL, secret, body); status != http.StatusUnauthorized {
+			t.Errorf("secret %q: expected 401, got %d", secret, status)
+		}
+	}
+	if jobs := p.Jobs(); len(jobs) != 0 {
+		t.Errorf("expected rejected updates not to be queued, got %d jobs", len(jobs))
+	}
+}
+
+func TestTelegramWebhook_QueuesPosts(t *testing.T) {
+	p, server := newWebhookServer(t)
+
+	updates := []string{
+		`{"update_id":1,"channel_post":{"message_id":7,"date":1700000000,"chat":{"id":-100123,"type":"channel"},"text":"hello",` +
+			`"entities":[{"type":"bold","offset":0,"length":5}]}}`,
+		`{"update_id":2,"edited_channel_post":{"message_id":7,"date":1700000000,"edit_date":1700000100,"chat":{"id":-100123,"type":"channel"},"text":"hello again"}}`,
+		`{"update_id":3,"channel_post":{"message_id":8,"date":0,"chat":{"id":-100456,"type":"channel"},"text":"other channel"}}`,
+		`{"update_id":4,"message":{"message_id":9,"date":0,"chat":{"id":5,"type":"private"},"text":"hi"}}`,
+	}
+	for _, body := range updates {
+		if status := postUpdate(t, server.URL, testWebhookSecret, body); status != http.StatusOK {
+			t.Fatalf("expected 200 for %s, got %d", body, status)
+		}
+	}
+
+	jobs := p.Jobs()
+	if len(jobs) != 2 {
+		t.Fatalf("expected a create and an edit job, got %+v", jobs)
+	}
+
+	created, edited := jobs[0], jobs[1]
+	if created.Kind != pipeline.JobCreate || created.Messages[0].Text != "hello" || len(created.Messages[0].Entities) != 1 {
+		t.Errorf("expected the channel post to be queued for creation, got %+v", created)
+	}
+	if edited.Kind != pipeline.JobEdit || edited.Messages[0].Text != "hello again" || edited.Messages[0].Chat.ID != -100123 {
+		t.Errorf("expected the edited post to be queued for editing, got %+v", edited)
+	}
+
+	// Telegram redelivers an update until it is acknowledged; it is only queued once
+	if status := postUpdate(t, server.URL, testWebhookSecret, updates[0]); status != http.StatusOK {
+		t.Errorf("expected 200 for a redelivered update, got %d", status)
+	}
+	if jobs := p.Jobs(); len(jobs) != 2 {
+		t.Errorf("expected the redelivered update to be ignored, got %d jobs", len(jobs))
+	}
+}
+
+func TestTelegramWebhook_MalformedUpdate(t *testing.T) {
+	_, server := newWebhookServer(t)
+
+	if status := postUpdate(t, server.URL, testWebhookSecret, `{"update_id":`); status != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", status)
+	}
+}
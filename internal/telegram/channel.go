@@ -1,6 +1,7 @@
 package telegram
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -25,8 +26,8 @@ func parseMessageResult(result any) (*Message, error) {
 }
 
 // SendMessage sends a message to a channel
-func (c *Client) SendMessage(req SendMessageRequest) (*Message, error) {
-	resp, err := c.makeRequest("sendMessage", req)
+func (c *Client) SendMessage(ctx context.Context, req SendMessageRequest) (*Message, error) {
+	resp, err := c.makeRequest(ctx, "sendMessage", &req)
 	if err != nil {
 		return nil, err
 	}
@@ -35,8 +36,8 @@ func (c *Client) SendMessage(req SendMessageRequest) (*Message, error) {
 }
 
 // EditMessageText edits the text of a message in a channel
-func (c *Client) EditMessageText(req EditMessageTextRequest) (*Message, error) {
-	resp, err := c.makeRequest("editMessageText", req)
+func (c *Client) EditMessageText(ctx context.Context, req EditMessageTextRequest) (*Message, error) {
+	resp, err := c.makeRequest(ctx, "editMessageText", &req)
 	if err != nil {
 		return nil, err
 	}
@@ -45,8 +46,8 @@ func (c *Client) EditMessageText(req EditMessageTextRequest) (*Message, error) {
 }
 
 // EditMessageCaption edits the caption of a message in a channel
-func (c *Client) EditMessageCaption(req EditMessageCaptionRequest) (*Message, error) {
-	resp, err := c.makeRequest("editMessageCaption", req)
+func (c *Client) EditMessageCaption(ctx context.Context, req EditMessageCaptionRequest) (*Message, error) {
+	resp, err := c.makeRequest(ctx, "editMessageCaption", &req)
 	if err != nil {
 		return nil, err
 	}
@@ -55,8 +56,19 @@ func (c *Client) EditMessageCaption(req EditMessageCaptionRequest) (*Message, er
 }
 
 // EditMessageMedia edits the media of a message in a channel
-func (c *Client) EditMessageMedia(req EditMessageMediaRequest) (*Message, error) {
-	resp, err := c.makeRequest("editMessageMedia", req)
+func (c *Client) EditMessageMedia(ctx context.Context, req EditMessageMediaRequest) (*Message, error) {
+	resp, err := c.makeRequest(ctx, "editMessageMedia", &req)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMessageResult(resp.Result)
+}
+
+// EditMessageReplyMarkup replaces the inline keyboard of a message in a channel.
+// A nil ReplyMarkup removes the keyboard.
+func (c *Client) EditMessageReplyMarkup(ctx context.Context, req EditMessageReplyMarkupRequest) (*Message, error) {
+	resp, err := c.makeRequest(ctx, "editMessageReplyMarkup", &req)
 	if err != nil {
 		return nil, err
 	}
@@ -65,8 +77,8 @@ func (c *Client) EditMessageMedia(req EditMessageMediaRequest) (*Message, error)
 }
 
 // DeleteMessage deletes a message from a channel
-func (c *Client) DeleteMessage(req DeleteMessageRequest) (bool, error) {
-	resp, err := c.makeRequest("deleteMessage", req)
+func (c *Client) DeleteMessage(ctx context.Context, req DeleteMessageRequest) (bool, error) {
+	resp, err := c.makeRequest(ctx, "deleteMessage", &req)
 	if err != nil {
 		return false, err
 	}
@@ -81,8 +93,8 @@ func (c *Client) DeleteMessage(req DeleteMessageRequest) (bool, error) {
 }
 
 // ForwardMessage forwards a message to a channel
-func (c *Client) ForwardMessage(req ForwardMessageRequest) (*Message, error) {
-	resp, err := c.makeRequest("forwardMessage", req)
+func (c *Client) ForwardMessage(ctx context.Context, req ForwardMessageRequest) (*Message, error) {
+	resp, err := c.makeRequest(ctx, "forwardMessage", &req)
 	if err != nil {
 		return nil, err
 	}
@@ -91,8 +103,8 @@ func (c *Client) ForwardMessage(req ForwardMessageRequest) (*Message, error) {
 }
 
 // CopyMessage copies a message to a channel
-func (c *Client) CopyMessage(req CopyMessageRequest) (*Message, error) {
-	resp, err := c.makeRequest("copyMessage", req)
+func (c *Client) CopyMessage(ctx context.Context, req CopyMessageRequest) (*Message, error) {
+	resp, err := c.makeRequest(ctx, "copyMessage", &req)
 	if err != nil {
 		return nil, err
 	}
@@ -101,8 +113,8 @@ func (c *Client) CopyMessage(req CopyMessageRequest) (*Message, error) {
 }
 
 // PinChatMessage pins a message in a channel
-func (c *Client) PinChatMessage(req PinChatMessageRequest) (bool, error) {
-	resp, err := c.makeRequest("pinChatMessage", req)
+func (c *Client) PinChatMessage(ctx context.Context, req PinChatMessageRequest) (bool, error) {
+	resp, err := c.makeRequest(ctx, "pinChatMessage", &req)
 	if err != nil {
 		return false, err
 	}
@@ -112,8 +124,8 @@ func (c *Client) PinChatMessage(req PinChatMessageRequest) (bool, error) {
 
 // UnpinChatMessage unpins a specific message in a channel
 // If MessageID is 0, it will unpin all messages
-func (c *Client) UnpinChatMessage(req UnpinChatMessageRequest) (bool, error) {
-	resp, err := c.makeRequest("unpinChatMessage", req)
+func (c *Client) UnpinChatMessage(ctx context.Context, req UnpinChatMessageRequest) (bool, error) {
+	resp, err := c.makeRequest(ctx, "unpinChatMessage", &req)
 	if err != nil {
 		return false, err
 	}
@@ -122,8 +134,8 @@ func (c *Client) UnpinChatMessage(req UnpinChatMessageRequest) (bool, error) {
 }
 
 // UnpinAllChatMessages unpins all messages in a channel
-func (c *Client) UnpinAllChatMessages(req UnpinAllChatMessagesRequest) (bool, error) {
-	resp, err := c.makeRequest("unpinAllChatMessages", req)
+func (c *Client) UnpinAllChatMessages(ctx context.Context, req UnpinAllChatMessagesRequest) (bool, error) {
+	resp, err := c.makeRequest(ctx, "unpinAllChatMessages", &req)
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,59 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// stall answers method only once the request is cancelled or the test ends
+func (api *fakeBotAPI) stall(t *testing.T, method string) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+
+	api.handle(method, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":-100,"type":"channel"}}}`)
+	})
+}
+
+func TestClient_SendMessage_Timeout(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	api.stall(t, "sendMessage")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.SendMessage(ctx, SendMessageRequest{ChatID: "@channel", Text: "Hello"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to give up at the deadline, took %v", elapsed)
+	}
+	if n := api.callCount("sendMessage"); n != 1 {
+		t.Errorf("expected 1 call without retries, got %d", n)
+	}
+}
+
+func TestClient_SendMessage_Canceled(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.SendMessage(ctx, SendMessageRequest{ChatID: "@channel", Text: "Hello"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled error, got %v", err)
+	}
+	if n := api.callCount("sendMessage"); n != 0 {
+		t.Errorf("expected no request to be sent, got %d", n)
+	}
+}
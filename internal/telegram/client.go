@@ -2,8 +2,11 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/en9inerd/go-pkgs/httpclient"
@@ -14,13 +17,28 @@ import (
 const (
 	// BaseURL is the base URL for Telegram Bot API
 	BaseURL = "https://api.telegram.org/bot"
+	// FileBaseURL is the base URL for downloading files from Telegram Bot API
+	FileBaseURL = "https://api.telegram.org/file/bot"
+	// MaxDownloadSize is the largest file the Bot API allows bots to download (20 MB)
+	MaxDownloadSize = 20 * 1024 * 1024
+	// DefaultMaxRetryAfter is the longest retry_after the client waits for before failing a request
+	DefaultMaxRetryAfter = time.Minute
 )
 
+// maxRateLimitRetries is the number of attempts of a request answered with retry_after
+const maxRateLimitRetries = 5
+
 // Client represents a Telegram Bot API client
 type Client struct {
-	httpClient *httpclient.Client
-	botToken   string
-	logger     *slog.Logger
+	httpClient      *httpclient.Client
+	fileClient      *http.Client // File downloads and uploads
+	apiURL          string       // Base URL of API methods, for uploads
+	fileBaseURL     string
+	maxDownloadSize int64
+	limiter         *rateLimiter
+	maxRetryAfter   time.Duration
+	botToken        string
+	logger          *slog.Logger
 }
 
 // NewClient creates a new Telegram Bot API client
@@ -37,8 +55,14 @@ func NewClient(botToken string, logger *slog.Logger) *Client {
 			WithLogger(logger).
 			WithTimeout(30*time.Second).
 			WithHeader("Content-Type", "application/json"),
-		botToken: botToken,
-		logger:   logger,
+		fileClient:      &http.Client{Timeout: 30 * time.Second},
+		apiURL:          baseURL,
+		fileBaseURL:     fmt.Sprintf("%s%s/", FileBaseURL, botToken),
+		maxDownloadSize: MaxDownloadSize,
+		limiter:         newRateLimiter(DefaultGlobalRateLimit, DefaultChatRateLimit),
+		maxRetryAfter:   DefaultMaxRetryAfter,
+		botToken:        botToken,
+		logger:          logger,
 	}
 }
 
@@ -48,9 +72,41 @@ func (c *Client) WithHTTPClient(client *httpclient.Client) *Client {
 	return c
 }
 
+// WithAPIURL points the client at a different Bot API server (e.g. a local Bot API server or a test double).
+// apiURL is the server root without the /bot<token> suffix, e.g. "http://localhost:8081".
+func (c *Client) WithAPIURL(apiURL string) *Client {
+	apiURL = strings.TrimSuffix(apiURL, "/")
+	c.apiURL = fmt.Sprintf("%s/bot%s/", apiURL, c.botToken)
+	c.httpClient = c.httpClient.WithBaseURL(c.apiURL)
+	c.fileBaseURL = fmt.Sprintf("%s/file/bot%s/", apiURL, c.botToken)
+	return c
+}
+
+// WithMaxDownloadSize sets the largest file size DownloadFile accepts, in bytes
+func (c *Client) WithMaxDownloadSize(size int64) *Client {
+	c.maxDownloadSize = size
+	return c
+}
+
+// WithRateLimits replaces the limits on outgoing requests: global applies to all requests
+// sent to chats, perChat to each chat on its own. Other requests, like getUpdates, are not
+// limited. Zero limits disable limiting.
+func (c *Client) WithRateLimits(global, perChat RateLimit) *Client {
+	c.limiter = newRateLimiter(global, perChat)
+	return c
+}
+
+// WithMaxRetryAfter sets the longest retry_after the client waits for when Telegram
+// answers 429 Too Many Requests. Requests asked to wait longer fail.
+func (c *Client) WithMaxRetryAfter(d time.Duration) *Client {
+	c.maxRetryAfter = d
+	return c
+}
+
 // WithTimeout sets a custom timeout for HTTP requests
 func (c *Client) WithTimeout(timeout time.Duration) *Client {
 	c.httpClient = c.httpClient.WithTimeout(timeout)
+	c.fileClient.Timeout = timeout
 	return c
 }
 
@@ -66,44 +122,99 @@ func (c *Client) validateRequest(req any) error {
 	return nil
 }
 
-// makeRequest makes an HTTP request to the Telegram Bot API with retry logic
-func (c *Client) makeRequest(method string, payload any) (*APIResponse, error) {
+// retryStrategy returns the retry strategy for transient failures
+func (c *Client) retryStrategy() retry.Strategy {
+	strategy := retry.DefaultStrategy()
+	strategy.MaxAttempts = 3
+	strategy.InitialDelay = 1 * time.Second
+	strategy.MaxDelay = 10 * time.Second
+	// Only retry on network errors, not API errors or a cancelled request
+	strategy.RetryableErrors = func(err error) bool {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		return retry.IsRetryableError(err)
+	}
+	return strategy
+}
+
+// makeRequest makes an HTTP request to the Telegram Bot API with retry logic.
+// Requests carrying file uploads are sent as multipart/form-data, others as JSON.
+func (c *Client) makeRequest(ctx context.Context, method string, payload any) (*APIResponse, error) {
 	// Validate request if it's validatable
 	if err := c.validateRequest(payload); err != nil {
 		return nil, err
 	}
 
-	// Configure retry strategy for transient failures
-	strategy := retry.DefaultStrategy()
-	strategy.MaxAttempts = 3
-	strategy.InitialDelay = 1 * time.Second
-	strategy.MaxDelay = 10 * time.Second
-	// Only retry on network errors, not API errors
-	strategy.RetryableErrors = retry.IsRetryableError
+	var form *multipartForm
+	if u, ok := payload.(uploader); ok {
+		var err error
+		if form, err = newMultipartForm(payload, u.inputFiles()); err != nil {
+			return nil, err
+		}
+	}
+
+	chatID := requestChatID(payload)
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("telegram api request failed: %w", err)
+		}
+		if err := c.limiter.wait(ctx, chatID); err != nil {
+			return nil, fmt.Errorf("telegram api request failed: %w", err)
+		}
+
+		apiResp, err := c.send(ctx, method, payload, form)
+		if err != nil {
+			return nil, fmt.Errorf("telegram api request failed: %w", err)
+		}
+		if apiResp.OK {
+			if chat, ok := resultChat(apiResp.Result); ok {
+				c.limiter.learn(chatID, chat)
+			}
+			return apiResp, nil
+		}
+
+		// Wait out flood control and try again
+		if retryAfter := apiResp.retryAfter(); retryAfter > 0 && retryAfter <= c.maxRetryAfter && attempt < maxRateLimitRetries {
+			c.logger.Warn("telegram api rate limit hit, waiting", "method", method, "chat_id", chatID, "retry_after", retryAfter)
+			c.limiter.pause(chatID, retryAfter)
+			continue
+		}
+
+		// Other API errors are not retryable
+		return nil, fmt.Errorf("telegram api request failed: %w", newAPIError(apiResp))
+	}
+}
+
+// send posts a request, retrying network errors, and returns the decoded response
+func (c *Client) send(ctx context.Context, method string, payload any, form *multipartForm) (*APIResponse, error) {
+	strategy := c.retryStrategy()
 
 	var apiResp APIResponse
-	err := retry.Do(context.Background(), strategy, func() error {
+	err := retry.Do(ctx, strategy, func() error {
 		c.logger.Debug("making telegram api request", "method", method)
 
-		err := c.httpClient.PostJSON(context.Background(), method, payload, &apiResp)
-		if err != nil {
+		apiResp = APIResponse{}
+		var err error
+		if form != nil {
+			err = c.postForm(ctx, method, form, &apiResp)
+		} else {
+			err = c.httpClient.PostJSON(ctx, method, payload, &apiResp)
+		}
+		if err != nil && apiResp.ErrorCode == 0 {
+			if ctx.Err() != nil {
+				return err
+			}
 			// Network errors will be retried automatically
 			c.logger.Warn("telegram api request failed, retrying", "error", err, "method", method)
 			return err
 		}
 
-		// Check if Telegram API returned an error
-		if !apiResp.OK {
-			// API errors are not retryable - return immediately
-			return fmt.Errorf("telegram api error: %s (code: %d)",
-				apiResp.Description, apiResp.ErrorCode)
-		}
-
+		// An error status with a Bot API error in the body is handled by the caller
 		return nil
 	})
-
 	if err != nil {
-		return nil, fmt.Errorf("telegram api request failed: %w", err)
+		return nil, err
 	}
 
 	return &apiResp, nil
@@ -0,0 +1,63 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Classes of Bot API errors, matched with errors.Is against an *APIError
+var (
+	// ErrMessageNotModified is returned when an edit doesn't change the message
+	ErrMessageNotModified = errors.New("message is not modified")
+	// ErrMessageNotFound is returned when the message to edit, delete, forward or copy doesn't exist
+	ErrMessageNotFound = errors.New("message not found")
+	// ErrChatNotFound is returned when the chat doesn't exist or the bot can't see it
+	ErrChatNotFound = errors.New("chat not found")
+	// ErrBotKicked is returned when the bot was removed from the chat or is not a member of it
+	ErrBotKicked = errors.New("bot was kicked from the chat")
+	// ErrTooManyRequests is returned when flood control was exceeded
+	ErrTooManyRequests = errors.New("too many requests")
+)
+
+// APIError is an error answered by the Bot API
+type APIError struct {
+	Code        int    // HTTP-like error code, e.g. 400, 403 or 429
+	Description string // e.g. "Bad Request: message to edit not found"
+	Parameters  *ResponseParameters
+}
+
+// newAPIError creates an APIError from a failed response
+func newAPIError(resp *APIResponse) *APIError {
+	return &APIError{Code: resp.ErrorCode, Description: resp.Description, Parameters: resp.Parameters}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram api error: %s (code: %d)", e.Description, e.Code)
+}
+
+// Is reports whether the error belongs to one of the classes ErrMessageNotModified,
+// ErrMessageNotFound, ErrChatNotFound, ErrBotKicked and ErrTooManyRequests.
+// Telegram only tells them apart by their description.
+func (e *APIError) Is(target error) bool {
+	description := strings.ToLower(e.Description)
+
+	switch target {
+	case ErrMessageNotModified:
+		return e.Code == http.StatusBadRequest && strings.Contains(description, "message is not modified")
+	case ErrMessageNotFound:
+		return e.Code == http.StatusBadRequest &&
+			(strings.Contains(description, "message to ") && strings.Contains(description, "not found") ||
+				strings.Contains(description, "message not found") ||
+				strings.Contains(description, "message_id_invalid"))
+	case ErrChatNotFound:
+		return e.Code == http.StatusBadRequest && strings.Contains(description, "chat not found")
+	case ErrBotKicked:
+		return e.Code == http.StatusForbidden &&
+			(strings.Contains(description, "bot was kicked") || strings.Contains(description, "bot is not a member"))
+	case ErrTooManyRequests:
+		return e.Code == http.StatusTooManyRequests
+	}
+	return false
+}
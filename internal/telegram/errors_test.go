@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAPIError_Is(t *testing.T) {
+	sentinels := []error{ErrMessageNotModified, ErrMessageNotFound, ErrChatNotFound, ErrBotKicked, ErrTooManyRequests}
+
+	tests := []struct {
+		code        int
+		description string
+		expected    error // nil when no class matches
+	}{
+		{400, "Bad Request: message is not modified: specified new message content and reply markup are exactly the same as a current content and reply markup of the message", ErrMessageNotModified},
+		{400, "Bad Request: message to edit not found", ErrMessageNotFound},
+		{400, "Bad Request: message to delete not found", ErrMessageNotFound},
+		{400, "Bad Request: message to copy not found", ErrMessageNotFound},
+		{400, "Bad Request: MESSAGE_ID_INVALID", ErrMessageNotFound},
+		{400, "Bad Request: chat not found", ErrChatNotFound},
+		{403, "Forbidden: bot was kicked from the channel chat", ErrBotKicked},
+		{403, "Forbidden: bot is not a member of the channel chat", ErrBotKicked},
+		{429, "Too Many Requests: retry after 5", ErrTooManyRequests},
+		{400, "Bad Request: message text is empty", nil},
+		{403, "Forbidden: bot can't send messages to bots", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			err := fmt.Errorf("telegram api request failed: %w", &APIError{Code: tt.code, Description: tt.description})
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.expected) {
+					t.Errorf("errors.Is(%q) = %v", sentinel, got)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_ReturnsAPIError(t *testing.T) {
+	_, client := newFakeBotAPI(t)
+
+	_, err := client.GetFile(context.Background(), GetFileRequest{FileID: "missing"})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an *APIError, got %v", err)
+	}
+	if apiErr.Code != 400 || apiErr.Description != "Bad Request: invalid file_id" {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+	if err.Error() != "telegram api request failed: telegram api error: Bad Request: invalid file_id (code: 400)" {
+		t.Errorf("unexpected message %q", err)
+	}
+}
+
+func TestClient_RetryAfterExhausted(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	api.flood("sendMessage", 120, 1)
+
+	_, err := client.SendMessage(context.Background(), SendMessageRequest{ChatID: "@channel", Text: "Hello"})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("expected a too many requests error, got %v", err)
+	}
+	if apiErr.Parameters == nil || apiErr.Parameters.RetryAfter != 120 {
+		t.Errorf("expected retry_after in the parameters, got %+v", apiErr.Parameters)
+	}
+	if api.callCount("sendMessage") != 1 {
+		t.Errorf("expected 1 call, got %d", api.callCount("sendMessage"))
+	}
+}
@@ -0,0 +1,133 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/en9inerd/go-pkgs/retry"
+)
+
+// ErrFileTooLarge is returned when a file exceeds the client's download size limit
+var ErrFileTooLarge = errors.New("file is too large to download")
+
+// statusError reports an unexpected HTTP status from the file endpoint
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.code)
+}
+
+// GetFile gets basic info about a file and prepares it for downloading
+func (c *Client) GetFile(ctx context.Context, req GetFileRequest) (*File, error) {
+	resp, err := c.makeRequest(ctx, "getFile", &req)
+	if err != nil {
+		return nil, err
+	}
+
+	fileBytes, err := json.Marshal(resp.Result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	var file File
+	if err := json.Unmarshal(fileBytes, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+
+	return &file, nil
+}
+
+// DownloadFile resolves the file path with getFile and streams the file contents into w.
+// It returns the number of bytes written. Files larger than the client's download size limit
+// are rejected with ErrFileTooLarge.
+func (c *Client) DownloadFile(ctx context.Context, fileID string, w io.Writer) (int64, error) {
+	file, err := c.GetFile(ctx, GetFileRequest{FileID: fileID})
+	if err != nil {
+		return 0, err
+	}
+
+	if file.FilePath == "" {
+		return 0, fmt.Errorf("file %s has no file path", fileID)
+	}
+	if file.FileSize > c.maxDownloadSize {
+		return 0, fmt.Errorf("%w: %d bytes", ErrFileTooLarge, file.FileSize)
+	}
+
+	strategy := c.retryStrategy()
+	retryable := strategy.RetryableErrors
+	cw := &countingWriter{w: w}
+	strategy.RetryableErrors = func(err error) bool {
+		// Once bytes reached w the download cannot be restarted transparently
+		if cw.n > 0 {
+			return false
+		}
+		var se *statusError
+		if errors.As(err, &se) {
+			return se.code >= http.StatusInternalServerError || se.code == http.StatusTooManyRequests
+		}
+		return retryable(err)
+	}
+
+	err = retry.Do(ctx, strategy, func() error {
+		c.logger.Debug("downloading telegram file", "file_id", fileID, "size", file.FileSize)
+
+		err := c.fetchFile(ctx, file.FilePath, cw)
+		if err != nil {
+			c.logger.Warn("telegram file download failed", "error", err, "file_id", fileID)
+		}
+		return err
+	})
+	if err != nil {
+		return cw.n, fmt.Errorf("failed to download file %s: %w", fileID, err)
+	}
+
+	return cw.n, nil
+}
+
+func (c *Client) fetchFile(ctx context.Context, filePath string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.fileBaseURL+filePath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create download request: %w", err)
+	}
+
+	resp, err := c.fileClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{code: resp.StatusCode}
+	}
+	if resp.ContentLength > c.maxDownloadSize {
+		return fmt.Errorf("%w: %d bytes", ErrFileTooLarge, resp.ContentLength)
+	}
+
+	// Read one byte past the limit to detect oversized bodies without a Content-Length
+	n, err := io.Copy(w, io.LimitReader(resp.Body, c.maxDownloadSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if n > c.maxDownloadSize {
+		return fmt.Errorf("%w: more than %d bytes", ErrFileTooLarge, c.maxDownloadSize)
+	}
+
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
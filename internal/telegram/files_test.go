@@ -0,0 +1,324 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const testToken = "123456:test-token"
+
+// fakeBotAPI is an httptest stand-in for the Bot API. getFile and the file endpoint serve files;
+// other methods are recorded and answered with a message, or with one message per album item
+// for sendMediaGroup, unless a test sets its own handler with handle.
+type fakeBotAPI struct {
+	files         map[string][]byte // file_id -> contents
+	reportedSizes map[string]int64  // file_id -> file_size returned by getFile, defaults to the real size
+	failures      atomic.Int32      // number of file downloads to answer with 502 first
+	downloads     atomic.Int32
+
+	mu       sync.Mutex
+	handlers map[string]http.HandlerFunc // method -> handler replacing the default answer
+	requests map[string]*sentRequest     // method -> last request answered by default
+	calls    map[string]int              // method -> number of requests
+}
+
+// sentRequest is what the fake Bot API received for one method call
+type sentRequest struct {
+	contentType string
+	fields      map[string]string
+	files       map[string]string // form field -> file name + ":" + contents
+}
+
+func newFakeBotAPI(t *testing.T) (*fakeBotAPI, *Client) {
+	t.Helper()
+
+	api := &fakeBotAPI{
+		files:         make(map[string][]byte),
+		reportedSizes: make(map[string]int64),
+		handlers:      make(map[string]http.HandlerFunc),
+		requests:      make(map[string]*sentRequest),
+		calls:         make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /bot"+testToken+"/{method}", api.serveMethod)
+	mux.HandleFunc("GET /file/bot"+testToken+"/documents/{fileID}", api.download)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := NewClient(testToken, nil).WithAPIURL(server.URL)
+	return api, client
+}
+
+// handle answers every request of method with handler
+func (api *fakeBotAPI) handle(method string, handler http.HandlerFunc) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.handlers[method] = handler
+}
+
+// request returns the last request of method answered by default, or nil if there was none
+func (api *fakeBotAPI) request(method string) *sentRequest {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.requests[method]
+}
+
+// callCount returns the number of requests of method
+func (api *fakeBotAPI) callCount(method string) int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.calls[method]
+}
+
+func (api *fakeBotAPI) serveMethod(w http.ResponseWriter, r *http.Request) {
+	method := r.PathValue("method")
+
+	api.mu.Lock()
+	api.calls[method]++
+	handler := api.handlers[method]
+	api.mu.Unlock()
+
+	switch {
+	case handler != nil:
+		handler(w, r)
+	case method == "getFile":
+		api.getFile(w, r)
+	default:
+		api.answer(w, r)
+	}
+}
+
+// answer records a request and answers it with a message
+func (api *fakeBotAPI) answer(w http.ResponseWriter, r *http.Request) {
+	method := r.PathValue("method")
+	sent := &sentRequest{contentType: r.Header.Get("Content-Type"), fields: map[string]string{}, files: map[string]string{}}
+
+	if strings.HasPrefix(sent.contentType, "multipart/form-data") {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for key, values := range r.MultipartForm.Value {
+			sent.fields[key] = values[0]
+		}
+		for key, headers := range r.MultipartForm.File {
+			f, err := headers[0].Open()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(f)
+			f.Close()
+			sent.files[key] = headers[0].Filename + ":" + string(data)
+		}
+	} else {
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for key, raw := range body {
+			var s string
+			if json.Unmarshal(raw, &s) != nil {
+				s = string(raw)
+			}
+			sent.fields[key] = s
+		}
+	}
+
+	api.mu.Lock()
+	api.requests[method] = sent
+	api.mu.Unlock()
+
+	message := `{"message_id":%d,"date":0,"chat":{"id":-100,"type":"channel"}}`
+	if method == "sendMediaGroup" {
+		var media []json.RawMessage
+		json.Unmarshal([]byte(sent.fields["media"]), &media)
+		items := make([]string, len(media))
+		for i := range media {
+			items[i] = fmt.Sprintf(message, 10+i)
+		}
+		fmt.Fprintf(w, `{"ok":true,"result":[%s]}`, strings.Join(items, ","))
+		return
+	}
+	fmt.Fprintf(w, `{"ok":true,"result":`+message+`}`, 1)
+}
+
+func (api *fakeBotAPI) getFile(w http.ResponseWriter, r *http.Request) {
+	var req GetFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, ok := api.files[req.FileID]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"ok":false,"error_code":400,"description":"Bad Request: invalid file_id"}`)
+		return
+	}
+
+	size, ok := api.reportedSizes[req.FileID]
+	if !ok {
+		size = int64(len(data))
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"ok": true,
+		"result": File{
+			FileID:       req.FileID,
+			FileUniqueID: "unique-" + req.FileID,
+			FileSize:     size,
+			FilePath:     "documents/" + req.FileID,
+		},
+	})
+}
+
+func (api *fakeBotAPI) download(w http.ResponseWriter, r *http.Request) {
+	api.downloads.Add(1)
+
+	if api.failures.Load() > 0 {
+		api.failures.Add(-1)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	data, ok := api.files[r.PathValue("fileID")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Stream without Content-Length so the size limit is enforced on the body
+	w.Header().Set("Transfer-Encoding", "chunked")
+	w.Write(data)
+}
+
+func TestClient_GetFile(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	api.files["file-1"] = []byte("hello")
+
+	file, err := client.GetFile(context.Background(), GetFileRequest{FileID: "file-1"})
+	if err != nil {
+		t.Fatalf("GetFile failed: %v", err)
+	}
+
+	if file.FilePath != "documents/file-1" {
+		t.Errorf("expected file_path 'documents/file-1', got '%s'", file.FilePath)
+	}
+	if file.FileSize != 5 {
+		t.Errorf("expected file_size 5, got %d", file.FileSize)
+	}
+}
+
+func TestClient_GetFile_APIError(t *testing.T) {
+	_, client := newFakeBotAPI(t)
+
+	if _, err := client.GetFile(context.Background(), GetFileRequest{FileID: "missing"}); err == nil {
+		t.Error("expected error for unknown file_id")
+	}
+}
+
+func TestClient_GetFile_Validation(t *testing.T) {
+	_, client := newFakeBotAPI(t)
+
+	if _, err := client.GetFile(context.Background(), GetFileRequest{}); err == nil {
+		t.Error("expected validation error for empty file_id")
+	}
+}
+
+func TestClient_DownloadFile(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	content := bytes.Repeat([]byte{0xFF, 0xD8, 0xFF, 0xE0}, 4096)
+	api.files["photo-1"] = content
+
+	var buf bytes.Buffer
+	n, err := client.DownloadFile(context.Background(), "photo-1", &buf)
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+
+	if n != int64(len(content)) {
+		t.Errorf("expected %d bytes written, got %d", len(content), n)
+	}
+	if !bytes.Equal(buf.Bytes(), content) {
+		t.Error("downloaded content does not match")
+	}
+}
+
+func TestClient_DownloadFile_RetriesServerErrors(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	api.files["photo-1"] = []byte("image data")
+	api.failures.Store(1)
+
+	var buf bytes.Buffer
+	if _, err := client.DownloadFile(context.Background(), "photo-1", &buf); err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+
+	if got := api.downloads.Load(); got != 2 {
+		t.Errorf("expected 2 download attempts, got %d", got)
+	}
+	if buf.String() != "image data" {
+		t.Errorf("expected 'image data', got '%s'", buf.String())
+	}
+}
+
+func TestClient_DownloadFile_TooLargeByFileSize(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	api.files["video-1"] = []byte("small")
+	api.reportedSizes["video-1"] = MaxDownloadSize + 1
+
+	var buf bytes.Buffer
+	_, err := client.DownloadFile(context.Background(), "video-1", &buf)
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("expected ErrFileTooLarge, got %v", err)
+	}
+
+	if got := api.downloads.Load(); got != 0 {
+		t.Errorf("expected no download attempt, got %d", got)
+	}
+}
+
+func TestClient_DownloadFile_TooLargeBody(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	client.WithMaxDownloadSize(8)
+	api.files["doc-1"] = []byte("0123456789")
+	// getFile may omit file_size, so the body itself must be limited
+	api.reportedSizes["doc-1"] = 0
+
+	var buf bytes.Buffer
+	_, err := client.DownloadFile(context.Background(), "doc-1", &buf)
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("expected ErrFileTooLarge, got %v", err)
+	}
+
+	if got := api.downloads.Load(); got != 1 {
+		t.Errorf("expected a single download attempt, got %d", got)
+	}
+}
+
+func TestClient_DownloadFile_ContextCanceled(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	api.files["photo-1"] = []byte("image data")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	if _, err := client.DownloadFile(ctx, "photo-1", &buf); err == nil {
+		t.Error("expected error for canceled context")
+	}
+}
@@ -0,0 +1,134 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/en9inerd/go-pkgs/validator"
+)
+
+func TestClient_SendMessage_ReplyMarkup(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+
+	_, err := client.SendMessage(context.Background(), SendMessageRequest{
+		ChatID: "@channel",
+		Text:   "New post",
+		ReplyMarkup: NewInlineKeyboard(
+			[]InlineKeyboardButton{{Text: "Read on the site", URL: "https://example.com/posts/1/"}},
+			[]InlineKeyboardButton{{Text: "👍", CallbackData: "like:1"}, {Text: "Comments", URL: "https://t.me/c/1/2?thread=2"}},
+		),
+	})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	expected := `{"inline_keyboard":[[{"text":"Read on the site","url":"https://example.com/posts/1/"}],` +
+		`[{"text":"👍","callback_data":"like:1"},{"text":"Comments","url":"https://t.me/c/1/2?thread=2"}]]}`
+	if got := api.request("sendMessage").fields["reply_markup"]; got != expected {
+		t.Errorf("expected reply_markup %s, got %s", expected, got)
+	}
+}
+
+func TestClient_SendPhoto_UploadWithReplyMarkup(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+
+	_, err := client.SendPhoto(context.Background(), SendPhotoRequest{
+		ChatID:      "@channel",
+		Photo:       InputFileUpload("cover.jpg", strings.NewReader("jpeg")),
+		ReplyMarkup: NewInlineKeyboard([]InlineKeyboardButton{{Text: "Open", URL: "tg://resolve?domain=channel"}}),
+	})
+	if err != nil {
+		t.Fatalf("SendPhoto failed: %v", err)
+	}
+
+	expected := `{"inline_keyboard":[[{"text":"Open","url":"tg://resolve?domain=channel"}]]}`
+	if got := api.request("sendPhoto").fields["reply_markup"]; got != expected {
+		t.Errorf("expected reply_markup form field %s, got %s", expected, got)
+	}
+}
+
+func TestClient_EditMessageReplyMarkup(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+
+	if _, err := client.EditMessageReplyMarkup(context.Background(), EditMessageReplyMarkupRequest{ChatID: "@channel", MessageID: 5}); err != nil {
+		t.Fatalf("EditMessageReplyMarkup failed: %v", err)
+	}
+	sent := api.request("editMessageReplyMarkup")
+	if _, ok := sent.fields["reply_markup"]; ok {
+		t.Errorf("expected no reply_markup to remove the keyboard, got %v", sent.fields)
+	}
+	if sent.fields["message_id"] != "5" {
+		t.Errorf("unexpected fields: %v", sent.fields)
+	}
+
+	_, err := client.EditMessageReplyMarkup(context.Background(), EditMessageReplyMarkupRequest{
+		ChatID:      "@channel",
+		MessageID:   5,
+		ReplyMarkup: NewInlineKeyboard([]InlineKeyboardButton{{Text: "Broken", URL: "javascript:alert(1)"}}),
+	})
+	if err == nil || !strings.Contains(err.Error(), "validation failed") {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestInlineKeyboardMarkup_Validate(t *testing.T) {
+	button := InlineKeyboardButton{Text: "Read", URL: "https://example.com"}
+	row := func(n int) []InlineKeyboardButton {
+		buttons := make([]InlineKeyboardButton, n)
+		for i := range buttons {
+			buttons[i] = button
+		}
+		return buttons
+	}
+	rows := func(n, size int) [][]InlineKeyboardButton {
+		keyboard := make([][]InlineKeyboardButton, n)
+		for i := range keyboard {
+			keyboard[i] = row(size)
+		}
+		return keyboard
+	}
+
+	tests := []struct {
+		name     string
+		keyboard [][]InlineKeyboardButton
+		field    string
+	}{
+		{"empty keyboard", nil, "reply_markup"},
+		{"empty row", [][]InlineKeyboardButton{row(1), {}}, "reply_markup.inline_keyboard[1]"},
+		{"row too long", [][]InlineKeyboardButton{row(9)}, "reply_markup.inline_keyboard[0]"},
+		{"too many buttons", rows(13, 8), "reply_markup"},
+		{"missing text", [][]InlineKeyboardButton{{{URL: "https://example.com"}}}, "reply_markup.inline_keyboard[0][0].text"},
+		{"no action", [][]InlineKeyboardButton{{{Text: "Nothing"}}}, "reply_markup.inline_keyboard[0][0]"},
+		{"two actions", [][]InlineKeyboardButton{{{Text: "Both", URL: "https://example.com", CallbackData: "x"}}}, "reply_markup.inline_keyboard[0][0]"},
+		{"relative url", [][]InlineKeyboardButton{{{Text: "Relative", URL: "/posts/1/"}}}, "reply_markup.inline_keyboard[0][0].url"},
+		{"url without host", [][]InlineKeyboardButton{{{Text: "No host", URL: "https:///posts"}}}, "reply_markup.inline_keyboard[0][0].url"},
+		{"unsupported scheme", [][]InlineKeyboardButton{{button, {Text: "FTP", URL: "ftp://example.com"}}}, "reply_markup.inline_keyboard[0][1].url"},
+		{"callback data too long", [][]InlineKeyboardButton{{{Text: "Long", CallbackData: strings.Repeat("x", 65)}}}, "reply_markup.inline_keyboard[0][0].callback_data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &validator.Validator{}
+			req := &SendMessageRequest{ChatID: "@channel", Text: "Post", ReplyMarkup: &InlineKeyboardMarkup{InlineKeyboard: tt.keyboard}}
+			req.Validate(v)
+			if _, ok := v.FieldErrors[tt.field]; !ok {
+				t.Errorf("expected an error for %s, got %s", tt.field, v.JSON())
+			}
+		})
+	}
+
+	valid := []validator.Validatable{
+		&SendMessageRequest{ChatID: "@channel", Text: "Post", ReplyMarkup: &InlineKeyboardMarkup{InlineKeyboard: rows(12, 8)}},
+		&EditMessageTextRequest{ChatID: "@channel", MessageID: 1, Text: "Post", ReplyMarkup: NewInlineKeyboard(row(8))},
+		&CopyMessageRequest{ChatID: "@a", FromChatID: "@b", MessageID: 1, ReplyMarkup: NewInlineKeyboard([]InlineKeyboardButton{{Text: "Like", CallbackData: strings.Repeat("x", 64)}})},
+		&EditMessageReplyMarkupRequest{InlineMessageID: "inline"},
+	}
+	for i, req := range valid {
+		v := &validator.Validator{}
+		req.Validate(v)
+		if !v.Valid() {
+			t.Errorf("expected request %d to be valid, got %s", i, v.JSON())
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SendPhoto sends a photo to a channel
+func (c *Client) SendPhoto(ctx context.Context, req SendPhotoRequest) (*Message, error) {
+	resp, err := c.makeRequest(ctx, "sendPhoto", &req)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMessageResult(resp.Result)
+}
+
+// SendDocument sends a general file to a channel
+func (c *Client) SendDocument(ctx context.Context, req SendDocumentRequest) (*Message, error) {
+	resp, err := c.makeRequest(ctx, "sendDocument", &req)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMessageResult(resp.Result)
+}
+
+// SendVideo sends a video to a channel
+func (c *Client) SendVideo(ctx context.Context, req SendVideoRequest) (*Message, error) {
+	resp, err := c.makeRequest(ctx, "sendVideo", &req)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMessageResult(resp.Result)
+}
+
+// SendMediaGroup sends an album to a channel and returns its messages
+func (c *Client) SendMediaGroup(ctx context.Context, req SendMediaGroupRequest) ([]Message, error) {
+	resp, err := c.makeRequest(ctx, "sendMediaGroup", &req)
+	if err != nil {
+		return nil, err
+	}
+
+	messagesBytes, err := json.Marshal(resp.Result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	var messages []Message
+	if err := json.Unmarshal(messagesBytes, &messages); err != nil {
+		return nil, fmt.Errorf("failed to parse messages: %w", err)
+	}
+
+	return messages, nil
+}
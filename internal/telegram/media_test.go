@@ -0,0 +1,207 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/en9inerd/go-pkgs/validator"
+)
+
+func TestClient_SendPhoto_URL(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+
+	msg, err := client.SendPhoto(context.Background(), SendPhotoRequest{
+		ChatID:  "@channel",
+		Photo:   InputFileURL("https://example.com/cover.jpg"),
+		Caption: "Cover",
+	})
+	if err != nil {
+		t.Fatalf("SendPhoto failed: %v", err)
+	}
+	if msg.MessageID != 1 {
+		t.Errorf("expected message 1, got %d", msg.MessageID)
+	}
+
+	sent := api.request("sendPhoto")
+	if !strings.HasPrefix(sent.contentType, "application/json") {
+		t.Errorf("expected a JSON request, got %s", sent.contentType)
+	}
+	if sent.fields["photo"] != "https://example.com/cover.jpg" || sent.fields["caption"] != "Cover" {
+		t.Errorf("unexpected fields: %v", sent.fields)
+	}
+}
+
+func TestClient_SendPhoto_Upload(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+
+	_, err := client.SendPhoto(context.Background(), SendPhotoRequest{
+		ChatID:     "-100123",
+		Photo:      InputFileUpload("cover.jpg", strings.NewReader("jpeg bytes")),
+		Caption:    "<b>Cover</b>",
+		ParseMode:  "HTML",
+		HasSpoiler: true,
+	})
+	if err != nil {
+		t.Fatalf("SendPhoto failed: %v", err)
+	}
+
+	sent := api.request("sendPhoto")
+	if !strings.HasPrefix(sent.contentType, "multipart/form-data") {
+		t.Fatalf("expected a multipart request, got %s", sent.contentType)
+	}
+	if sent.files["photo"] != "cover.jpg:jpeg bytes" {
+		t.Errorf("expected the photo uploaded as the photo field, got %v", sent.files)
+	}
+	expected := map[string]string{"chat_id": "-100123", "caption": "<b>Cover</b>", "parse_mode": "HTML", "has_spoiler": "true"}
+	for key, value := range expected {
+		if sent.fields[key] != value {
+			t.Errorf("expected %s=%q, got %q", key, value, sent.fields[key])
+		}
+	}
+	if _, ok := sent.fields["photo"]; ok {
+		t.Error("expected no photo form value next to the upload")
+	}
+}
+
+func TestClient_SendDocumentAndVideo(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	ctx := context.Background()
+
+	if _, err := client.SendDocument(ctx, SendDocumentRequest{ChatID: "@channel", Document: InputFileUpload("notes.pdf", strings.NewReader("%PDF"))}); err != nil {
+		t.Fatalf("SendDocument failed: %v", err)
+	}
+	if api.request("sendDocument").files["document"] != "notes.pdf:%PDF" {
+		t.Errorf("unexpected document upload: %v", api.request("sendDocument").files)
+	}
+
+	if _, err := client.SendVideo(ctx, SendVideoRequest{ChatID: "@channel", Video: InputFileID("BAADvideo"), Duration: 12, SupportsStreaming: true}); err != nil {
+		t.Fatalf("SendVideo failed: %v", err)
+	}
+	sent := api.request("sendVideo")
+	if sent.fields["video"] != "BAADvideo" || sent.fields["duration"] != "12" || sent.fields["supports_streaming"] != "true" {
+		t.Errorf("unexpected video fields: %v", sent.fields)
+	}
+}
+
+func TestClient_SendMediaGroup(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+
+	messages, err := client.SendMediaGroup(context.Background(), SendMediaGroupRequest{
+		ChatID: "@channel",
+		Media: []InputMedia{
+			&InputMediaPhoto{Media: InputFileUpload("a.jpg", strings.NewReader("first")), Caption: "Album"},
+			&InputMediaPhoto{Media: InputFileID("AgACphoto")},
+			&InputMediaVideo{Media: InputFileUpload("c.mp4", strings.NewReader("third")), Width: 640},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SendMediaGroup failed: %v", err)
+	}
+	if len(messages) != 3 || messages[2].MessageID != 12 {
+		t.Errorf("unexpected messages: %+v", messages)
+	}
+
+	sent := api.request("sendMediaGroup")
+	var media []map[string]any
+	if err := json.Unmarshal([]byte(sent.fields["media"]), &media); err != nil {
+		t.Fatalf("failed to decode media field %q: %v", sent.fields["media"], err)
+	}
+	expected := []map[string]any{
+		{"type": "photo", "media": "attach://file0", "caption": "Album"},
+		{"type": "photo", "media": "AgACphoto"},
+		{"type": "video", "media": "attach://file1", "width": float64(640)},
+	}
+	if fmt.Sprint(media) != fmt.Sprint(expected) {
+		t.Errorf("expected media %v, got %v", expected, media)
+	}
+	if sent.files["file0"] != "a.jpg:first" || sent.files["file1"] != "c.mp4:third" {
+		t.Errorf("unexpected attachments: %v", sent.files)
+	}
+}
+
+func TestClient_EditMessageMedia_Upload(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+
+	_, err := client.EditMessageMedia(context.Background(), EditMessageMediaRequest{
+		ChatID:    "@channel",
+		MessageID: 7,
+		Media:     &InputMediaDocument{Media: InputFileUpload("v2.pdf", strings.NewReader("new"))},
+	})
+	if err != nil {
+		t.Fatalf("EditMessageMedia failed: %v", err)
+	}
+
+	sent := api.request("editMessageMedia")
+	if sent.fields["media"] != `{"type":"document","media":"attach://file0"}` || sent.files["file0"] != "v2.pdf:new" {
+		t.Errorf("unexpected request: fields %v, files %v", sent.fields, sent.files)
+	}
+}
+
+func TestMediaRequests_Validate(t *testing.T) {
+	photo := func() InputMedia { return &InputMediaPhoto{Media: InputFileID("photo")} }
+	longCaption := strings.Repeat("é", MaxCaptionLength+1)
+
+	tests := []struct {
+		name  string
+		req   validator.Validatable
+		field string
+	}{
+		{"photo missing", &SendPhotoRequest{ChatID: "@channel"}, "photo"},
+		{"photo caption too long", &SendPhotoRequest{ChatID: "@channel", Photo: InputFileID("x"), Caption: longCaption}, "caption"},
+		{"document parse mode", &SendDocumentRequest{ChatID: "@channel", Document: InputFileID("x"), ParseMode: "BBCode"}, "parse_mode"},
+		{"video chat missing", &SendVideoRequest{Video: InputFileID("x")}, "chat_id"},
+		{"album of one", &SendMediaGroupRequest{ChatID: "@channel", Media: []InputMedia{photo()}}, "media"},
+		{"album of eleven", &SendMediaGroupRequest{ChatID: "@channel", Media: []InputMedia{
+			photo(), photo(), photo(), photo(), photo(), photo(), photo(), photo(), photo(), photo(), photo(),
+		}}, "media"},
+		{"album mixing documents", &SendMediaGroupRequest{ChatID: "@channel", Media: []InputMedia{
+			photo(), &InputMediaDocument{Media: InputFileID("doc")},
+		}}, "media"},
+		{"album item caption too long", &SendMediaGroupRequest{ChatID: "@channel", Media: []InputMedia{
+			photo(), &InputMediaVideo{Media: InputFileID("video"), Caption: longCaption},
+		}}, "media[1].caption"},
+		{"album item file missing", &SendMediaGroupRequest{ChatID: "@channel", Media: []InputMedia{photo(), &InputMediaPhoto{}}}, "media[1].media"},
+		{"edit media missing", &EditMessageMediaRequest{ChatID: "@channel", MessageID: 1}, "media"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &validator.Validator{}
+			tt.req.Validate(v)
+			if _, ok := v.FieldErrors[tt.field]; !ok {
+				t.Errorf("expected an error for %s, got %s", tt.field, v.JSON())
+			}
+		})
+	}
+
+	valid := []validator.Validatable{
+		&SendPhotoRequest{ChatID: "@channel", Photo: InputFileID("x"), Caption: strings.Repeat("é", MaxCaptionLength)},
+		&SendMediaGroupRequest{ChatID: "@channel", Media: []InputMedia{photo(), &InputMediaVideo{Media: InputFileURL("https://example.com/v.mp4")}}},
+		&SendMediaGroupRequest{ChatID: "@channel", Media: []InputMedia{&InputMediaDocument{Media: InputFileID("a")}, &InputMediaDocument{Media: InputFileID("b")}}},
+	}
+	for i, req := range valid {
+		v := &validator.Validator{}
+		req.Validate(v)
+		if !v.Valid() {
+			t.Errorf("expected request %d to be valid, got %s", i, v.JSON())
+		}
+	}
+}
+
+func TestClient_SendMediaGroup_ValidationFails(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+
+	_, err := client.SendMediaGroup(context.Background(), SendMediaGroupRequest{
+		ChatID: "@channel",
+		Media:  []InputMedia{&InputMediaPhoto{Media: InputFileID("only")}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "validation failed") {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+	if n := api.callCount("sendMediaGroup"); n != 0 {
+		t.Errorf("expected no request to be sent, got %d", n)
+	}
+}
@@ -0,0 +1,256 @@
+package telegram
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket: Burst requests may be sent at once, and the bucket refills
+// at Rate requests per second. A zero Rate disables the limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// Telegram's documented limits: about 30 messages per second overall,
+// and no more than 20 messages per minute to the same group or channel.
+var (
+	DefaultGlobalRateLimit = RateLimit{Rate: 30, Burst: 30}
+	DefaultChatRateLimit   = RateLimit{Rate: 20.0 / 60, Burst: 3}
+)
+
+// maxIdleChats is the number of per-chat buckets kept before full ones are dropped
+const maxIdleChats = 1000
+
+// tokenBucket tracks the tokens of one RateLimit. Tokens go negative when requests
+// are reserved ahead of time.
+type tokenBucket struct {
+	limit        RateLimit
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time // Set from retry_after
+}
+
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{limit: limit, tokens: float64(max(limit.Burst, 1)), last: now}
+}
+
+// reserve takes a token and returns how long to wait before using it
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	var wait time.Duration
+	if b.limit.Rate > 0 {
+		b.refill(now)
+		b.tokens--
+		if b.tokens < 0 {
+			wait = time.Duration(-b.tokens / b.limit.Rate * float64(time.Second))
+		}
+	}
+	return max(wait, b.blocked(now))
+}
+
+// blocked returns how long retry_after still holds back requests
+func (b *tokenBucket) blocked(now time.Time) time.Duration {
+	if b.blockedUntil.After(now) {
+		return b.blockedUntil.Sub(now)
+	}
+	return 0
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(float64(max(b.limit.Burst, 1)), b.tokens+elapsed.Seconds()*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// idle reports whether the bucket is full and not blocked, so dropping it changes nothing
+func (b *tokenBucket) idle(now time.Time) bool {
+	if b.blockedUntil.After(now) {
+		return false
+	}
+	if b.limit.Rate <= 0 {
+		return true
+	}
+	b.refill(now)
+	return b.tokens >= float64(max(b.limit.Burst, 1))
+}
+
+// release returns a reserved token that was not used
+func (b *tokenBucket) release(now time.Time) {
+	if b.limit.Rate > 0 {
+		b.refill(now)
+		b.tokens = min(float64(max(b.limit.Burst, 1)), b.tokens+1)
+	}
+}
+
+// rateLimiter spaces out requests with a global bucket and one bucket per chat.
+// A channel is limited as one chat whether it is addressed by @username or by ID,
+// once a response has shown which ID the username belongs to.
+type rateLimiter struct {
+	mu      sync.Mutex
+	global  *tokenBucket
+	perChat RateLimit
+	chats   map[string]*tokenBucket
+	ids     map[string]string // Lowercase @username -> chat ID
+	now     func() time.Time
+}
+
+func newRateLimiter(global, perChat RateLimit) *rateLimiter {
+	return &rateLimiter{
+		global:  newTokenBucket(global, time.Now()),
+		perChat: perChat,
+		chats:   make(map[string]*tokenBucket),
+		ids:     make(map[string]string),
+		now:     time.Now,
+	}
+}
+
+// reserve takes a token from the global bucket and the bucket of chatID,
+// and returns how long to wait before sending. Requests not sent to a chat, like
+// getUpdates and getFile, take no token and only wait out a global retry_after.
+func (l *rateLimiter) reserve(chatID string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if chatID == "" {
+		return l.global.blocked(now)
+	}
+	return max(l.global.reserve(now), l.chat(chatID, now).reserve(now))
+}
+
+// wait blocks until a request to chatID may be sent. A request given up while waiting
+// returns its tokens.
+func (l *rateLimiter) wait(ctx context.Context, chatID string) error {
+	if d := l.reserve(chatID); d > 0 && !sleep(ctx, d) {
+		l.release(chatID)
+		return ctx.Err()
+	}
+	return nil
+}
+
+// release returns the tokens reserved for a request to chatID that was not sent
+func (l *rateLimiter) release(chatID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if chatID == "" {
+		return
+	}
+	now := l.now()
+	l.global.release(now)
+	l.chat(chatID, now).release(now)
+}
+
+// learn records the ID of the chat a request to chatID was sent to. Requests to its
+// @username then share the bucket of the ID.
+func (l *rateLimiter) learn(chatID string, chat *Chat) {
+	if !strings.HasPrefix(chatID, "@") || !strings.EqualFold(chatID[1:], chat.Username) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	username := strings.ToLower(chatID)
+	if _, ok := l.ids[username]; ok {
+		return
+	}
+	id := strconv.FormatInt(chat.ID, 10)
+	l.ids[username] = id
+
+	bucket, ok := l.chats[username]
+	if !ok {
+		return
+	}
+	delete(l.chats, username)
+	if existing, ok := l.chats[id]; ok {
+		// Both buckets counted requests to the same chat
+		now := l.now()
+		bucket.refill(now)
+		existing.refill(now)
+		existing.tokens += bucket.tokens - float64(max(bucket.limit.Burst, 1))
+		if bucket.blockedUntil.After(existing.blockedUntil) {
+			existing.blockedUntil = bucket.blockedUntil
+		}
+		return
+	}
+	l.chats[id] = bucket
+}
+
+// pause holds back requests to chatID, or all requests when chatID is empty, for d
+func (l *rateLimiter) pause(chatID string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	bucket := l.global
+	if chatID != "" {
+		bucket = l.chat(chatID, now)
+	}
+	if until := now.Add(d); until.After(bucket.blockedUntil) {
+		bucket.blockedUntil = until
+	}
+}
+
+func (l *rateLimiter) chat(chatID string, now time.Time) *tokenBucket {
+	if strings.HasPrefix(chatID, "@") {
+		chatID = strings.ToLower(chatID)
+		if id, ok := l.ids[chatID]; ok {
+			chatID = id
+		}
+	}
+
+	bucket, ok := l.chats[chatID]
+	if ok {
+		return bucket
+	}
+
+	if len(l.chats) >= maxIdleChats {
+		for id, b := range l.chats {
+			if b.idle(now) {
+				delete(l.chats, id)
+			}
+		}
+	}
+	bucket = newTokenBucket(l.perChat, now)
+	l.chats[chatID] = bucket
+	return bucket
+}
+
+// chatRequest is a request sent to a chat, limited by the chat's bucket
+type chatRequest interface {
+	chatID() string
+}
+
+// requestChatID returns the chat_id of a request, or "" for requests not sent to a chat
+func requestChatID(payload any) string {
+	if req, ok := payload.(chatRequest); ok {
+		return req.chatID()
+	}
+	return ""
+}
+
+// resultChat returns the chat of the message, or first message of an album, a request returned
+func resultChat(result any) (*Chat, bool) {
+	if messages, ok := result.([]any); ok && len(messages) > 0 {
+		result = messages[0]
+	}
+	message, ok := result.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	chat, ok := message["chat"].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	id, ok := chat["id"].(float64)
+	if !ok {
+		return nil, false
+	}
+	username, _ := chat["username"].(string)
+	return &Chat{ID: int64(id), Username: username}, true
+}
@@ -0,0 +1,291 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket_Reserve(t *testing.T) {
+	start := time.Now()
+	bucket := newTokenBucket(RateLimit{Rate: 2, Burst: 3}, start)
+
+	for i := range 3 {
+		if wait := bucket.reserve(start); wait != 0 {
+			t.Errorf("expected request %d of the burst to go at once, got wait %v", i, wait)
+		}
+	}
+	if wait := bucket.reserve(start); wait != 500*time.Millisecond {
+		t.Errorf("expected the fourth request to wait 500ms, got %v", wait)
+	}
+	if wait := bucket.reserve(start); wait != time.Second {
+		t.Errorf("expected the fifth request to wait 1s, got %v", wait)
+	}
+
+	// Two seconds refill four tokens, two of which were already reserved
+	later := start.Add(2 * time.Second)
+	if wait := bucket.reserve(later); wait != 0 {
+		t.Errorf("expected a refilled token, got wait %v", wait)
+	}
+
+	bucket.blockedUntil = later.Add(3 * time.Second)
+	if wait := bucket.reserve(later); wait != 3*time.Second {
+		t.Errorf("expected to wait for retry_after, got %v", wait)
+	}
+}
+
+func TestTokenBucket_Unlimited(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(RateLimit{}, now)
+	for range 100 {
+		if wait := bucket.reserve(now); wait != 0 {
+			t.Fatalf("expected no limit, got wait %v", wait)
+		}
+	}
+	if !bucket.idle(now) {
+		t.Error("expected an unlimited bucket to be idle")
+	}
+}
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(RateLimit{Rate: 10, Burst: 3}, RateLimit{Rate: 1, Burst: 1})
+	limiter.now = func() time.Time { return now }
+	limiter.global = newTokenBucket(RateLimit{Rate: 10, Burst: 3}, now)
+
+	if wait := limiter.reserve("@a"); wait != 0 {
+		t.Errorf("expected the first request to @a to go at once, got %v", wait)
+	}
+	if wait := limiter.reserve("@a"); wait != time.Second {
+		t.Errorf("expected the second request to @a to wait for its chat, got %v", wait)
+	}
+	if wait := limiter.reserve("@b"); wait != 0 {
+		t.Errorf("expected other chats not to wait, got %v", wait)
+	}
+	if wait := limiter.reserve("@c"); wait != 100*time.Millisecond {
+		t.Errorf("expected the global limit to apply, got %v", wait)
+	}
+
+	limiter.pause("@b", 5*time.Second)
+	if wait := limiter.reserve("@b"); wait != 5*time.Second {
+		t.Errorf("expected @b to be paused, got %v", wait)
+	}
+	limiter.pause("", 7*time.Second)
+	if wait := limiter.reserve("@d"); wait != 7*time.Second {
+		t.Errorf("expected all chats to be paused, got %v", wait)
+	}
+}
+
+func TestRateLimiter_RequestsNotSentToChats(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(RateLimit{Rate: 1, Burst: 1}, RateLimit{})
+	limiter.now = func() time.Time { return now }
+	limiter.global = newTokenBucket(RateLimit{Rate: 1, Burst: 1}, now)
+
+	// Long polling does not use up the budget for sending
+	for range 5 {
+		if wait := limiter.reserve(""); wait != 0 {
+			t.Fatalf("expected requests not sent to a chat to go at once, got %v", wait)
+		}
+	}
+	if wait := limiter.reserve("@a"); wait != 0 {
+		t.Errorf("expected the global token to be left for a chat, got wait %v", wait)
+	}
+
+	limiter.pause("", 3*time.Second)
+	if wait := limiter.reserve(""); wait != 3*time.Second {
+		t.Errorf("expected a global retry_after to hold back all requests, got %v", wait)
+	}
+}
+
+func TestRateLimiter_DropsIdleChats(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(RateLimit{}, RateLimit{Rate: 1, Burst: 1})
+	limiter.now = func() time.Time { return now }
+
+	for i := range maxIdleChats {
+		limiter.reserve(fmt.Sprint(i))
+	}
+	now = now.Add(time.Minute)
+	limiter.pause("0", time.Hour)
+	limiter.reserve("new")
+
+	if len(limiter.chats) != 2 {
+		t.Errorf("expected only the paused and the new chat to be kept, got %d", len(limiter.chats))
+	}
+}
+
+func TestRateLimiter_UsernameSharesBucketOfID(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(RateLimit{}, RateLimit{Rate: 1, Burst: 1})
+	limiter.now = func() time.Time { return now }
+
+	limiter.reserve("@Channel")
+	limiter.learn("@channel", &Chat{ID: -100123, Username: "other"})
+	if wait := limiter.reserve("-100123"); wait != 0 {
+		t.Errorf("expected a response of another chat not to link the username, got wait %v", wait)
+	}
+
+	// The bucket of the username is merged into the bucket of the ID
+	limiter.learn("@Channel", &Chat{ID: -100123, Username: "channel"})
+	if wait := limiter.reserve("@channel"); wait != 2*time.Second {
+		t.Errorf("expected the username to share the bucket of the ID, got wait %v", wait)
+	}
+	if wait := limiter.reserve("-100123"); wait != 3*time.Second {
+		t.Errorf("expected the ID to share the bucket of the username, got wait %v", wait)
+	}
+	if len(limiter.chats) != 1 {
+		t.Errorf("expected a single bucket for the channel, got %d", len(limiter.chats))
+	}
+}
+
+func TestRateLimiter_WaitReleasesTokensWhenCancelled(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(RateLimit{Rate: 1, Burst: 1}, RateLimit{Rate: 1, Burst: 1})
+	limiter.now = func() time.Time { return now }
+	limiter.global = newTokenBucket(RateLimit{Rate: 1, Burst: 1}, now)
+
+	limiter.reserve("@a")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.wait(ctx, "@a"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the wait to be cancelled, got %v", err)
+	}
+
+	// The cancelled request does not hold back the next one
+	if wait := limiter.reserve("@a"); wait != time.Second {
+		t.Errorf("expected the next request to wait for one token, got %v", wait)
+	}
+}
+
+func TestResultChat(t *testing.T) {
+	var resp APIResponse
+	if err := json.Unmarshal([]byte(`{"ok":true,"result":[{"message_id":1,"chat":{"id":-100123,"type":"channel","username":"channel"}}]}`), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if chat, ok := resultChat(resp.Result); !ok || chat.ID != -100123 || chat.Username != "channel" {
+		t.Errorf("expected the chat of the album, got %+v", chat)
+	}
+	if _, ok := resultChat(true); ok {
+		t.Error("expected no chat for a result that is not a message")
+	}
+}
+
+func TestRequestChatID(t *testing.T) {
+	if id := requestChatID(&SendMessageRequest{ChatID: "@channel"}); id != "@channel" {
+		t.Errorf("expected @channel, got %q", id)
+	}
+	if id := requestChatID(&GetFileRequest{FileID: "x"}); id != "" {
+		t.Errorf("expected no chat, got %q", id)
+	}
+}
+
+// flood answers the first failures requests of method with 429 and the given retry_after
+func (api *fakeBotAPI) flood(method string, retryAfter, failures int) {
+	api.handle(method, func(w http.ResponseWriter, r *http.Request) {
+		if api.callCount(method) <= failures {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprintf(w, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after %d","parameters":{"retry_after":%d}}`, retryAfter, retryAfter)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":9,"date":0,"chat":{"id":-100,"type":"channel"}}}`)
+	})
+}
+
+func TestClient_WaitsForRetryAfter(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	api.flood("sendMessage", 1, 1)
+
+	start := time.Now()
+	msg, err := client.SendMessage(context.Background(), SendMessageRequest{ChatID: "@channel", Text: "Hello"})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if msg.MessageID != 9 {
+		t.Errorf("expected message 9, got %d", msg.MessageID)
+	}
+	if api.callCount("sendMessage") != 2 {
+		t.Errorf("expected 2 calls, got %d", api.callCount("sendMessage"))
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected to wait retry_after (1s), took %v", elapsed)
+	}
+}
+
+func TestClient_RetryAfterTooLong(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	api.flood("sendMessage", 120, 1)
+
+	start := time.Now()
+	_, err := client.SendMessage(context.Background(), SendMessageRequest{ChatID: "@channel", Text: "Hello"})
+	if err == nil || !strings.Contains(err.Error(), "Too Many Requests") {
+		t.Fatalf("expected the 429 error, got %v", err)
+	}
+	if api.callCount("sendMessage") != 1 {
+		t.Errorf("expected 1 call, got %d", api.callCount("sendMessage"))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to fail without waiting, took %v", elapsed)
+	}
+}
+
+func TestClient_RetryAfterStopsOnContext(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	api.flood("sendMessage", 1, 100)
+	client.WithRateLimits(RateLimit{}, RateLimit{})
+
+	// Pausing after each 429 would take four seconds, so give up on the context instead
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	_, err := client.makeRequest(ctx, "sendMessage", &SendMessageRequest{ChatID: "@channel", Text: "Hello"})
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("expected the deadline to stop waiting, got %v", err)
+	}
+	if api.callCount("sendMessage") != 2 {
+		t.Errorf("expected 2 calls before the deadline, got %d", api.callCount("sendMessage"))
+	}
+}
+
+func TestClient_ChatRateLimit(t *testing.T) {
+	_, client := newFakeBotAPI(t)
+	client.WithRateLimits(RateLimit{}, RateLimit{Rate: 10, Burst: 1})
+
+	start := time.Now()
+	for _, chat := range []string{"@a", "@b", "@c"} {
+		if _, err := client.SendMessage(context.Background(), SendMessageRequest{ChatID: chat, Text: "Hello"}); err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 90*time.Millisecond {
+		t.Errorf("expected different chats not to wait, took %v", elapsed)
+	}
+
+	start = time.Now()
+	for range 3 {
+		if _, err := client.SendMessage(context.Background(), SendMessageRequest{ChatID: "@a", Text: "Hello"}); err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected requests to the same chat to be spaced 100ms apart, took %v", elapsed)
+	}
+}
+
+func TestAPIResponse_Parameters(t *testing.T) {
+	var resp APIResponse
+	data := `{"ok":false,"error_code":400,"description":"Bad Request: group chat was upgraded to a supergroup chat","parameters":{"migrate_to_chat_id":-1001234567890}}`
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Parameters == nil || resp.Parameters.MigrateToChatID != -1001234567890 {
+		t.Errorf("unexpected parameters: %+v", resp.Parameters)
+	}
+	if resp.retryAfter() != 0 {
+		t.Errorf("expected no retry_after for a 400, got %v", resp.retryAfter())
+	}
+}
@@ -1,15 +1,154 @@
 package telegram
 
-import "github.com/en9inerd/go-pkgs/validator"
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/en9inerd/go-pkgs/validator"
+)
+
+// Update represents an incoming update from the Telegram Bot API.
+// At most one of the optional fields is present in any given update.
+type Update struct {
+	UpdateID          int64    `json:"update_id"`
+	Message           *Message `json:"message,omitempty"`
+	EditedMessage     *Message `json:"edited_message,omitempty"`
+	ChannelPost       *Message `json:"channel_post,omitempty"`
+	EditedChannelPost *Message `json:"edited_channel_post,omitempty"`
+}
 
 // Message represents a Telegram message
 type Message struct {
-	MessageID int64  `json:"message_id"`
-	Date      int64  `json:"date"`
-	Chat      *Chat  `json:"chat,omitempty"`
-	Text      string `json:"text,omitempty"`
-	Caption   string `json:"caption,omitempty"`
-	From      *User  `json:"from,omitempty"`
+	MessageID       int64           `json:"message_id"`
+	Date            int64           `json:"date"`
+	Chat            *Chat           `json:"chat,omitempty"`
+	Text            string          `json:"text,omitempty"`
+	Caption         string          `json:"caption,omitempty"`
+	From            *User           `json:"from,omitempty"`
+	SenderChat      *Chat           `json:"sender_chat,omitempty"`
+	AuthorSignature string          `json:"author_signature,omitempty"`
+	ForwardOrigin   *MessageOrigin  `json:"forward_origin,omitempty"`
+	EditDate        int64           `json:"edit_date,omitempty"`
+	MediaGroupID    string          `json:"media_group_id,omitempty"`   // Shared by all messages of an album
+	Entities        []MessageEntity `json:"entities,omitempty"`         // Formatting of Text
+	CaptionEntities []MessageEntity `json:"caption_entities,omitempty"` // Formatting of Caption
+	Photo           []PhotoSize     `json:"photo,omitempty"`            // Available sizes of the photo, smallest first
+	Document        *Document       `json:"document,omitempty"`
+	Video           *Video          `json:"video,omitempty"`
+	Animation       *Animation      `json:"animation,omitempty"` // When set, Document is set as well
+	HasMediaSpoiler bool            `json:"has_media_spoiler,omitempty"`
+}
+
+// Message entity types
+const (
+	EntityMention       = "mention"
+	EntityHashtag       = "hashtag"
+	EntityCashtag       = "cashtag"
+	EntityBotCommand    = "bot_command"
+	EntityURL           = "url"
+	EntityEmail         = "email"
+	EntityPhoneNumber   = "phone_number"
+	EntityBold          = "bold"
+	EntityItalic        = "italic"
+	EntityUnderline     = "underline"
+	EntityStrikethrough = "strikethrough"
+	EntitySpoiler       = "spoiler"
+	EntityBlockquote    = "blockquote"
+	EntityExpandable    = "expandable_blockquote"
+	EntityCode          = "code"
+	EntityPre           = "pre"
+	EntityTextLink      = "text_link"
+	EntityTextMention   = "text_mention"
+	EntityCustomEmoji   = "custom_emoji"
+)
+
+// MessageEntity represents one special entity in a text message.
+// Offset and Length are measured in UTF-16 code units.
+type MessageEntity struct {
+	Type          string `json:"type"`
+	Offset        int    `json:"offset"`
+	Length        int    `json:"length"`
+	URL           string `json:"url,omitempty"`             // For "text_link" only
+	User          *User  `json:"user,omitempty"`            // For "text_mention" only
+	Language      string `json:"language,omitempty"`        // For "pre" only
+	CustomEmojiID string `json:"custom_emoji_id,omitempty"` // For "custom_emoji" only
+}
+
+// Message origin types
+const (
+	OriginUser       = "user"
+	OriginHiddenUser = "hidden_user"
+	OriginChat       = "chat"
+	OriginChannel    = "channel"
+)
+
+// MessageOrigin describes the origin of a forwarded message.
+// Which optional fields are set depends on Type.
+type MessageOrigin struct {
+	Type            string `json:"type"` // "user", "hidden_user", "chat", "channel"
+	Date            int64  `json:"date"`
+	SenderUser      *User  `json:"sender_user,omitempty"`      // For "user"
+	SenderUserName  string `json:"sender_user_name,omitempty"` // For "hidden_user"
+	SenderChat      *Chat  `json:"sender_chat,omitempty"`      // For "chat"
+	Chat            *Chat  `json:"chat,omitempty"`             // For "channel"
+	MessageID       int64  `json:"message_id,omitempty"`       // For "channel"
+	AuthorSignature string `json:"author_signature,omitempty"` // For "chat" and "channel"
+}
+
+// PhotoSize represents one size of a photo or a file/sticker thumbnail
+type PhotoSize struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// Document represents a general file
+type Document struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Thumbnail    *PhotoSize `json:"thumbnail,omitempty"`
+	FileName     string     `json:"file_name,omitempty"`
+	MimeType     string     `json:"mime_type,omitempty"`
+	FileSize     int64      `json:"file_size,omitempty"`
+}
+
+// Video represents a video file
+type Video struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Width        int        `json:"width"`
+	Height       int        `json:"height"`
+	Duration     int        `json:"duration"` // In seconds
+	Thumbnail    *PhotoSize `json:"thumbnail,omitempty"`
+	FileName     string     `json:"file_name,omitempty"`
+	MimeType     string     `json:"mime_type,omitempty"`
+	FileSize     int64      `json:"file_size,omitempty"`
+}
+
+// Animation represents an animation file (GIF or H.264/MPEG-4 AVC video without sound)
+type Animation struct {
+	FileID       string     `json:"file_id"`
+	FileUniqueID string     `json:"file_unique_id"`
+	Width        int        `json:"width"`
+	Height       int        `json:"height"`
+	Duration     int        `json:"duration"` // In seconds
+	Thumbnail    *PhotoSize `json:"thumbnail,omitempty"`
+	FileName     string     `json:"file_name,omitempty"`
+	MimeType     string     `json:"mime_type,omitempty"`
+	FileSize     int64      `json:"file_size,omitempty"`
+}
+
+// File represents a file ready to be downloaded
+type File struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileSize     int64  `json:"file_size,omitempty"`
+	FilePath     string `json:"file_path,omitempty"` // Use DownloadFile to fetch the contents
 }
 
 // Chat represents a Telegram chat (channel, group, etc.)
@@ -36,10 +175,25 @@ type User struct {
 // Result is a generic "any" to handle different response types
 // (Message for most operations, bool for delete/pin operations, etc.)
 type APIResponse struct {
-	OK          bool   `json:"ok"`
-	Description string `json:"description,omitempty"`
-	ErrorCode   int    `json:"error_code,omitempty"`
-	Result      any    `json:"result,omitempty"`
+	OK          bool                `json:"ok"`
+	Description string              `json:"description,omitempty"`
+	ErrorCode   int                 `json:"error_code,omitempty"`
+	Result      any                 `json:"result,omitempty"`
+	Parameters  *ResponseParameters `json:"parameters,omitempty"` // Why a request failed and how to recover
+}
+
+// retryAfter returns how long to wait before repeating a request that hit flood control, or 0
+func (r *APIResponse) retryAfter() time.Duration {
+	if r.OK || r.Parameters == nil || r.ErrorCode != http.StatusTooManyRequests {
+		return 0
+	}
+	return time.Duration(r.Parameters.RetryAfter) * time.Second
+}
+
+// ResponseParameters describes why a request failed
+type ResponseParameters struct {
+	MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"` // The group was migrated to a supergroup with this ID
+	RetryAfter      int   `json:"retry_after,omitempty"`        // Seconds to wait when flood control was exceeded
 }
 
 // SendMessageRequest represents a request to send a message
@@ -50,8 +204,12 @@ type SendMessageRequest struct {
 	DisableWebPagePreview bool   `json:"disable_web_page_preview,omitempty"`
 	DisableNotification   bool   `json:"disable_notification,omitempty"`
 	ReplyToMessageID      int64  `json:"reply_to_message_id,omitempty"`
+
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"` // Buttons below the message
 }
 
+func (r *SendMessageRequest) chatID() string { return r.ChatID }
+
 // Validate validates the SendMessageRequest
 func (r *SendMessageRequest) Validate(v *validator.Validator) {
 	v.CheckField(validator.NotBlank(r.ChatID), "chat_id", "chat_id is required")
@@ -60,6 +218,7 @@ func (r *SendMessageRequest) Validate(v *validator.Validator) {
 	if r.ParseMode != "" {
 		v.CheckField(validator.PermittedValue(r.ParseMode, "HTML", "Markdown", "MarkdownV2"), "parse_mode", "parse_mode must be HTML, Markdown, or MarkdownV2")
 	}
+	r.ReplyMarkup.validate(v)
 }
 
 // EditMessageTextRequest represents a request to edit message text
@@ -70,8 +229,12 @@ type EditMessageTextRequest struct {
 	ParseMode             string `json:"parse_mode,omitempty"` // "HTML", "Markdown", "MarkdownV2"
 	DisableWebPagePreview bool   `json:"disable_web_page_preview,omitempty"`
 	InlineMessageID       string `json:"inline_message_id,omitempty"` // For inline messages
+
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"` // Replaces the buttons, which are removed when nil
 }
 
+func (r *EditMessageTextRequest) chatID() string { return r.ChatID }
+
 // Validate validates the EditMessageTextRequest
 func (r *EditMessageTextRequest) Validate(v *validator.Validator) {
 	v.CheckField(validator.NotBlank(r.ChatID) || validator.NotBlank(r.InlineMessageID), "chat_id", "chat_id or inline_message_id is required")
@@ -83,6 +246,7 @@ func (r *EditMessageTextRequest) Validate(v *validator.Validator) {
 	if r.MessageID == 0 && r.InlineMessageID == "" {
 		v.AddNonFieldError("either message_id or inline_message_id must be provided")
 	}
+	r.ReplyMarkup.validate(v)
 }
 
 // EditMessageCaptionRequest represents a request to edit message caption
@@ -92,8 +256,12 @@ type EditMessageCaptionRequest struct {
 	Caption         string `json:"caption,omitempty"`           // New caption
 	ParseMode       string `json:"parse_mode,omitempty"`        // "HTML", "Markdown", "MarkdownV2"
 	InlineMessageID string `json:"inline_message_id,omitempty"` // For inline messages
+
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"` // Replaces the buttons, which are removed when nil
 }
 
+func (r *EditMessageCaptionRequest) chatID() string { return r.ChatID }
+
 // Validate validates the EditMessageCaptionRequest
 func (r *EditMessageCaptionRequest) Validate(v *validator.Validator) {
 	v.CheckField(validator.NotBlank(r.ChatID) || validator.NotBlank(r.InlineMessageID), "chat_id", "chat_id or inline_message_id is required")
@@ -103,14 +271,361 @@ func (r *EditMessageCaptionRequest) Validate(v *validator.Validator) {
 	if r.MessageID == 0 && r.InlineMessageID == "" {
 		v.AddNonFieldError("either message_id or inline_message_id must be provided")
 	}
+	r.ReplyMarkup.validate(v)
 }
 
 // EditMessageMediaRequest represents a request to edit message media
 type EditMessageMediaRequest struct {
-	ChatID          string `json:"chat_id"`                     // Channel username or ID
-	MessageID       int64  `json:"message_id"`                  // Message ID to edit
-	Media           any    `json:"media"`                       // InputMedia object
-	InlineMessageID string `json:"inline_message_id,omitempty"` // For inline messages
+	ChatID          string     `json:"chat_id"`                     // Channel username or ID
+	MessageID       int64      `json:"message_id"`                  // Message ID to edit
+	Media           InputMedia `json:"media"`                       // New photo, video or document
+	InlineMessageID string     `json:"inline_message_id,omitempty"` // For inline messages
+
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"` // Replaces the buttons, which are removed when nil
+}
+
+func (r *EditMessageMediaRequest) chatID() string { return r.ChatID }
+
+// Validate validates the EditMessageMediaRequest
+func (r *EditMessageMediaRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.ChatID) || validator.NotBlank(r.InlineMessageID), "chat_id", "chat_id or inline_message_id is required")
+	if r.Media == nil {
+		v.AddFieldError("media", "media is required")
+	} else {
+		r.Media.validate(v, "media")
+	}
+	if r.MessageID == 0 && r.InlineMessageID == "" {
+		v.AddNonFieldError("either message_id or inline_message_id must be provided")
+	}
+	r.ReplyMarkup.validate(v)
+}
+
+func (r *EditMessageMediaRequest) inputFiles() []*InputFile {
+	if r.Media == nil {
+		return nil
+	}
+	return r.Media.inputFiles()
+}
+
+// MaxCaptionLength is the longest caption of a media message
+const MaxCaptionLength = 1024
+
+// validateCaption checks the length and parse mode of a caption. prefix names the object holding it, e.g. "media[0].".
+func validateCaption(v *validator.Validator, prefix, caption, parseMode string) {
+	v.CheckField(validator.MaxChars(caption, MaxCaptionLength), prefix+"caption", "caption must be 1024 characters or less")
+	if parseMode != "" {
+		v.CheckField(validator.PermittedValue(parseMode, "HTML", "Markdown", "MarkdownV2"), prefix+"parse_mode", "parse_mode must be HTML, Markdown, or MarkdownV2")
+	}
+}
+
+// Input media types
+const (
+	MediaPhoto    = "photo"
+	MediaVideo    = "video"
+	MediaDocument = "document"
+)
+
+// InputMedia is the content of a media message to send: *InputMediaPhoto, *InputMediaVideo or *InputMediaDocument
+type InputMedia interface {
+	// MediaType returns MediaPhoto, MediaVideo or MediaDocument
+	MediaType() string
+
+	inputFiles() []*InputFile
+	validate(v *validator.Validator, field string)
+}
+
+// InputMediaPhoto represents a photo to be sent
+type InputMediaPhoto struct {
+	Media      InputFile `json:"media"`
+	Caption    string    `json:"caption,omitempty"`
+	ParseMode  string    `json:"parse_mode,omitempty"` // "HTML", "Markdown", "MarkdownV2"
+	HasSpoiler bool      `json:"has_spoiler,omitempty"`
+}
+
+// MediaType returns MediaPhoto
+func (m *InputMediaPhoto) MediaType() string { return MediaPhoto }
+
+// MarshalJSON adds the media type to the JSON object
+func (m *InputMediaPhoto) MarshalJSON() ([]byte, error) {
+	type photo InputMediaPhoto
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		*photo
+	}{MediaPhoto, (*photo)(m)})
+}
+
+func (m *InputMediaPhoto) inputFiles() []*InputFile { return []*InputFile{&m.Media} }
+
+func (m *InputMediaPhoto) validate(v *validator.Validator, field string) {
+	m.Media.validate(v, field+".media")
+	validateCaption(v, field+".", m.Caption, m.ParseMode)
+}
+
+// InputMediaVideo represents a video to be sent
+type InputMediaVideo struct {
+	Media             InputFile `json:"media"`
+	Caption           string    `json:"caption,omitempty"`
+	ParseMode         string    `json:"parse_mode,omitempty"` // "HTML", "Markdown", "MarkdownV2"
+	Width             int       `json:"width,omitempty"`
+	Height            int       `json:"height,omitempty"`
+	Duration          int       `json:"duration,omitempty"` // In seconds
+	SupportsStreaming bool      `json:"supports_streaming,omitempty"`
+	HasSpoiler        bool      `json:"has_spoiler,omitempty"`
+}
+
+// MediaType returns MediaVideo
+func (m *InputMediaVideo) MediaType() string { return MediaVideo }
+
+// MarshalJSON adds the media type to the JSON object
+func (m *InputMediaVideo) MarshalJSON() ([]byte, error) {
+	type video InputMediaVideo
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		*video
+	}{MediaVideo, (*video)(m)})
+}
+
+func (m *InputMediaVideo) inputFiles() []*InputFile { return []*InputFile{&m.Media} }
+
+func (m *InputMediaVideo) validate(v *validator.Validator, field string) {
+	m.Media.validate(v, field+".media")
+	validateCaption(v, field+".", m.Caption, m.ParseMode)
+}
+
+// InputMediaDocument represents a general file to be sent
+type InputMediaDocument struct {
+	Media                       InputFile `json:"media"`
+	Caption                     string    `json:"caption,omitempty"`
+	ParseMode                   string    `json:"parse_mode,omitempty"` // "HTML", "Markdown", "MarkdownV2"
+	DisableContentTypeDetection bool      `json:"disable_content_type_detection,omitempty"`
+}
+
+// MediaType returns MediaDocument
+func (m *InputMediaDocument) MediaType() string { return MediaDocument }
+
+// MarshalJSON adds the media type to the JSON object
+func (m *InputMediaDocument) MarshalJSON() ([]byte, error) {
+	type document InputMediaDocument
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		*document
+	}{MediaDocument, (*document)(m)})
+}
+
+func (m *InputMediaDocument) inputFiles() []*InputFile { return []*InputFile{&m.Media} }
+
+func (m *InputMediaDocument) validate(v *validator.Validator, field string) {
+	m.Media.validate(v, field+".media")
+	validateCaption(v, field+".", m.Caption, m.ParseMode)
+}
+
+// SendPhotoRequest represents a request to send a photo
+type SendPhotoRequest struct {
+	ChatID              string    `json:"chat_id"` // Channel username (e.g., "@channel") or ID
+	Photo               InputFile `json:"photo"`
+	Caption             string    `json:"caption,omitempty"`
+	ParseMode           string    `json:"parse_mode,omitempty"` // "HTML", "Markdown", "MarkdownV2"
+	HasSpoiler          bool      `json:"has_spoiler,omitempty"`
+	DisableNotification bool      `json:"disable_notification,omitempty"`
+	ReplyToMessageID    int64     `json:"reply_to_message_id,omitempty"`
+
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"` // Buttons below the message
+}
+
+func (r *SendPhotoRequest) chatID() string { return r.ChatID }
+
+// Validate validates the SendPhotoRequest
+func (r *SendPhotoRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.ChatID), "chat_id", "chat_id is required")
+	r.Photo.validate(v, "photo")
+	validateCaption(v, "", r.Caption, r.ParseMode)
+	r.ReplyMarkup.validate(v)
+}
+
+func (r *SendPhotoRequest) inputFiles() []*InputFile { return []*InputFile{&r.Photo} }
+
+// SendDocumentRequest represents a request to send a general file
+type SendDocumentRequest struct {
+	ChatID                      string    `json:"chat_id"` // Channel username (e.g., "@channel") or ID
+	Document                    InputFile `json:"document"`
+	Caption                     string    `json:"caption,omitempty"`
+	ParseMode                   string    `json:"parse_mode,omitempty"` // "HTML", "Markdown", "MarkdownV2"
+	DisableContentTypeDetection bool      `json:"disable_content_type_detection,omitempty"`
+	DisableNotification         bool      `json:"disable_notification,omitempty"`
+	ReplyToMessageID            int64     `json:"reply_to_message_id,omitempty"`
+
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"` // Buttons below the message
+}
+
+func (r *SendDocumentRequest) chatID() string { return r.ChatID }
+
+// Validate validates the SendDocumentRequest
+func (r *SendDocumentRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.ChatID), "chat_id", "chat_id is required")
+	r.Document.validate(v, "document")
+	validateCaption(v, "", r.Caption, r.ParseMode)
+	r.ReplyMarkup.validate(v)
+}
+
+func (r *SendDocumentRequest) inputFiles() []*InputFile { return []*InputFile{&r.Document} }
+
+// SendVideoRequest represents a request to send a video
+type SendVideoRequest struct {
+	ChatID              string    `json:"chat_id"` // Channel username (e.g., "@channel") or ID
+	Video               InputFile `json:"video"`
+	Duration            int       `json:"duration,omitempty"` // In seconds
+	Width               int       `json:"width,omitempty"`
+	Height              int       `json:"height,omitempty"`
+	Caption             string    `json:"caption,omitempty"`
+	ParseMode           string    `json:"parse_mode,omitempty"` // "HTML", "Markdown", "MarkdownV2"
+	HasSpoiler          bool      `json:"has_spoiler,omitempty"`
+	SupportsStreaming   bool      `json:"supports_streaming,omitempty"`
+	DisableNotification bool      `json:"disable_notification,omitempty"`
+	ReplyToMessageID    int64     `json:"reply_to_message_id,omitempty"`
+
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"` // Buttons below the message
+}
+
+func (r *SendVideoRequest) chatID() string { return r.ChatID }
+
+// Validate validates the SendVideoRequest
+func (r *SendVideoRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.ChatID), "chat_id", "chat_id is required")
+	r.Video.validate(v, "video")
+	validateCaption(v, "", r.Caption, r.ParseMode)
+	v.CheckField(r.Duration >= 0 && r.Width >= 0 && r.Height >= 0, "video", "duration, width and height must not be negative")
+	r.ReplyMarkup.validate(v)
+}
+
+func (r *SendVideoRequest) inputFiles() []*InputFile { return []*InputFile{&r.Video} }
+
+// SendMediaGroupRequest represents a request to send an album
+type SendMediaGroupRequest struct {
+	ChatID              string       `json:"chat_id"` // Channel username (e.g., "@channel") or ID
+	Media               []InputMedia `json:"media"`   // 2-10 photos and videos, or 2-10 documents
+	DisableNotification bool         `json:"disable_notification,omitempty"`
+	ReplyToMessageID    int64        `json:"reply_to_message_id,omitempty"`
+}
+
+func (r *SendMediaGroupRequest) chatID() string { return r.ChatID }
+
+// Validate validates the SendMediaGroupRequest
+func (r *SendMediaGroupRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.ChatID), "chat_id", "chat_id is required")
+	v.CheckField(len(r.Media) >= 2 && len(r.Media) <= 10, "media", "media must include 2-10 items")
+
+	documents := 0
+	for i, media := range r.Media {
+		field := fmt.Sprintf("media[%d]", i)
+		if media == nil {
+			v.AddFieldError(field, field+" is required")
+			continue
+		}
+		if media.MediaType() == MediaDocument {
+			documents++
+		}
+		media.validate(v, field)
+	}
+	if documents > 0 && documents < len(r.Media) {
+		v.AddFieldError("media", "documents cannot be grouped with photos or videos")
+	}
+}
+
+func (r *SendMediaGroupRequest) inputFiles() []*InputFile {
+	var files []*InputFile
+	for _, media := range r.Media {
+		if media != nil {
+			files = append(files, media.inputFiles()...)
+		}
+	}
+	return files
+}
+
+// EditMessageReplyMarkupRequest represents a request to edit the inline keyboard of a message
+type EditMessageReplyMarkupRequest struct {
+	ChatID          string                `json:"chat_id,omitempty"`           // Channel username or ID
+	MessageID       int64                 `json:"message_id,omitempty"`        // Message ID to edit
+	InlineMessageID string                `json:"inline_message_id,omitempty"` // For inline messages
+	ReplyMarkup     *InlineKeyboardMarkup `json:"reply_markup,omitempty"`      // New buttons, nil removes them
+}
+
+func (r *EditMessageReplyMarkupRequest) chatID() string { return r.ChatID }
+
+// Validate validates the EditMessageReplyMarkupRequest
+func (r *EditMessageReplyMarkupRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.ChatID) || validator.NotBlank(r.InlineMessageID), "chat_id", "chat_id or inline_message_id is required")
+	if r.MessageID == 0 && r.InlineMessageID == "" {
+		v.AddNonFieldError("either message_id or inline_message_id must be provided")
+	}
+	r.ReplyMarkup.validate(v)
+}
+
+// Inline keyboard limits
+const (
+	MaxInlineKeyboardButtons = 100 // Buttons in a keyboard
+	MaxInlineKeyboardRow     = 8   // Buttons in a row
+	MaxCallbackDataLength    = 64  // Bytes of callback data
+)
+
+// InlineKeyboardMarkup represents buttons shown below a message, row by row
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// NewInlineKeyboard creates a keyboard from rows of buttons
+func NewInlineKeyboard(rows ...[]InlineKeyboardButton) *InlineKeyboardMarkup {
+	return &InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// InlineKeyboardButton represents a button of an inline keyboard.
+// Exactly one of URL and CallbackData must be set.
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	URL          string `json:"url,omitempty"`           // http(s):// or tg:// link opened by the button
+	CallbackData string `json:"callback_data,omitempty"` // Sent to the bot in a callback query, 1-64 bytes
+}
+
+// validate checks the number of buttons and each button. A nil keyboard is valid.
+func (m *InlineKeyboardMarkup) validate(v *validator.Validator) {
+	if m == nil {
+		return
+	}
+
+	count := 0
+	for i, row := range m.InlineKeyboard {
+		count += len(row)
+		v.CheckField(len(row) > 0 && len(row) <= MaxInlineKeyboardRow, fmt.Sprintf("reply_markup.inline_keyboard[%d]", i), "a row must have 1-8 buttons")
+		for j, button := range row {
+			button.validate(v, fmt.Sprintf("reply_markup.inline_keyboard[%d][%d]", i, j))
+		}
+	}
+	v.CheckField(count > 0 && count <= MaxInlineKeyboardButtons, "reply_markup", "reply_markup must have 1-100 buttons")
+}
+
+func (b *InlineKeyboardButton) validate(v *validator.Validator, field string) {
+	v.CheckField(validator.NotBlank(b.Text), field+".text", "text is required")
+	v.CheckField((b.URL == "") != (b.CallbackData == ""), field, "exactly one of url and callback_data is required")
+	if b.URL != "" {
+		v.CheckField(isButtonURL(b.URL), field+".url", "url must be an http, https or tg URL")
+	}
+	if b.CallbackData != "" {
+		v.CheckField(len(b.CallbackData) <= MaxCallbackDataLength, field+".callback_data", "callback_data must be 64 bytes or less")
+	}
+}
+
+// isButtonURL reports whether s is a URL Telegram opens from a button
+func isButtonURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return u.Host != ""
+	case "tg":
+		return u.Host != "" || u.Opaque != ""
+	}
+	return false
 }
 
 // DeleteMessageRequest represents a request to delete a message
@@ -119,6 +634,8 @@ type DeleteMessageRequest struct {
 	MessageID int64  `json:"message_id"` // Message ID to delete
 }
 
+func (r *DeleteMessageRequest) chatID() string { return r.ChatID }
+
 // Validate validates the DeleteMessageRequest
 func (r *DeleteMessageRequest) Validate(v *validator.Validator) {
 	v.CheckField(validator.NotBlank(r.ChatID), "chat_id", "chat_id is required")
@@ -133,6 +650,8 @@ type ForwardMessageRequest struct {
 	DisableNotification bool   `json:"disable_notification,omitempty"`
 }
 
+func (r *ForwardMessageRequest) chatID() string { return r.ChatID }
+
 // Validate validates the ForwardMessageRequest
 func (r *ForwardMessageRequest) Validate(v *validator.Validator) {
 	v.CheckField(validator.NotBlank(r.ChatID), "chat_id", "chat_id is required")
@@ -149,8 +668,12 @@ type CopyMessageRequest struct {
 	ParseMode           string `json:"parse_mode,omitempty"` // "HTML", "Markdown", "MarkdownV2"
 	DisableNotification bool   `json:"disable_notification,omitempty"`
 	ReplyToMessageID    int64  `json:"reply_to_message_id,omitempty"`
+
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"` // Buttons below the copy
 }
 
+func (r *CopyMessageRequest) chatID() string { return r.ChatID }
+
 // Validate validates the CopyMessageRequest
 func (r *CopyMessageRequest) Validate(v *validator.Validator) {
 	v.CheckField(validator.NotBlank(r.ChatID), "chat_id", "chat_id is required")
@@ -159,6 +682,7 @@ func (r *CopyMessageRequest) Validate(v *validator.Validator) {
 	if r.ParseMode != "" {
 		v.CheckField(validator.PermittedValue(r.ParseMode, "HTML", "Markdown", "MarkdownV2"), "parse_mode", "parse_mode must be HTML, Markdown, or MarkdownV2")
 	}
+	r.ReplyMarkup.validate(v)
 }
 
 // PinChatMessageRequest represents a request to pin a message
@@ -168,6 +692,8 @@ type PinChatMessageRequest struct {
 	DisableNotification bool   `json:"disable_notification,omitempty"`
 }
 
+func (r *PinChatMessageRequest) chatID() string { return r.ChatID }
+
 // Validate validates the PinChatMessageRequest
 func (r *PinChatMessageRequest) Validate(v *validator.Validator) {
 	v.CheckField(validator.NotBlank(r.ChatID), "chat_id", "chat_id is required")
@@ -180,6 +706,8 @@ type UnpinChatMessageRequest struct {
 	MessageID int64  `json:"message_id"` // Message ID to unpin (optional, if not provided unpins all)
 }
 
+func (r *UnpinChatMessageRequest) chatID() string { return r.ChatID }
+
 // Validate validates the UnpinChatMessageRequest
 func (r *UnpinChatMessageRequest) Validate(v *validator.Validator) {
 	v.CheckField(validator.NotBlank(r.ChatID), "chat_id", "chat_id is required")
@@ -191,7 +719,38 @@ type UnpinAllChatMessagesRequest struct {
 	ChatID string `json:"chat_id"` // Channel username or ID
 }
 
+func (r *UnpinAllChatMessagesRequest) chatID() string { return r.ChatID }
+
 // Validate validates the UnpinAllChatMessagesRequest
 func (r *UnpinAllChatMessagesRequest) Validate(v *validator.Validator) {
 	v.CheckField(validator.NotBlank(r.ChatID), "chat_id", "chat_id is required")
 }
+
+// GetFileRequest represents a request to get file info for downloading
+type GetFileRequest struct {
+	FileID string `json:"file_id"`
+}
+
+// Validate validates the GetFileRequest
+func (r *GetFileRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.FileID), "file_id", "file_id is required")
+}
+
+// GetUpdatesRequest represents a request to receive incoming updates using long polling
+type GetUpdatesRequest struct {
+	Offset         int64    `json:"offset,omitempty"`          // Identifier of the first update to be returned
+	Limit          int      `json:"limit,omitempty"`           // 1-100, defaults to 100
+	Timeout        int      `json:"timeout,omitempty"`         // Long polling timeout in seconds
+	AllowedUpdates []string `json:"allowed_updates,omitempty"` // e.g. "channel_post", "edited_channel_post"
+}
+
+// Validate validates the GetUpdatesRequest
+func (r *GetUpdatesRequest) Validate(v *validator.Validator) {
+	v.CheckField(r.Limit >= 0 && r.Limit <= 100, "limit", "limit must be between 1 and 100")
+	v.CheckField(r.Timeout >= 0, "timeout", "timeout must not be negative")
+}
+
+// DeleteWebhookRequest represents a request to remove webhook integration
+type DeleteWebhookRequest struct {
+	DropPendingUpdates bool `json:"drop_pending_updates,omitempty"`
+}
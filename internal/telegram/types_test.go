@@ -0,0 +1,223 @@
+package telegram
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadUpdate(t *testing.T, name string) Update {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	var update Update
+	if err := json.Unmarshal(data, &update); err != nil {
+		t.Fatalf("failed to decode fixture %s: %v", name, err)
+	}
+
+	return update
+}
+
+func TestUpdate_ChannelPostWithEntities(t *testing.T) {
+	update := loadUpdate(t, "channel_post_text_entities.json")
+
+	if update.UpdateID != 538012745 {
+		t.Errorf("expected update_id 538012745, got %d", update.UpdateID)
+	}
+
+	msg := update.ChannelPost
+	if msg == nil {
+		t.Fatal("expected channel_post to be set")
+	}
+	if update.EditedChannelPost != nil || update.Message != nil {
+		t.Error("expected only channel_post to be set")
+	}
+
+	if msg.Chat == nil || msg.Chat.ID != -1001987654321 || msg.Chat.Type != "channel" {
+		t.Errorf("unexpected chat: %+v", msg.Chat)
+	}
+	if msg.SenderChat == nil || msg.SenderChat.Username != "devnotes" {
+		t.Errorf("unexpected sender_chat: %+v", msg.SenderChat)
+	}
+
+	if len(msg.Entities) != 5 {
+		t.Fatalf("expected 5 entities, got %d", len(msg.Entities))
+	}
+
+	link := msg.Entities[2]
+	if link.Type != EntityTextLink || link.URL != "https://example.com/changelog" {
+		t.Errorf("unexpected text_link entity: %+v", link)
+	}
+	if link.Offset != 25 || link.Length != 9 {
+		t.Errorf("expected text_link at 25+9 (UTF-16), got %d+%d", link.Offset, link.Length)
+	}
+
+	pre := msg.Entities[3]
+	if pre.Type != EntityPre || pre.Language != "bash" {
+		t.Errorf("unexpected pre entity: %+v", pre)
+	}
+
+	if msg.Entities[4].Type != EntityHashtag {
+		t.Errorf("expected hashtag entity, got %q", msg.Entities[4].Type)
+	}
+}
+
+func TestUpdate_AlbumPhoto(t *testing.T) {
+	msg := loadUpdate(t, "channel_post_album_photo.json").ChannelPost
+	if msg == nil {
+		t.Fatal("expected channel_post to be set")
+	}
+
+	if msg.MediaGroupID != "13722445081524468" {
+		t.Errorf("expected media_group_id to be decoded, got %q", msg.MediaGroupID)
+	}
+	if !msg.HasMediaSpoiler {
+		t.Error("expected has_media_spoiler to be true")
+	}
+
+	if len(msg.Photo) != 3 {
+		t.Fatalf("expected 3 photo sizes, got %d", len(msg.Photo))
+	}
+	largest := msg.Photo[2]
+	if largest.Width != 1280 || largest.Height != 853 || largest.FileSize != 102366 {
+		t.Errorf("unexpected largest photo size: %+v", largest)
+	}
+	if largest.FileID != "AgACAgIAAx0CdGVzdAACBLVmZ1-large" {
+		t.Errorf("unexpected file_id: %s", largest.FileID)
+	}
+
+	if msg.Caption != "Conference photos, part 1" {
+		t.Errorf("unexpected caption: %q", msg.Caption)
+	}
+	if len(msg.CaptionEntities) != 1 || msg.CaptionEntities[0].Type != EntityItalic {
+		t.Errorf("unexpected caption_entities: %+v", msg.CaptionEntities)
+	}
+}
+
+func TestUpdate_EditedChannelPost(t *testing.T) {
+	update := loadUpdate(t, "edited_channel_post.json")
+
+	if update.ChannelPost != nil {
+		t.Error("expected channel_post to be nil")
+	}
+
+	msg := update.EditedChannelPost
+	if msg == nil {
+		t.Fatal("expected edited_channel_post to be set")
+	}
+	if msg.MessageID != 1204 {
+		t.Errorf("expected message_id 1204, got %d", msg.MessageID)
+	}
+	if msg.EditDate != 1718032512 {
+		t.Errorf("expected edit_date 1718032512, got %d", msg.EditDate)
+	}
+	if msg.AuthorSignature != "Alex" {
+		t.Errorf("expected author_signature Alex, got %q", msg.AuthorSignature)
+	}
+}
+
+func TestUpdate_ForwardOrigin(t *testing.T) {
+	msg := loadUpdate(t, "channel_post_forwarded.json").ChannelPost
+	if msg == nil {
+		t.Fatal("expected channel_post to be set")
+	}
+
+	origin := msg.ForwardOrigin
+	if origin == nil {
+		t.Fatal("expected forward_origin to be set")
+	}
+	if origin.Type != OriginChannel {
+		t.Errorf("expected origin type channel, got %q", origin.Type)
+	}
+	if origin.Chat == nil || origin.Chat.Username != "gonews" {
+		t.Errorf("unexpected origin chat: %+v", origin.Chat)
+	}
+	if origin.MessageID != 877 || origin.Date != 1717999200 {
+		t.Errorf("unexpected origin message: id=%d date=%d", origin.MessageID, origin.Date)
+	}
+	if origin.AuthorSignature != "Editor" {
+		t.Errorf("expected author_signature Editor, got %q", origin.AuthorSignature)
+	}
+}
+
+func TestUpdate_Document(t *testing.T) {
+	msg := loadUpdate(t, "channel_post_document.json").ChannelPost
+	if msg == nil || msg.Document == nil {
+		t.Fatal("expected document to be set")
+	}
+
+	doc := msg.Document
+	if doc.FileName != "slides.pdf" || doc.MimeType != "application/pdf" {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+	if doc.FileSize != 2483911 {
+		t.Errorf("expected file_size 2483911, got %d", doc.FileSize)
+	}
+	if doc.Thumbnail == nil || doc.Thumbnail.Width != 226 {
+		t.Errorf("unexpected thumbnail: %+v", doc.Thumbnail)
+	}
+}
+
+func TestUpdate_Video(t *testing.T) {
+	msg := loadUpdate(t, "channel_post_video.json").ChannelPost
+	if msg == nil || msg.Video == nil {
+		t.Fatal("expected video to be set")
+	}
+
+	video := msg.Video
+	if video.Duration != 42 || video.Width != 1920 || video.Height != 1080 {
+		t.Errorf("unexpected video dimensions: %+v", video)
+	}
+	if video.FileName != "demo.mp4" || video.MimeType != "video/mp4" {
+		t.Errorf("unexpected video file: %+v", video)
+	}
+	if video.Thumbnail == nil || video.Thumbnail.FileID != "AAMCAgADHQJ0ZXN0-vthumb" {
+		t.Errorf("unexpected thumbnail: %+v", video.Thumbnail)
+	}
+}
+
+func TestUpdate_Animation(t *testing.T) {
+	msg := loadUpdate(t, "channel_post_animation.json").ChannelPost
+	if msg == nil || msg.Animation == nil {
+		t.Fatal("expected animation to be set")
+	}
+
+	if msg.Animation.Duration != 3 || msg.Animation.FileSize != 201344 {
+		t.Errorf("unexpected animation: %+v", msg.Animation)
+	}
+
+	// Telegram sends animations as documents too, for backward compatibility
+	if msg.Document == nil || msg.Document.FileID != msg.Animation.FileID {
+		t.Errorf("expected document to mirror the animation, got %+v", msg.Document)
+	}
+}
+
+func TestAPIResponse_GetUpdates(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "get_updates.json"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	var resp struct {
+		OK     bool     `json:"ok"`
+		Result []Update `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("failed to decode getUpdates response: %v", err)
+	}
+
+	if !resp.OK || len(resp.Result) != 2 {
+		t.Fatalf("expected 2 updates, got %d", len(resp.Result))
+	}
+	if resp.Result[0].ChannelPost == nil || resp.Result[0].ChannelPost.Text != "First" {
+		t.Errorf("unexpected first update: %+v", resp.Result[0])
+	}
+	if resp.Result[1].EditedChannelPost == nil || resp.Result[1].EditedChannelPost.EditDate != 1718053260 {
+		t.Errorf("unexpected second update: %+v", resp.Result[1])
+	}
+}
@@ -0,0 +1,182 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetUpdates receives incoming updates using long polling
+func (c *Client) GetUpdates(ctx context.Context, req GetUpdatesRequest) ([]Update, error) {
+	resp, err := c.makeRequest(ctx, "getUpdates", &req)
+	if err != nil {
+		return nil, err
+	}
+
+	updatesBytes, err := json.Marshal(resp.Result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	var updates []Update
+	if err := json.Unmarshal(updatesBytes, &updates); err != nil {
+		return nil, fmt.Errorf("failed to parse updates: %w", err)
+	}
+
+	return updates, nil
+}
+
+// DeleteWebhook removes the webhook integration, which is required before using GetUpdates
+func (c *Client) DeleteWebhook(ctx context.Context, req DeleteWebhookRequest) (bool, error) {
+	resp, err := c.makeRequest(ctx, "deleteWebhook", &req)
+	if err != nil {
+		return false, err
+	}
+
+	return resp.OK, nil
+}
+
+// PollOptions configures the long polling loop
+type PollOptions struct {
+	OffsetFile     string        // File used to persist the offset between restarts (optional)
+	Timeout        int           // Long polling timeout in seconds, defaults to 25
+	AllowedUpdates []string      // Update types to receive
+	RetryDelay     time.Duration // Delay before polling again after a failure, defaults to 5s
+	MaxAttempts    int           // Times an update is handled before it is skipped, defaults to 5
+
+	// OnSkip is called with an update that failed MaxAttempts times and the last error, before
+	// the offset moves past it (optional)
+	OnSkip func(update *Update, err error)
+}
+
+// Poll receives updates with getUpdates and passes them to handle one at a time until ctx is done.
+// The offset is advanced and persisted only after an update has been handled successfully,
+// so a failed update is delivered again on the next poll. An update that keeps failing is
+// skipped after MaxAttempts, so it doesn't hold back the updates after it.
+func (c *Client) Poll(ctx context.Context, opts PollOptions, handle func(context.Context, *Update) error) error {
+	if opts.Timeout == 0 {
+		opts.Timeout = 25
+	}
+	if opts.RetryDelay == 0 {
+		opts.RetryDelay = 5 * time.Second
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = 5
+	}
+
+	offset, err := readOffset(opts.OffsetFile)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Info("polling telegram updates", "offset", offset)
+
+	attempts := 0 // Failed attempts to handle the update at offset
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		updates, err := c.GetUpdates(ctx, GetUpdatesRequest{
+			Offset:         offset,
+			Timeout:        opts.Timeout,
+			AllowedUpdates: opts.AllowedUpdates,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.logger.Warn("failed to get telegram updates", "error", err)
+			if !sleep(ctx, opts.RetryDelay) {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		for i := range updates {
+			update := &updates[i]
+			if err := handle(ctx, update); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				attempts++
+				if attempts < opts.MaxAttempts {
+					c.logger.Error("failed to handle telegram update", "update_id", update.UpdateID, "attempt", attempts, "error", err)
+					if !sleep(ctx, opts.RetryDelay) {
+						return ctx.Err()
+					}
+					break
+				}
+
+				c.logger.Error("skipping telegram update after repeated failures", "update_id", update.UpdateID, "attempts", attempts, "error", err)
+				if opts.OnSkip != nil {
+					opts.OnSkip(update, err)
+				}
+			}
+
+			attempts = 0
+			offset = update.UpdateID + 1
+			if err := writeOffset(opts.OffsetFile, offset); err != nil {
+				c.logger.Error("failed to persist telegram offset", "offset", offset, "error", err)
+			}
+		}
+	}
+}
+
+func readOffset(path string) (int64, error) {
+	if path == "" {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read offset file: %w", err)
+	}
+
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset in %s: %w", path, err)
+	}
+
+	return offset, nil
+}
+
+// writeOffset replaces the offset file atomically so a crash never leaves it truncated
+func writeOffset(path string, offset int64) error {
+	if path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create offset directory: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(strconv.FormatInt(offset, 10)), 0644); err != nil {
+		return fmt.Errorf("failed to write offset file: %w", err)
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
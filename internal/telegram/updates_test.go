@@ -0,0 +1,139 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// serveUpdates answers getUpdates with the updates from the requested offset on and records the offsets
+func (api *fakeBotAPI) serveUpdates(ids ...int64) *[]int64 {
+	var offsets []int64
+	api.handle("getUpdates", func(w http.ResponseWriter, r *http.Request) {
+		var req GetUpdatesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		api.mu.Lock()
+		offsets = append(offsets, req.Offset)
+		api.mu.Unlock()
+
+		updates := []Update{}
+		for _, id := range ids {
+			if id >= req.Offset {
+				updates = append(updates, Update{UpdateID: id})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": updates})
+	})
+	return &offsets
+}
+
+func readOffsetFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read offset file: %v", err)
+	}
+	return string(data)
+}
+
+func TestClient_Poll_PersistsOffset(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	offsets := api.serveUpdates(10, 11, 12)
+	opts := PollOptions{OffsetFile: filepath.Join(t.TempDir(), "data", "offset"), RetryDelay: time.Millisecond}
+
+	// Stop after update 11; update 12 fails with the cancelled context and stays unhandled
+	var handled []int64
+	ctx, cancel := context.WithCancel(context.Background())
+	err := client.Poll(ctx, opts, func(ctx context.Context, update *Update) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		handled = append(handled, update.UpdateID)
+		if update.UpdateID == 11 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Poll to stop with the context, got %v", err)
+	}
+	if !slices.Equal(handled, []int64{10, 11}) {
+		t.Errorf("expected updates 10 and 11 to be handled, got %v", handled)
+	}
+	if offset := readOffsetFile(t, opts.OffsetFile); offset != "12" {
+		t.Errorf("expected offset 12 to be persisted, got %s", offset)
+	}
+
+	// A restart resumes at the persisted offset
+	handled = nil
+	ctx, cancel = context.WithCancel(context.Background())
+	err = client.Poll(ctx, opts, func(ctx context.Context, update *Update) error {
+		handled = append(handled, update.UpdateID)
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Poll to stop with the context, got %v", err)
+	}
+	if !slices.Equal(handled, []int64{12}) {
+		t.Errorf("expected only update 12 after the restart, got %v", handled)
+	}
+	if requested := (*offsets)[len(*offsets)-1]; requested != 12 {
+		t.Errorf("expected getUpdates with offset 12 after the restart, got %d", requested)
+	}
+	if offset := readOffsetFile(t, opts.OffsetFile); offset != "13" {
+		t.Errorf("expected offset 13 to be persisted, got %s", offset)
+	}
+}
+
+func TestClient_Poll_SkipsFailingUpdate(t *testing.T) {
+	api, client := newFakeBotAPI(t)
+	api.serveUpdates(20, 21)
+
+	var skipped []int64
+	opts := PollOptions{
+		OffsetFile:  filepath.Join(t.TempDir(), "offset"),
+		RetryDelay:  time.Millisecond,
+		MaxAttempts: 3,
+		OnSkip: func(update *Update, err error) {
+			if err.Error() != "poison" {
+				t.Errorf("expected the handler error, got %v", err)
+			}
+			skipped = append(skipped, update.UpdateID)
+		},
+	}
+
+	attempts := make(map[int64]int)
+	ctx, cancel := context.WithCancel(context.Background())
+	err := client.Poll(ctx, opts, func(ctx context.Context, update *Update) error {
+		attempts[update.UpdateID]++
+		if update.UpdateID == 20 {
+			return errors.New("poison")
+		}
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Poll to stop with the context, got %v", err)
+	}
+
+	if attempts[20] != 3 || attempts[21] != 1 {
+		t.Errorf("expected 3 attempts for the failing update and 1 for the next, got %v", attempts)
+	}
+	if !slices.Equal(skipped, []int64{20}) {
+		t.Errorf("expected update 20 to be skipped, got %v", skipped)
+	}
+	if offset := readOffsetFile(t, opts.OffsetFile); offset != "22" {
+		t.Errorf("expected offset 22 to be persisted, got %s", offset)
+	}
+}
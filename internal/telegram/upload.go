@@ -0,0 +1,190 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/en9inerd/go-pkgs/validator"
+)
+
+// InputFile is a file to send: the file_id of a file already on the Telegram servers,
+// an HTTP URL for Telegram to download, or contents to upload with multipart/form-data.
+// Create it with InputFileID, InputFileURL or InputFileUpload.
+type InputFile struct {
+	ref    string    // file_id or URL
+	name   string    // File name of an upload
+	reader io.Reader // Contents of an upload
+	attach string    // Name of the upload part in the form
+}
+
+// InputFileID refers to a file already stored on the Telegram servers
+func InputFileID(fileID string) InputFile {
+	return InputFile{ref: fileID}
+}
+
+// InputFileURL lets Telegram download the file from an HTTP URL
+func InputFileURL(url string) InputFile {
+	return InputFile{ref: url}
+}
+
+// InputFileUpload uploads the contents of r as a file with the given name.
+// The reader is consumed by the first request that sends it.
+func InputFileUpload(name string, r io.Reader) InputFile {
+	return InputFile{name: name, reader: r}
+}
+
+// IsZero reports whether no file is set
+func (f InputFile) IsZero() bool {
+	return f.ref == "" && f.reader == nil
+}
+
+// IsUpload reports whether the file is uploaded with the request
+func (f InputFile) IsUpload() bool {
+	return f.reader != nil
+}
+
+// MarshalJSON encodes the file_id or URL, or the attach:// reference of an upload
+func (f InputFile) MarshalJSON() ([]byte, error) {
+	if f.reader != nil {
+		return json.Marshal("attach://" + f.attach)
+	}
+	return json.Marshal(f.ref)
+}
+
+// validate checks that a file is set
+func (f InputFile) validate(v *validator.Validator, field string) {
+	v.CheckField(!f.IsZero(), field, field+" is required")
+}
+
+// uploader is implemented by requests that may carry files to upload
+type uploader interface {
+	inputFiles() []*InputFile
+}
+
+// multipartForm is a request body encoded as multipart/form-data
+type multipartForm struct {
+	body        []byte
+	contentType string
+}
+
+// newMultipartForm encodes payload as a form when any of files is an upload, and returns nil otherwise.
+// Uploads in top-level fields are sent as those fields; uploads inside InputMedia are attached
+// under their own names and referenced with attach://<name>. The whole body is buffered so that
+// the request can be retried.
+func newMultipartForm(payload any, files []*InputFile) (*multipartForm, error) {
+	var uploads []*InputFile
+	for _, f := range files {
+		if f.IsUpload() {
+			f.attach = fmt.Sprintf("file%d", len(uploads))
+			uploads = append(uploads, f)
+		}
+	}
+	if len(uploads) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	written := make(map[*InputFile]bool, len(uploads))
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		raw := fields[key]
+		if string(raw) == "null" {
+			continue
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			// Numbers, booleans, objects and arrays are sent as JSON
+			value = string(raw)
+		} else if name, ok := strings.CutPrefix(value, "attach://"); ok {
+			i := slices.IndexFunc(uploads, func(f *InputFile) bool { return f.attach == name })
+			if i >= 0 {
+				if err := writeFormFile(mw, key, uploads[i]); err != nil {
+					return nil, err
+				}
+				written[uploads[i]] = true
+				continue
+			}
+		}
+
+		if err := mw.WriteField(key, value); err != nil {
+			return nil, fmt.Errorf("failed to write form field %s: %w", key, err)
+		}
+	}
+
+	for _, f := range uploads {
+		if !written[f] {
+			if err := writeFormFile(mw, f.attach, f); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish form: %w", err)
+	}
+
+	return &multipartForm{body: buf.Bytes(), contentType: mw.FormDataContentType()}, nil
+}
+
+func writeFormFile(mw *multipart.Writer, field string, f *InputFile) error {
+	name := f.name
+	if name == "" {
+		name = field
+	}
+	part, err := mw.CreateFormFile(field, name)
+	if err != nil {
+		return fmt.Errorf("failed to write form file %s: %w", field, err)
+	}
+	if _, err := io.Copy(part, f.reader); err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return nil
+}
+
+// postForm posts a multipart form to a Bot API method and decodes the response into apiResp
+func (c *Client) postForm(ctx context.Context, method string, form *multipartForm, apiResp *APIResponse) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL+method, bytes.NewReader(form.body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.contentType)
+
+	resp, err := c.fileClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The Bot API describes failed requests in the body
+	if err := json.NewDecoder(resp.Body).Decode(apiResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return &statusError{code: resp.StatusCode}
+		}
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
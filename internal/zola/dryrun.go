@@ -0,0 +1,320 @@
+package zola
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-billy/v6/util"
+)
+
+// Actions of a FileChange
+const (
+	ChangeAdd    = "add"
+	ChangeModify = "modify"
+	ChangeDelete = "delete"
+)
+
+// FileChange is a file of the site repository that a dry run would have written or removed
+type FileChange struct {
+	Path        string `json:"path"` // Relative to the repository root
+	Action      string `json:"action"`
+	Size        int    `json:"size"`                   // Size of the new contents in bytes
+	FrontMatter string `json:"front_matter,omitempty"` // Front matter of a post file
+	Content     string `json:"content,omitempty"`      // Processed Markdown of a post file
+	Previous    string `json:"previous,omitempty"`     // Previous contents of a modified post file
+}
+
+// DryRun returns a copy of the service that keeps every write in memory and leaves
+// the posts directory, the repository and the index untouched. Changes reports what it wrote.
+func (s *Service) DryRun() *Service {
+	dry := *s
+	dry.index = s.index.clone()
+	dry.dryRun = newOverlayFS(s.fs)
+	dry.fs = dry.dryRun
+	return &dry
+}
+
+// Changes returns the files written or removed by a dry run compared to the service's filesystem,
+// ordered by path. It returns nil for a service that is not a dry run.
+func (s *Service) Changes() []FileChange {
+	if s.dryRun == nil {
+		return nil
+	}
+	return s.dryRun.changes()
+}
+
+// stage adds a file to the git index, unless the service is a dry run or has no git service
+func (s *Service) stage(relPath string) error {
+	if s.dryRun != nil || s.gitService == nil {
+		return nil
+	}
+	return s.gitService.Add(relPath)
+}
+
+// unstage removes a file from the git index, unless the service is a dry run or has no git service
+func (s *Service) unstage(relPath string) error {
+	if s.dryRun != nil || s.gitService == nil {
+		return nil
+	}
+	return s.gitService.Remove(relPath)
+}
+
+// overlayFS keeps writes in memory on top of a read-only base filesystem, for dry runs
+type overlayFS struct {
+	base    FS
+	mem     billy.Filesystem
+	written map[string]bool // Files written to mem
+	removed map[string]bool // Files and directories of base hidden by a removal
+}
+
+func newOverlayFS(base FS) *overlayFS {
+	return &overlayFS{
+		base:    base,
+		mem:     memfs.New(),
+		written: make(map[string]bool),
+		removed: make(map[string]bool),
+	}
+}
+
+func (o *overlayFS) Create(filename string) (billy.File, error) {
+	return o.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (o *overlayFS) Open(filename string) (billy.File, error) {
+	return o.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (o *overlayFS) OpenFile(filename string, flag int, perm fs.FileMode) (billy.File, error) {
+	filename = filepath.Clean(filename)
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		if o.written[filename] {
+			return o.mem.Open(filename)
+		}
+		if o.isRemoved(filename) {
+			return nil, notExist("open", filename)
+		}
+		return o.base.Open(filename)
+	}
+
+	// Copy the file on its first write so that it can be modified in place
+	if !o.written[filename] && flag&os.O_TRUNC == 0 && !o.isRemoved(filename) {
+		data, err := util.ReadFile(o.base, filename)
+		if err == nil {
+			if err := util.WriteFile(o.mem, filename, data, perm); err != nil {
+				return nil, err
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	if err := o.mem.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, err
+	}
+	f, err := o.mem.OpenFile(filename, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	o.written[filename] = true
+	o.unremove(filename)
+	return f, nil
+}
+
+func (o *overlayFS) Stat(filename string) (fs.FileInfo, error) {
+	filename = filepath.Clean(filename)
+	if o.written[filename] {
+		return o.mem.Stat(filename)
+	}
+	if !o.isRemoved(filename) {
+		if info, err := o.base.Stat(filename); err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return info, err
+		}
+	}
+	// Directories created by writes
+	return o.mem.Stat(filename)
+}
+
+func (o *overlayFS) Rename(oldpath, newpath string) error {
+	data, err := util.ReadFile(o, oldpath)
+	if err != nil {
+		return err
+	}
+	if err := util.WriteFile(o, newpath, data, 0644); err != nil {
+		return err
+	}
+	return o.Remove(oldpath)
+}
+
+func (o *overlayFS) Remove(filename string) error {
+	filename = filepath.Clean(filename)
+	info, err := o.Stat(filename)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := o.ReadDir(filename)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return &fs.PathError{Op: "remove", Path: filename, Err: syscall.ENOTEMPTY}
+		}
+	}
+
+	if o.written[filename] {
+		delete(o.written, filename)
+		if err := o.mem.Remove(filename); err != nil {
+			return err
+		}
+	}
+	if _, err := o.base.Stat(filename); err == nil {
+		o.removed[filename] = true
+	}
+	return nil
+}
+
+func (o *overlayFS) Join(elem ...string) string {
+	return filepath.Join(elem...)
+}
+
+func (o *overlayFS) ReadDir(path string) ([]fs.DirEntry, error) {
+	path = filepath.Clean(path)
+
+	var entries []fs.DirEntry
+	found := false
+	if !o.isRemoved(path) {
+		baseEntries, err := o.base.ReadDir(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		found = err == nil
+		for _, entry := range baseEntries {
+			name := filepath.Join(path, entry.Name())
+			if !o.removed[name] && !o.written[name] {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	memEntries, err := o.mem.ReadDir(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	found = found || err == nil
+	for _, entry := range memEntries {
+		exists := slices.ContainsFunc(entries, func(e fs.DirEntry) bool { return e.Name() == entry.Name() })
+		if !exists {
+			entries = append(entries, entry)
+		}
+	}
+
+	if !found {
+		return nil, notExist("readdir", path)
+	}
+
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+
+func (o *overlayFS) MkdirAll(filename string, perm fs.FileMode) error {
+	filename = filepath.Clean(filename)
+	o.unremove(filename)
+	return o.mem.MkdirAll(filename, perm)
+}
+
+// isRemoved reports whether a path or one of its parent directories was removed
+func (o *overlayFS) isRemoved(filename string) bool {
+	for p := filename; p != "." && p != string(filepath.Separator); p = filepath.Dir(p) {
+		if o.removed[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// unremove restores the parent directories of a path written after a removal
+func (o *overlayFS) unremove(filename string) {
+	for p := filename; p != "." && p != string(filepath.Separator); p = filepath.Dir(p) {
+		delete(o.removed, p)
+	}
+}
+
+// changes compares the files written and removed with the base filesystem, ordered by path
+func (o *overlayFS) changes() []FileChange {
+	paths := make([]string, 0, len(o.written)+len(o.removed))
+	for path := range o.written {
+		paths = append(paths, path)
+	}
+	for path := range o.removed {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	changes := []FileChange{}
+	for _, path := range paths {
+		if info, err := o.base.Stat(path); err == nil && info.IsDir() {
+			continue
+		}
+		previous, err := util.ReadFile(o.base, path)
+		exists := err == nil
+
+		var data []byte
+		if o.written[path] {
+			if data, err = util.ReadFile(o.mem, path); err != nil {
+				continue
+			}
+		}
+
+		change := FileChange{Path: filepath.ToSlash(path), Size: len(data)}
+		switch {
+		case o.written[path] && !exists:
+			change.Action = ChangeAdd
+		case o.written[path] && !bytes.Equal(data, previous):
+			change.Action = ChangeModify
+			if isPostFile(path) {
+				change.Previous = string(previous)
+			}
+		case !o.written[path] && exists:
+			change.Action = ChangeDelete
+		default:
+			continue
+		}
+
+		if o.written[path] && isPostFile(path) {
+			change.FrontMatter, change.Content = splitFrontMatter(string(data))
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+func notExist(op, path string) error {
+	return &fs.PathError{Op: op, Path: path, Err: fs.ErrNotExist}
+}
+
+func isPostFile(path string) bool {
+	return strings.HasSuffix(path, ".md")
+}
+
+// splitFrontMatter separates the TOML front matter of a post from its Markdown
+func splitFrontMatter(content string) (string, string) {
+	rest, ok := strings.CutPrefix(content, "+++\n")
+	if !ok {
+		return "", content
+	}
+	end := strings.Index(rest, "\n+++\n")
+	if end == -1 {
+		return "", content
+	}
+	end += len("+++\n") + len("\n+++\n")
+	return content[:end], strings.TrimPrefix(content[end:], "\n")
+}
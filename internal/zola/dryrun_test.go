@@ -0,0 +1,191 @@
+package zola
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/en9inerd/postpal/internal/telegram"
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-billy/v6/util"
+)
+
+func TestService_DryRun_CreatePost(t *testing.T) {
+	service, tempDir := setupTestService(t)
+	ctx := context.Background()
+
+	dry := service.DryRun()
+	post := Post{
+		ID:         500,
+		ChatID:     -100123,
+		Content:    "Hello world",
+		Entities:   []telegram.MessageEntity{{Type: telegram.EntityCode, Offset: 6, Length: 5}},
+		Date:       time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		MessageIDs: []int64{500},
+	}
+	if err := dry.CreatePost(ctx, post, [][]byte{createPNGBytes()}); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "content", "posts", "500")); !os.IsNotExist(err) {
+		t.Error("expected dry run not to write the post directory")
+	}
+	if _, ok := service.PostID(-100123, 500); ok {
+		t.Error("expected dry run not to index the post")
+	}
+
+	changes := dry.Changes()
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+
+	postFile := changes[1]
+	if postFile.Path != "content/posts/500/index.md" || postFile.Action != ChangeAdd {
+		t.Errorf("unexpected post file change: %+v", postFile)
+	}
+	if !contains(postFile.FrontMatter, `images = ["image_0.png"]`) || !contains(postFile.FrontMatter, "telegram_message_ids = [500]") {
+		t.Errorf("expected front matter with image and message references, got: %s", postFile.FrontMatter)
+	}
+	if postFile.Content != "Hello `world`\n" {
+		t.Errorf("expected processed content without front matter, got: %q", postFile.Content)
+	}
+
+	image := changes[0]
+	if image.Path != "content/posts/500/image_0.png" || image.Action != ChangeAdd || image.Size != len(createPNGBytes()) {
+		t.Errorf("unexpected image change: %+v", image)
+	}
+	if image.Content != "" {
+		t.Errorf("expected no content for images, got: %q", image.Content)
+	}
+}
+
+func TestService_DryRun_EditPost(t *testing.T) {
+	service, tempDir := setupTestService(t)
+	ctx := context.Background()
+
+	post := Post{ID: 510, ChatID: -100123, Content: "Original", Date: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)}
+	if err := service.CreatePost(ctx, post, nil); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+
+	dry := service.DryRun()
+	edit := Post{ID: 510, ChatID: -100123, Content: "Edited", Date: post.Date}
+	if err := dry.EditPost(ctx, edit, nil); err != nil {
+		t.Fatalf("EditPost failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "content", "posts", "510.md"))
+	if err != nil {
+		t.Fatalf("failed to read post file: %v", err)
+	}
+	if contains(string(content), "Edited") {
+		t.Error("expected dry run not to modify the post file")
+	}
+
+	changes := dry.Changes()
+	if len(changes) != 1 || changes[0].Action != ChangeModify {
+		t.Fatalf("expected a single modified file, got %+v", changes)
+	}
+	if changes[0].Previous != string(content) || changes[0].Content != "Edited\n" {
+		t.Errorf("expected previous and new content, got %+v", changes[0])
+	}
+}
+
+func TestService_DryRun_DeletePost(t *testing.T) {
+	service, tempDir := setupTestService(t)
+	ctx := context.Background()
+
+	post := Post{ID: 520, ChatID: -100123, Content: "Album", Date: time.Now(), MessageIDs: []int64{520, 521}}
+	if err := service.CreatePost(ctx, post, [][]byte{createJPEGBytes(), createJPEGBytes()}); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+
+	dry := service.DryRun()
+	if err := dry.DeletePost(ctx, -100123, "521"); err != nil {
+		t.Fatalf("DeletePost failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "content", "posts", "520", "index.md")); err != nil {
+		t.Errorf("expected dry run not to remove the post: %v", err)
+	}
+	if _, ok := service.PostID(-100123, 520); !ok {
+		t.Error("expected dry run not to change the index")
+	}
+
+	changes := dry.Changes()
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 removed files, got %+v", changes)
+	}
+	for _, change := range changes {
+		if change.Action != ChangeDelete {
+			t.Errorf("expected %s to be deleted, got %s", change.Path, change.Action)
+		}
+	}
+}
+
+func TestService_Changes_NotDryRun(t *testing.T) {
+	service, _ := setupTestService(t)
+
+	if changes := service.Changes(); changes != nil {
+		t.Errorf("expected no changes outside of a dry run, got %+v", changes)
+	}
+}
+
+func TestOverlayFS(t *testing.T) {
+	base := memfs.New()
+	for path, data := range map[string]string{"posts/1.md": "one", "posts/2/index.md": "two", "posts/2/image_0.jpg": "jpg"} {
+		if err := util.WriteFile(base, path, []byte(data), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	overlay := newOverlayFS(base)
+	if err := util.WriteFile(overlay, "posts/1.md", []byte("ONE"), 0644); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if err := util.WriteFile(overlay, "posts/3.md", []byte("three"), 0644); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if err := util.RemoveAll(overlay, "posts/2"); err != nil {
+		t.Fatalf("failed to remove: %v", err)
+	}
+
+	if data, _ := util.ReadFile(base, "posts/1.md"); string(data) != "one" {
+		t.Errorf("expected base to be unchanged, got %q", data)
+	}
+	if _, err := base.Stat("posts/2/index.md"); err != nil {
+		t.Errorf("expected base file to be kept: %v", err)
+	}
+	if data, _ := util.ReadFile(overlay, "posts/1.md"); string(data) != "ONE" {
+		t.Errorf("expected overlay to read its own write, got %q", data)
+	}
+	if _, err := overlay.Stat("posts/2/index.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected removed file to be hidden, got %v", err)
+	}
+
+	entries, err := overlay.ReadDir("posts")
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !slices.Equal(names, []string{"1.md", "3.md"}) {
+		t.Errorf("expected merged directory listing, got %v", names)
+	}
+
+	var actions []string
+	for _, change := range overlay.changes() {
+		actions = append(actions, change.Action+" "+change.Path)
+	}
+	expected := []string{"modify posts/1.md", "delete posts/2/image_0.jpg", "delete posts/2/index.md", "add posts/3.md"}
+	if !slices.Equal(actions, expected) {
+		t.Errorf("expected changes %v, got %v", expected, actions)
+	}
+}
@@ -0,0 +1,162 @@
+package zola
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/en9inerd/postpal/internal/telegram"
+	"golang.org/x/net/html"
+)
+
+// EntitiesToMarkdown converts the text of a Telegram message and its entities to CommonMark.
+// Entity offsets are in UTF-16 code units. Entities that partially overlap are split so that
+// they nest, and code blocks and blockquotes are never nested in inline formatting.
+// Formatting is rendered like the HTML of HTMLToMarkdown; links to users and chats point to
+// Telegram, and hashtags, cashtags and custom emoji are kept as plain text.
+func EntitiesToMarkdown(text string, entities []telegram.MessageEntity) string {
+	r := &markdownRenderer{}
+	r.renderChildren(buildEntityTree(text, entities))
+	return strings.TrimRight(r.sb.String(), " \n")
+}
+
+// entitySpan is an entity with its bounds in UTF-16 code units
+type entitySpan struct {
+	node       *htmlNode // Element the entity becomes, without children
+	start, end int
+	block      bool
+	index      int // Position in the message, to keep the order of entities with the same bounds
+}
+
+// buildEntityTree builds the tree rendered by markdownRenderer from a text and its entities.
+// The text is cut at every entity boundary and each piece is placed under the elements of the
+// entities covering it, reusing the elements of the previous piece where possible.
+func buildEntityTree(text string, entities []telegram.MessageEntity) *htmlNode {
+	units := utf16.Encode([]rune(text))
+
+	var spans []entitySpan
+	boundaries := []int{0, len(units)}
+	for i, entity := range entities {
+		start := codePointStart(units, entity.Offset)
+		end := codePointStart(units, entity.Offset+entity.Length)
+		if start >= end {
+			continue
+		}
+		node := entityNode(entity, string(utf16.Decode(units[start:end])))
+		if node == nil {
+			continue
+		}
+		block := blockTags[node.tag]
+		spans = append(spans, entitySpan{node: node, start: start, end: end, block: block, index: i})
+		boundaries = append(boundaries, start, end)
+	}
+	slices.Sort(boundaries)
+	boundaries = slices.Compact(boundaries)
+
+	// Outer elements first: blocks, then the entity that starts first and ends last
+	slices.SortFunc(spans, func(a, b entitySpan) int {
+		if a.block != b.block {
+			if a.block {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(a.start, b.start), cmp.Compare(b.end, a.end), cmp.Compare(a.index, b.index))
+	})
+
+	root := &htmlNode{tag: "#root"}
+	var path []*entitySpan // Entities of the elements the previous piece was placed in
+	var nodes []*htmlNode  // Elements of path
+	for i := 0; i+1 < len(boundaries); i++ {
+		start, end := boundaries[i], boundaries[i+1]
+
+		var active []*entitySpan
+		for j := range spans {
+			if spans[j].start <= start && spans[j].end >= end {
+				active = append(active, &spans[j])
+			}
+		}
+
+		shared := 0
+		for shared < len(path) && shared < len(active) && path[shared] == active[shared] {
+			shared++
+		}
+		path, nodes = path[:shared], nodes[:shared]
+
+		for _, span := range active[shared:] {
+			parent := root
+			if len(nodes) > 0 {
+				parent = nodes[len(nodes)-1]
+			}
+			node := &htmlNode{tag: span.node.tag, attrs: span.node.attrs}
+			parent.children = append(parent.children, node)
+			path, nodes = append(path, span), append(nodes, node)
+		}
+
+		parent := root
+		if len(nodes) > 0 {
+			parent = nodes[len(nodes)-1]
+		}
+		parent.children = append(parent.children, &htmlNode{text: string(utf16.Decode(units[start:end]))})
+	}
+
+	return root
+}
+
+// entityNode returns the element an entity is rendered as, or nil for entities kept as plain text
+func entityNode(entity telegram.MessageEntity, text string) *htmlNode {
+	link := func(href string) *htmlNode {
+		return &htmlNode{tag: "a", attrs: []html.Attribute{{Key: "href", Val: href}}}
+	}
+
+	switch entity.Type {
+	case telegram.EntityBold:
+		return &htmlNode{tag: "b"}
+	case telegram.EntityItalic:
+		return &htmlNode{tag: "i"}
+	case telegram.EntityUnderline:
+		return &htmlNode{tag: "u"}
+	case telegram.EntityStrikethrough:
+		return &htmlNode{tag: "s"}
+	case telegram.EntitySpoiler:
+		return &htmlNode{tag: "tg-spoiler"}
+	case telegram.EntityCode:
+		return &htmlNode{tag: "code"}
+	case telegram.EntityPre:
+		return &htmlNode{tag: "pre", attrs: []html.Attribute{{Key: "language", Val: entity.Language}}}
+	case telegram.EntityBlockquote, telegram.EntityExpandable:
+		return &htmlNode{tag: "blockquote"}
+	case telegram.EntityTextLink:
+		return link(entity.URL)
+	case telegram.EntityTextMention:
+		if entity.User == nil {
+			return nil
+		}
+		if entity.User.Username != "" {
+			return link("https://t.me/" + entity.User.Username)
+		}
+		return link("tg://user?id=" + strconv.FormatInt(entity.User.ID, 10))
+	case telegram.EntityMention:
+		return link("https://t.me/" + strings.TrimPrefix(text, "@"))
+	case telegram.EntityURL:
+		if !strings.Contains(text, "://") {
+			text = "https://" + text
+		}
+		return link(text)
+	case telegram.EntityEmail:
+		return link("mailto:" + text)
+	}
+	return nil
+}
+
+// codePointStart clamps an offset to the text and moves it off the second half of a surrogate pair
+func codePointStart(units []uint16, offset int) int {
+	offset = min(max(offset, 0), len(units))
+	if offset > 0 && offset < len(units) && utf16.IsSurrogate(rune(units[offset])) &&
+		units[offset] >= 0xdc00 && units[offset-1] < 0xdc00 && utf16.IsSurrogate(rune(units[offset-1])) {
+		offset--
+	}
+	return offset
+}
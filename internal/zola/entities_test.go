@@ -0,0 +1,79 @@
+package zola
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/en9inerd/postpal/internal/telegram"
+)
+
+// TestEntitiesToMarkdown_Golden converts every testdata/entities/*.json message and compares it with the .md file next to it
+func TestEntitiesToMarkdown_Golden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "entities", "*.json"))
+	if err != nil {
+		t.Fatalf("failed to list golden files: %v", err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no golden files found")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", input, err)
+			}
+			var msg telegram.Message
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatalf("failed to parse %s: %v", input, err)
+			}
+			result := EntitiesToMarkdown(msg.Text, msg.Entities) + "\n"
+
+			golden := strings.TrimSuffix(input, ".json") + ".md"
+			if *update {
+				if err := os.WriteFile(golden, []byte(result), 0644); err != nil {
+					t.Fatalf("failed to update %s: %v", golden, err)
+				}
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", golden, err)
+			}
+			if result != string(expected) {
+				t.Errorf("Markdown mismatch for %s\nexpected:\n%s\ngot:\n%s", input, expected, result)
+			}
+		})
+	}
+}
+
+func TestEntitiesToMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		entities []telegram.MessageEntity
+		expected string
+	}{
+		{"no entities", "Plain <b>text</b>", nil, "Plain &lt;b&gt;text&lt;/b&gt;"},
+		{"offsets after emoji", "🚀 go", []telegram.MessageEntity{{Type: telegram.EntityBold, Offset: 3, Length: 2}}, "🚀 **go**"},
+		{"adjacent emphasis", "ab", []telegram.MessageEntity{
+			{Type: telegram.EntityBold, Offset: 0, Length: 1},
+			{Type: telegram.EntityItalic, Offset: 1, Length: 1},
+		}, "**a**_b_"},
+		{"entity past the end", "abc", []telegram.MessageEntity{{Type: telegram.EntityItalic, Offset: 1, Length: 10}}, "a*bc*"},
+		{"empty entity", "abc", []telegram.MessageEntity{{Type: telegram.EntityBold, Offset: 1, Length: 0}}, "abc"},
+		{"pre without language", "x = 1", []telegram.MessageEntity{{Type: telegram.EntityPre, Offset: 0, Length: 5}}, "```\nx = 1\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := EntitiesToMarkdown(tt.text, tt.entities); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
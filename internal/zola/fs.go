@@ -0,0 +1,13 @@
+package zola
+
+import (
+	"github.com/go-git/go-billy/v6"
+)
+
+// FS is the filesystem posts and images are read from and written to, rooted at the repository.
+// Its methods are a subset of go-billy's billy.Filesystem, so osfs, memfs and the worktree
+// filesystem of a go-git repository can be used as is.
+type FS interface {
+	billy.Basic
+	billy.Dir
+}
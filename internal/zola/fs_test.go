@@ -0,0 +1,61 @@
+package zola
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-billy/v6/util"
+	gogit "github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func TestService_WithFS_MemoryWorktree(t *testing.T) {
+	repo, err := gogit.Init(memory.NewStorage(), gogit.WithWorkTree(memfs.New()))
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+
+	service := NewService("/site/content/posts", "content/posts", "/site", "@testchannel", nil, "").WithFS(worktree.Filesystem)
+	ctx := context.Background()
+
+	post := Post{ID: 600, ChatID: -100123, Content: "In memory", Date: time.Now(), MessageIDs: []int64{600, 601}}
+	if err := service.CreatePost(ctx, post, [][]byte{createJPEGBytes(), createPNGBytes()}); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if err := service.EditPost(ctx, Post{ID: 601, ChatID: -100123, Date: time.Now()}, createJPEGBytes()); err != nil {
+		t.Fatalf("EditPost failed: %v", err)
+	}
+
+	content, err := util.ReadFile(worktree.Filesystem, "content/posts/600/index.md")
+	if err != nil {
+		t.Fatalf("failed to read post file: %v", err)
+	}
+	if !contains(string(content), `images = ["image_0.jpg", "image_1.jpg"]`) {
+		t.Errorf("expected edited image in front matter, got: %s", content)
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	var files []string
+	for path := range status {
+		files = append(files, path)
+	}
+	slices.Sort(files)
+	expected := []string{"content/posts/600/image_0.jpg", "content/posts/600/image_1.jpg", "content/posts/600/index.md"}
+	if !slices.Equal(files, expected) {
+		t.Errorf("expected worktree files %v, got %v", expected, files)
+	}
+
+	if count, err := service.RebuildIndex(); err != nil || count != 1 {
+		t.Errorf("expected the post to be indexed from the worktree, got %d (%v)", count, err)
+	}
+}
@@ -0,0 +1,287 @@
+package zola
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrPostNotFound is returned when a Telegram message is not mapped to any post
+var ErrPostNotFound = errors.New("post not found")
+
+// IndexEntry locates the post a Telegram message was published as
+type IndexEntry struct {
+	PostID int64 `json:"post_id"`
+	Slot   int   `json:"slot"` // Image slot of the message within the post (image_<slot>.<ext>)
+}
+
+// Index is a durable mapping from Telegram messages and media groups to posts.
+// Messages are keyed by "chat_id:message_id"; chat ID 0 matches any chat and is used
+// for posts that were published before their chat was recorded.
+// Changes stay in memory until Save, so that Revert can drop the changes of posts
+// that were never committed.
+type Index struct {
+	path string
+	mu   sync.RWMutex
+
+	Messages    map[string]IndexEntry `json:"messages"`
+	MediaGroups map[string]int64      `json:"media_groups"`
+
+	// State as of the last Save or load
+	savedMessages    map[string]IndexEntry
+	savedMediaGroups map[string]int64
+}
+
+// NewIndex creates an empty index. An empty path keeps the index in memory only.
+func NewIndex(path string) *Index {
+	return &Index{
+		path:             path,
+		Messages:         make(map[string]IndexEntry),
+		MediaGroups:      make(map[string]int64),
+		savedMessages:    make(map[string]IndexEntry),
+		savedMediaGroups: make(map[string]int64),
+	}
+}
+
+// LoadIndex reads the index stored at path, returning an empty index if the file does not exist
+func LoadIndex(path string) (*Index, error) {
+	index := NewIndex(path)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read post index: %w", err)
+	}
+
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse post index %s: %w", path, err)
+	}
+	if index.Messages == nil {
+		index.Messages = make(map[string]IndexEntry)
+	}
+	if index.MediaGroups == nil {
+		index.MediaGroups = make(map[string]int64)
+	}
+	index.savedMessages = maps.Clone(index.Messages)
+	index.savedMediaGroups = maps.Clone(index.MediaGroups)
+
+	return index, nil
+}
+
+// Save writes the index to disk atomically. Revert returns to the saved state.
+func (i *Index) Save() error {
+	i.mu.Lock()
+	i.savedMessages = maps.Clone(i.Messages)
+	i.savedMediaGroups = maps.Clone(i.MediaGroups)
+	i.mu.Unlock()
+
+	if i.path == "" {
+		return nil
+	}
+
+	i.mu.RLock()
+	data, err := json.MarshalIndent(i, "", "  ")
+	i.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode post index: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(i.path), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	tmpPath := i.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write post index: %w", err)
+	}
+
+	return os.Rename(tmpPath, i.path)
+}
+
+// Lookup finds the post a message belongs to
+func (i *Index) Lookup(chatID, messageID int64) (IndexEntry, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if entry, ok := i.Messages[messageKey(chatID, messageID)]; ok {
+		return entry, true
+	}
+	entry, ok := i.Messages[messageKey(0, messageID)]
+	return entry, ok
+}
+
+// LookupMediaGroup finds the post an album was published as
+func (i *Index) LookupMediaGroup(mediaGroupID string) (int64, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	postID, ok := i.MediaGroups[mediaGroupID]
+	return postID, ok
+}
+
+// Put records that a message is published in the given post and image slot
+func (i *Index) Put(chatID, messageID int64, entry IndexEntry) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.Messages[messageKey(chatID, messageID)] = entry
+}
+
+// PutMediaGroup records that an album is published as the given post
+func (i *Index) PutMediaGroup(mediaGroupID string, postID int64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.MediaGroups[mediaGroupID] = postID
+}
+
+// RemovePost drops every message and media group that maps to the post
+func (i *Index) RemovePost(postID int64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for key, entry := range i.Messages {
+		if entry.PostID == postID {
+			delete(i.Messages, key)
+		}
+	}
+	for id, pid := range i.MediaGroups {
+		if pid == postID {
+			delete(i.MediaGroups, id)
+		}
+	}
+}
+
+// postMessageIDs returns the message IDs of a post ordered by image slot
+func (i *Index) postMessageIDs(postID int64) []int64 {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	type slotted struct {
+		messageID int64
+		slot      int
+	}
+	var found []slotted
+	for key, entry := range i.Messages {
+		if entry.PostID != postID {
+			continue
+		}
+		_, idStr, _ := strings.Cut(key, ":")
+		if id, err := strconv.ParseInt(idStr, 10, 64); err == nil {
+			found = append(found, slotted{messageID: id, slot: entry.Slot})
+		}
+	}
+
+	slices.SortFunc(found, func(a, b slotted) int { return a.slot - b.slot })
+	ids := make([]int64, 0, len(found))
+	for _, f := range found {
+		if !slices.Contains(ids, f.messageID) {
+			ids = append(ids, f.messageID)
+		}
+	}
+	return ids
+}
+
+// postMediaGroupID returns the media group a post was created from, if any
+func (i *Index) postMediaGroupID(postID int64) string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for id, pid := range i.MediaGroups {
+		if pid == postID {
+			return id
+		}
+	}
+	return ""
+}
+
+// clone returns an in-memory copy of the index
+func (i *Index) clone() *Index {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	c := NewIndex("")
+	maps.Copy(c.Messages, i.Messages)
+	maps.Copy(c.MediaGroups, i.MediaGroups)
+	maps.Copy(c.savedMessages, i.savedMessages)
+	maps.Copy(c.savedMediaGroups, i.savedMediaGroups)
+	return c
+}
+
+// Revert drops the changes made since the index was last saved or loaded
+func (i *Index) Revert() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.Messages = maps.Clone(i.savedMessages)
+	i.MediaGroups = maps.Clone(i.savedMediaGroups)
+}
+
+func (i *Index) reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.Messages = make(map[string]IndexEntry)
+	i.MediaGroups = make(map[string]int64)
+}
+
+func messageKey(chatID, messageID int64) string {
+	return strconv.FormatInt(chatID, 10) + ":" + strconv.FormatInt(messageID, 10)
+}
+
+var (
+	chatIDRegex       = regexp.MustCompile(`(?m)^telegram_chat_id\s*=\s*(-?\d+)\s*$`)
+	messageIDsRegex   = regexp.MustCompile(`(?m)^telegram_message_ids\s*=\s*\[([^\]]*)\]\s*$`)
+	mediaGroupIDRegex = regexp.MustCompile(`(?m)^telegram_media_group_id\s*=\s*"([^"]*)"\s*$`)
+	imagesRegex       = regexp.MustCompile(`(?m)^images\s*=\s*\[([^\]]*)\]\s*$`)
+)
+
+// frontMatterRefs holds the Telegram references stored in a post's front matter
+type frontMatterRefs struct {
+	chatID       int64
+	messageIDs   []int64
+	mediaGroupID string
+	imageCount   int
+}
+
+func parseFrontMatterRefs(content string) frontMatterRefs {
+	var refs frontMatterRefs
+
+	rest, ok := strings.CutPrefix(content, "+++\n")
+	if !ok {
+		return refs
+	}
+	frontMatter, _, ok := strings.Cut(rest, "+++")
+	if !ok {
+		return refs
+	}
+
+	if m := chatIDRegex.FindStringSubmatch(frontMatter); m != nil {
+		refs.chatID, _ = strconv.ParseInt(m[1], 10, 64)
+	}
+	if m := messageIDsRegex.FindStringSubmatch(frontMatter); m != nil {
+		for idStr := range strings.SplitSeq(m[1], ",") {
+			if id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64); err == nil {
+				refs.messageIDs = append(refs.messageIDs, id)
+			}
+		}
+	}
+	if m := mediaGroupIDRegex.FindStringSubmatch(frontMatter); m != nil {
+		refs.mediaGroupID = m[1]
+	}
+	if m := imagesRegex.FindStringSubmatch(frontMatter); m != nil && strings.TrimSpace(m[1]) != "" {
+		refs.imageCount = len(strings.Split(m[1], ","))
+	}
+
+	return refs
+}
@@ -0,0 +1,340 @@
+package zola
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlNode is an element or a text of the Telegram HTML tree
+type htmlNode struct {
+	tag      string // Lowercase tag name, empty for text
+	attrs    []html.Attribute
+	text     string // Unescaped text of a text node
+	children []*htmlNode
+}
+
+func (n *htmlNode) attr(key string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+var (
+	// voidTags are elements without content or end tag
+	voidTags = map[string]bool{"br": true, "img": true, "hr": true}
+	// blockTags are elements that can't be nested in inline formatting
+	blockTags = map[string]bool{"pre": true, "blockquote": true}
+)
+
+// parseTelegramHTML builds a tree from Telegram HTML. It is lenient: unclosed elements are
+// closed at the end of their parent, a code block or blockquote closes the inline formatting
+// it is in, end tags without a matching start tag are ignored, and comments are dropped.
+func parseTelegramHTML(content string) *htmlNode {
+	root := &htmlNode{tag: "#root"}
+	stack := []*htmlNode{root}
+
+	z := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		token := z.Token()
+		if blockTags[token.Data] && tt == html.StartTagToken {
+			for len(stack) > 1 && !blockTags[stack[len(stack)-1].tag] {
+				stack = stack[:len(stack)-1]
+			}
+		}
+
+		parent := stack[len(stack)-1]
+		switch tt {
+		case html.TextToken:
+			parent.children = append(parent.children, &htmlNode{text: token.Data})
+		case html.StartTagToken, html.SelfClosingTagToken:
+			node := &htmlNode{tag: token.Data, attrs: token.Attr}
+			parent.children = append(parent.children, node)
+			if tt == html.StartTagToken && !voidTags[token.Data] {
+				stack = append(stack, node)
+			}
+		case html.EndTagToken:
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == token.Data {
+					stack = stack[:i]
+					break
+				}
+			}
+		}
+	}
+
+	return root
+}
+
+// HTMLToMarkdown converts the HTML of a Telegram message to CommonMark.
+// Bold, italic, strikethrough, links, inline code, code blocks and blockquotes become Markdown,
+// expandable blockquotes become regular blockquotes, and underline and spoilers are kept as
+// inline HTML. Newlines are hard line breaks and blank lines separate paragraphs, as in Telegram.
+// Unknown tags are dropped, keeping their content.
+func HTMLToMarkdown(content string) string {
+	r := &markdownRenderer{}
+	r.renderChildren(parseTelegramHTML(content))
+	return strings.TrimRight(r.sb.String(), " \n")
+}
+
+// markdownRenderer writes the Markdown of an HTML tree
+type markdownRenderer struct {
+	sb         strings.Builder
+	afterBlock bool // A block ended and the next text starts a new paragraph
+}
+
+func (r *markdownRenderer) renderChildren(n *htmlNode) {
+	for _, child := range n.children {
+		r.render(child)
+	}
+}
+
+func (r *markdownRenderer) render(n *htmlNode) {
+	switch n.tag {
+	case "":
+		r.writeText(n.text)
+	case "b", "strong":
+		r.writeDelimited(n, "**", "**")
+	case "i", "em":
+		r.writeDelimited(n, "*", "*")
+	case "s", "strike", "del":
+		r.writeDelimited(n, "~~", "~~")
+	case "u", "ins":
+		r.writeDelimited(n, "<u>", "</u>")
+	case "tg-spoiler", "spoiler":
+		r.writeDelimited(n, `<span class="spoiler">`, "</span>")
+	case "span":
+		if class, _ := n.attr("class"); class == "tg-spoiler" {
+			r.writeDelimited(n, `<span class="spoiler">`, "</span>")
+		} else {
+			r.renderChildren(n)
+		}
+	case "a":
+		r.writeLink(n)
+	case "code":
+		r.writeCodeSpan(textContent(n))
+	case "pre":
+		r.writeCodeBlock(n)
+	case "blockquote":
+		r.writeBlockquote(n)
+	case "br":
+		r.newline()
+	default:
+		r.renderChildren(n)
+	}
+}
+
+func (r *markdownRenderer) write(s string) {
+	if s == "" {
+		return
+	}
+	if r.afterBlock {
+		r.sb.WriteString("\n")
+		r.afterBlock = false
+	}
+	r.sb.WriteString(s)
+}
+
+func (r *markdownRenderer) atLineStart() bool {
+	out := r.sb.String()
+	return r.afterBlock || out == "" || strings.HasSuffix(out, "\n")
+}
+
+// writeText writes escaped text, with newlines as line breaks
+func (r *markdownRenderer) writeText(text string) {
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			r.newline()
+		}
+		if line == "" {
+			continue
+		}
+		r.write(escapeMarkdown(line, r.atLineStart()))
+	}
+}
+
+// newline ends the current line with a hard line break. A second newline ends the paragraph instead.
+func (r *markdownRenderer) newline() {
+	out := strings.TrimRight(r.sb.String(), " ")
+	if r.afterBlock || out == "" {
+		// A block is always followed by a single blank line
+		return
+	}
+
+	if strings.HasSuffix(out, "\n") {
+		out = strings.TrimRight(out, " \n") + "\n\n"
+	} else {
+		out += "  \n"
+	}
+	r.sb.Reset()
+	r.sb.WriteString(out)
+}
+
+// writeDelimited wraps the Markdown of an element in delimiters. Surrounding whitespace is
+// moved outside of the delimiters, since CommonMark does not allow emphasis to start or end with it.
+func (r *markdownRenderer) writeDelimited(n *htmlNode, open, close string) {
+	inner := r.renderInline(n)
+	trimmed := strings.TrimLeft(inner, " \n")
+	leading := inner[:len(inner)-len(trimmed)]
+	core := strings.TrimRight(trimmed, " \n")
+	trailing := trimmed[len(core):]
+
+	r.writeText(leading)
+	if core != "" {
+		// Emphasis right after other emphasis would merge into a single delimiter run
+		if open[0] == '*' && strings.HasSuffix(r.sb.String(), "*") && !r.afterBlock {
+			open = strings.ReplaceAll(open, "*", "_")
+			close = strings.ReplaceAll(close, "*", "_")
+		}
+		r.write(open + core + close)
+	}
+	r.writeText(trailing)
+}
+
+// renderInline renders the children of an element on their own, continuing the current line
+func (r *markdownRenderer) renderInline(n *htmlNode) string {
+	inner := &markdownRenderer{}
+	if !r.atLineStart() {
+		// Line start escapes only apply at the start of the line
+		inner.sb.WriteString("x")
+	}
+	inner.renderChildren(n)
+	out := inner.sb.String()
+	if !r.atLineStart() {
+		out = out[1:]
+	}
+	return out
+}
+
+func (r *markdownRenderer) writeLink(n *htmlNode) {
+	href, _ := n.attr("href")
+	text := strings.TrimSpace(r.renderInline(n))
+	if href == "" || text == "" {
+		r.renderChildren(n)
+		return
+	}
+
+	if strings.ContainsAny(href, " ()<>") {
+		href = "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(href) + ">"
+	}
+	r.write("[" + text + "](" + href + ")")
+}
+
+func (r *markdownRenderer) writeCodeSpan(code string) {
+	if code == "" {
+		return
+	}
+	fence := strings.Repeat("`", longestRun(code, '`')+1)
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") ||
+		(strings.HasPrefix(code, " ") && strings.HasSuffix(code, " ") && strings.TrimSpace(code) != "") {
+		code = " " + code + " "
+	}
+	r.write(fence + code + fence)
+}
+
+func (r *markdownRenderer) writeCodeBlock(n *htmlNode) {
+	language := ""
+	for _, child := range n.children {
+		if child.tag != "code" {
+			continue
+		}
+		if class, ok := child.attr("class"); ok {
+			language = strings.TrimPrefix(class, "language-")
+		}
+		break
+	}
+	if lang, ok := n.attr("language"); ok && language == "" {
+		language = lang
+	}
+
+	code := strings.TrimRight(textContent(n), "\n")
+	fence := strings.Repeat("`", max(3, longestRun(code, '`')+1))
+
+	r.startBlock()
+	r.sb.WriteString(fence + language + "\n" + code + "\n" + fence + "\n")
+	r.afterBlock = true
+}
+
+func (r *markdownRenderer) writeBlockquote(n *htmlNode) {
+	inner := &markdownRenderer{}
+	inner.renderChildren(n)
+	quote := strings.TrimRight(inner.sb.String(), " \n")
+	if quote == "" {
+		return
+	}
+
+	r.startBlock()
+	for line := range strings.SplitSeq(quote, "\n") {
+		if line == "" {
+			r.sb.WriteString(">\n")
+		} else {
+			r.sb.WriteString("> " + line + "\n")
+		}
+	}
+	r.afterBlock = true
+}
+
+// startBlock ends the current paragraph with a blank line before a block element
+func (r *markdownRenderer) startBlock() {
+	out := strings.TrimRight(r.sb.String(), " \n")
+	r.sb.Reset()
+	r.sb.WriteString(out)
+	if out != "" {
+		r.sb.WriteString("\n\n")
+	}
+	r.afterBlock = false
+}
+
+// textContent returns the text of a node and all its descendants
+func textContent(n *htmlNode) string {
+	if n.tag == "" {
+		return n.text
+	}
+	var sb strings.Builder
+	for _, child := range n.children {
+		sb.WriteString(textContent(child))
+	}
+	return sb.String()
+}
+
+func longestRun(s string, c byte) int {
+	longest, current := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return longest
+}
+
+var (
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "~", `\~`,
+		"&", "&amp;", "<", "&lt;", ">", "&gt;",
+	)
+	lineStartRegex   = regexp.MustCompile(`^(\s*)([#+=-])`)
+	orderedListRegex = regexp.MustCompile(`^(\s*\d+)([.)])`)
+)
+
+// escapeMarkdown escapes text so that it renders literally. Characters that only have a meaning
+// at the start of a line, like headings and list markers, are escaped when atLineStart is set.
+func escapeMarkdown(text string, atLineStart bool) string {
+	text = markdownEscaper.Replace(text)
+	if atLineStart {
+		text = lineStartRegex.ReplaceAllString(text, `$1\$2`)
+		text = orderedListRegex.ReplaceAllString(text, `$1\$2`)
+	}
+	return text
+}
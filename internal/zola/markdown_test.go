@@ -0,0 +1,74 @@
+package zola
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// TestHTMLToMarkdown_Golden converts every testdata/markdown/*.html file and compares it with the .md file next to it
+func TestHTMLToMarkdown_Golden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "markdown", "*.html"))
+	if err != nil {
+		t.Fatalf("failed to list golden files: %v", err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no golden files found")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".html")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", input, err)
+			}
+			result := HTMLToMarkdown(string(data)) + "\n"
+
+			golden := strings.TrimSuffix(input, ".html") + ".md"
+			if *update {
+				if err := os.WriteFile(golden, []byte(result), 0644); err != nil {
+					t.Fatalf("failed to update %s: %v", golden, err)
+				}
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", golden, err)
+			}
+			if result != string(expected) {
+				t.Errorf("Markdown mismatch for %s\nexpected:\n%s\ngot:\n%s", input, expected, result)
+			}
+		})
+	}
+}
+
+func TestHTMLToMarkdown_Inline(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"bold", "<b>bold</b>", "**bold**"},
+		{"whitespace outside of emphasis", "a<b> b </b>c", "a **b** c"},
+		{"empty emphasis", "a<i></i>b", "ab"},
+		{"link with spaces", `<a href="https://example.com/a b">x</a>`, "[x](<https://example.com/a b>)"},
+		{"code with backticks", "<code>a`b</code>", "``a`b``"},
+		{"hard break", "a<br>b", "a  \nb"},
+		{"paragraphs", "a\n\nb", "a\n\nb"},
+		{"ordered list marker", "2024. A year", `2024\. A year`},
+		{"marker after emphasis", "<b>x</b> - y", "**x** - y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := HTMLToMarkdown(tt.input); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
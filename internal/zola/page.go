@@ -0,0 +1,144 @@
+package zola
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-git/go-billy/v6/util"
+)
+
+// Page is a Zola page: its TOML front matter and Markdown content
+type Page struct {
+	Path        string // Source file relative to the content directory, e.g. "posts/123.md"
+	Title       string
+	Description string
+	Date        time.Time
+	Draft       bool
+	Slug        string              // Replaces the file name in the permalink
+	URLPath     string              // "path" front matter, replaces the whole permalink path
+	Taxonomies  map[string][]string // e.g. "tags"
+	Extra       map[string]any
+	Content     string // Markdown after the front matter
+}
+
+// ParsePage parses a Zola page. pagePath is the path of the file relative to the content directory.
+// Front matter keys PostPal does not use are ignored.
+func ParsePage(pagePath string, data []byte) (*Page, error) {
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	frontMatter, body := splitFrontMatter(content)
+	if frontMatter == "" {
+		return nil, errors.New("page has no TOML front matter")
+	}
+
+	var doc map[string]any
+	_, err := toml.Decode(strings.TrimSuffix(strings.TrimPrefix(frontMatter, "+++\n"), "+++\n"), &doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse front matter: %w", err)
+	}
+
+	page := &Page{Path: path.Clean(pagePath), Content: body}
+	page.Title, _ = doc["title"].(string)
+	page.Description, _ = doc["description"].(string)
+	page.Draft, _ = doc["draft"].(bool)
+	page.Slug, _ = doc["slug"].(string)
+	page.URLPath, _ = doc["path"].(string)
+	page.Extra, _ = doc["extra"].(map[string]any)
+
+	switch date := doc["date"].(type) {
+	case time.Time:
+		page.Date = date
+	case string:
+		if page.Date, err = time.Parse(time.RFC3339, date); err != nil {
+			if page.Date, err = time.Parse(time.DateOnly, date); err != nil {
+				return nil, fmt.Errorf("invalid page date %q", date)
+			}
+		}
+	}
+
+	if taxonomies, ok := doc["taxonomies"].(map[string]any); ok {
+		page.Taxonomies = make(map[string][]string, len(taxonomies))
+		for name, terms := range taxonomies {
+			items, _ := terms.([]any)
+			for _, item := range items {
+				if term, ok := item.(string); ok {
+					page.Taxonomies[name] = append(page.Taxonomies[name], term)
+				}
+			}
+		}
+	}
+
+	return page, nil
+}
+
+// Permalink returns the URL Zola publishes the page at, below the site's base URL.
+// Like Zola, it uses the "path" front matter if set, and otherwise the section directories
+// followed by the slug, or the file name (the directory name for index.md).
+func (p *Page) Permalink(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if p.URLPath != "" {
+		return baseURL + "/" + strings.Trim(p.URLPath, "/") + "/"
+	}
+
+	dir, file := path.Split(p.Path)
+	name := strings.TrimSuffix(file, ".md")
+	if name == "index" {
+		dir, name = path.Split(strings.TrimSuffix(dir, "/"))
+	}
+	if p.Slug != "" {
+		name = p.Slug
+	}
+
+	parts := []string{baseURL}
+	for section := range strings.SplitSeq(strings.Trim(dir, "/"), "/") {
+		if section != "" {
+			parts = append(parts, section)
+		}
+	}
+	parts = append(parts, slugify(name))
+	return strings.Join(parts, "/") + "/"
+}
+
+// slugify lowercases a name and replaces runs of other characters than letters and digits with dashes
+func slugify(name string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return sb.String()
+}
+
+// Page reads and parses the page of a post
+func (s *Service) Page(postID int64) (*Page, error) {
+	postIDStr := strconv.FormatInt(postID, 10)
+	postPath := filepath.Join(s.relPostsDir, postIDStr, "index.md")
+	data, err := util.ReadFile(s.fs, postPath)
+	if err != nil {
+		postPath = filepath.Join(s.relPostsDir, postIDStr+".md")
+		if data, err = util.ReadFile(s.fs, postPath); err != nil {
+			return nil, fmt.Errorf("failed to read post %d: %w", postID, err)
+		}
+	}
+
+	// Zola paths are relative to the content directory
+	contentPath, ok := strings.CutPrefix(filepath.ToSlash(postPath), "content/")
+	if !ok {
+		contentPath = filepath.ToSlash(postPath)
+	}
+	return ParsePage(contentPath, data)
+}
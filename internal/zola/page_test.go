@@ -0,0 +1,156 @@
+package zola
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-billy/v6/util"
+)
+
+func TestParsePage(t *testing.T) {
+	data := "+++\r\n" +
+		"title = \"Hello, world\"\r\n" +
+		"date = 2024-05-01T10:00:00Z\r\n" +
+		"draft = true\r\n" +
+		"slug = \"hello\"\r\n" +
+		"unknown = 1\r\n" +
+		"[taxonomies]\r\n" +
+		"tags = [\"go\", \"zola\"]\r\n" +
+		"[extra]\r\n" +
+		"telegram_id = 42\r\n" +
+		"+++\r\n" +
+		"\r\n" +
+		"Body text\r\n"
+
+	page, err := ParsePage("posts/./hello-world.md", []byte(data))
+	if err != nil {
+		t.Fatalf("ParsePage failed: %v", err)
+	}
+
+	if page.Path != "posts/hello-world.md" {
+		t.Errorf("Expected cleaned path, got %q", page.Path)
+	}
+	if page.Title != "Hello, world" || page.Slug != "hello" || !page.Draft {
+		t.Errorf("Unexpected front matter: %+v", page)
+	}
+	if !page.Date.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected date %v", page.Date)
+	}
+	if !slices.Equal(page.Taxonomies["tags"], []string{"go", "zola"}) {
+		t.Errorf("Unexpected tags %v", page.Taxonomies["tags"])
+	}
+	if page.Extra["telegram_id"] != int64(42) {
+		t.Errorf("Unexpected extra %v", page.Extra)
+	}
+	if page.Content != "Body text\n" {
+		t.Errorf("Unexpected content %q", page.Content)
+	}
+}
+
+func TestParsePage_MultiLineStrings(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "pages", "multiline.md"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	page, err := ParsePage("posts/release.md", data)
+	if err != nil {
+		t.Fatalf("ParsePage failed: %v", err)
+	}
+
+	want := "A long description that Zola themes show\nbelow the title, written over several lines."
+	if page.Description != want {
+		t.Errorf("Expected multi-line description %q, got %q", want, page.Description)
+	}
+	if page.Extra["summary"] != `Literal text with a \backslash` {
+		t.Errorf("Unexpected extra %v", page.Extra)
+	}
+	if !page.Date.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, page.Date.Location())) || page.Title != "Release notes" {
+		t.Errorf("Unexpected front matter: %+v", page)
+	}
+	if page.Content != "What changed in this release.\n" {
+		t.Errorf("Unexpected content %q", page.Content)
+	}
+}
+
+func TestParsePage_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"no front matter", "Just text\n"},
+		{"yaml front matter", "---\ntitle: x\n---\n"},
+		{"invalid toml", "+++\ntitle = \n+++\n"},
+		{"invalid date string", "+++\ndate = \"yesterday\"\n+++\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePage("posts/a.md", []byte(tt.data)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestPage_Permalink(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     Page
+		expected string
+	}{
+		{"file name", Page{Path: "posts/Hello World.md"}, "https://example.com/posts/hello-world/"},
+		{"index page", Page{Path: "posts/123/index.md"}, "https://example.com/posts/123/"},
+		{"slug", Page{Path: "posts/123/index.md", Slug: "My Post"}, "https://example.com/posts/my-post/"},
+		{"path override", Page{Path: "posts/a.md", URLPath: "/custom/url"}, "https://example.com/custom/url/"},
+		{"top level page", Page{Path: "about.md"}, "https://example.com/about/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.page.Permalink("https://example.com/"); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestService_Page(t *testing.T) {
+	fs := memfs.New()
+	service := NewService("/site/content/posts", "content/posts", "/site", "@testchannel", nil, "").WithFS(fs)
+
+	post := Post{ID: 700, ChatID: -100123, Content: "Hello from Telegram", Date: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), MessageIDs: []int64{700}}
+	if err := service.CreatePost(context.Background(), post, nil); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+
+	page, err := service.Page(700)
+	if err != nil {
+		t.Fatalf("Page failed: %v", err)
+	}
+	if page.Path != "posts/700.md" {
+		t.Errorf("Expected path relative to the content directory, got %q", page.Path)
+	}
+	if !contains(page.Content, "Hello from Telegram") {
+		t.Errorf("Unexpected content %q", page.Content)
+	}
+	if page.Permalink("https://example.com") != "https://example.com/posts/700/" {
+		t.Errorf("Unexpected permalink %q", page.Permalink("https://example.com"))
+	}
+
+	if err := util.WriteFile(fs, "content/posts/701/index.md", []byte("+++\ntitle = \"Bundle\"\n+++\n"), 0644); err != nil {
+		t.Fatalf("failed to write page: %v", err)
+	}
+	if page, err := service.Page(701); err != nil || page.Path != "posts/701/index.md" || page.Title != "Bundle" {
+		t.Errorf("Unexpected page bundle %+v, %v", page, err)
+	}
+
+	if _, err := service.Page(702); err == nil {
+		t.Error("Expected an error for a missing post")
+	}
+}
@@ -1,58 +1,22 @@
 package zola
 
 import (
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/en9inerd/postpal/internal/telegram"
 )
 
 // ProcessContent converts Telegram HTML content to Markdown format.
-// HTML entities are NOT decoded (matches TypeScript implementation).
+// See HTMLToMarkdown for the supported tags. Posts are rendered from message entities instead, see EntitiesToMarkdown.
 func ProcessContent(content string) string {
 	if content == "" {
 		return ""
 	}
-
-	codeRegex := regexp.MustCompile(`<code>([\s\S]*?)</code>`)
-	content = codeRegex.ReplaceAllStringFunc(content, func(match string) string {
-		codeContent := codeRegex.FindStringSubmatch(match)[1]
-		escapedCodeContent := strings.ReplaceAll(codeContent, "<", "&lt;")
-		escapedCodeContent = strings.ReplaceAll(escapedCodeContent, ">", "&gt;")
-		return "<code>" + escapedCodeContent + "</code>"
-	})
-
-	codeBlockRegex := regexp.MustCompile(`<pre><code class="language-(.*?)">([\s\S]*?)</code></pre>`)
-	codeBlockPlaceholders := make(map[string]string)
-	codeIndex := 0
-	content = codeBlockRegex.ReplaceAllStringFunc(content, func(match string) string {
-		matches := codeBlockRegex.FindStringSubmatch(match)
-		language := matches[1]
-		codeContent := strings.TrimRight(matches[2], "\n")
-		markdownCodeBlock := "```" + language + "\n" + codeContent + "\n```"
-		placeholder := "___CODEBLOCK_" + string(rune('A'+codeIndex)) + "___"
-		codeBlockPlaceholders[placeholder] = markdownCodeBlock
-		codeIndex++
-		return placeholder
-	})
-
-	blockquoteRegex := regexp.MustCompile(`<blockquote>([\s\S]*?)</blockquote>`)
-	content = blockquoteRegex.ReplaceAllStringFunc(content, func(match string) string {
-		blockquoteContent := blockquoteRegex.FindStringSubmatch(match)[1]
-		blockquoteContent = strings.ReplaceAll(blockquoteContent, "\n", "<br>")
-		return "<blockquote>" + blockquoteContent + "</blockquote>"
-	})
-
-	content = strings.ReplaceAll(content, "\n", "  \n")
-	content = strings.ReplaceAll(content, "  \n  \n", "  \n\n")
-
-	for placeholder, codeBlock := range codeBlockPlaceholders {
-		content = strings.ReplaceAll(content, placeholder, codeBlock)
-	}
-
-	spoilerRegex := regexp.MustCompile(`<spoiler>([\s\S]*?)</spoiler>`)
-	content = spoilerRegex.ReplaceAllString(content, `<span class="spoiler">$1</span>`)
-
-	return content
+	return HTMLToMarkdown(content)
 }
 
 // ExtractTitle looks for an address regex pattern (0x...) in content.
@@ -72,6 +36,43 @@ func ExtractTitle(content string, channelID string) string {
 	return channelID
 }
 
+// Title strategies select how a post title is derived from its content
+const (
+	TitleStrategyAddress   = "address"    // Channel name followed by a trailing 0x... address (default)
+	TitleStrategyFirstLine = "first_line" // First non-empty line of the content
+	TitleStrategyChannel   = "channel"    // Channel name only
+)
+
+// TitleStrategies lists the supported title strategies
+var TitleStrategies = []string{TitleStrategyAddress, TitleStrategyFirstLine, TitleStrategyChannel}
+
+const maxTitleLength = 100
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// BuildTitle derives a post title from content using the given strategy.
+// Unknown strategies fall back to TitleStrategyAddress.
+func BuildTitle(strategy, content, channelName string) string {
+	switch strategy {
+	case TitleStrategyChannel:
+		return channelName
+	case TitleStrategyFirstLine:
+		for line := range strings.SplitSeq(content, "\n") {
+			line = strings.TrimSpace(htmlTagRegex.ReplaceAllString(line, ""))
+			if line == "" {
+				continue
+			}
+			if runes := []rune(line); len(runes) > maxTitleLength {
+				line = strings.TrimSpace(string(runes[:maxTitleLength])) + "…"
+			}
+			return line
+		}
+		return channelName
+	default:
+		return ExtractTitle(content, channelName)
+	}
+}
+
 // RemoveAddressPattern removes the address regex pattern from content.
 func RemoveAddressPattern(content string) string {
 	addressRegex := regexp.MustCompile(`(?m)(\s\s\n)?0x[0-9a-fA-F]+\n?$`)
@@ -80,38 +81,115 @@ func RemoveAddressPattern(content string) string {
 
 // Post represents a Zola blog post
 type Post struct {
-	ID         int64
-	Title      string
-	Content    string
-	Date       time.Time
-	ImageNames []string
+	ID           int64
+	Title        string
+	Content      string                   // Text or caption of the Telegram message
+	Entities     []telegram.MessageEntity // Formatting of Content
+	Date         time.Time
+	ImageNames   []string
+	Tags         []string
+	Draft        bool
+	ChatID       int64   // Telegram chat the post was published from
+	MessageIDs   []int64 // Telegram message of each image slot; a single ID for text posts
+	MediaGroupID string  // Telegram album the post was created from
 }
 
 // BuildFrontMatter generates TOML front matter for a Zola post.
 func BuildFrontMatter(post Post) string {
 	var sb strings.Builder
 	sb.WriteString("+++\n")
-	sb.WriteString("title = \"")
-	sb.WriteString(strings.ReplaceAll(post.Title, "\"", "\\\""))
-	sb.WriteString("\"\n")
+	sb.WriteString("title = ")
+	sb.WriteString(tomlString(post.Title))
+	sb.WriteString("\n")
 	sb.WriteString("date = ")
 	sb.WriteString(post.Date.Format(time.RFC3339))
-	sb.WriteString("\n\n")
+	sb.WriteString("\n")
+	if post.Draft {
+		sb.WriteString("draft = true\n")
+	}
+	sb.WriteString("\n")
 
-	if len(post.ImageNames) > 0 {
+	if len(post.Tags) > 0 {
+		sb.WriteString("[taxonomies]\n")
+		sb.WriteString("tags = [")
+		for i, tag := range post.Tags {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(tomlString(tag))
+		}
+		sb.WriteString("]\n\n")
+	}
+
+	if len(post.ImageNames) > 0 || len(post.MessageIDs) > 0 {
 		sb.WriteString("[extra]\n")
+	}
+
+	if len(post.ImageNames) > 0 {
 		sb.WriteString("images = [")
 		for i, imgName := range post.ImageNames {
 			if i > 0 {
 				sb.WriteString(", ")
 			}
-			sb.WriteString("\"")
-			sb.WriteString(imgName)
-			sb.WriteString("\"")
+			sb.WriteString(tomlString(imgName))
 		}
 		sb.WriteString("]\n")
 	}
 
+	// Telegram references allow the post index to be rebuilt from the posts alone
+	if len(post.MessageIDs) > 0 {
+		if post.ChatID != 0 {
+			sb.WriteString("telegram_chat_id = ")
+			sb.WriteString(strconv.FormatInt(post.ChatID, 10))
+			sb.WriteString("\n")
+		}
+		sb.WriteString("telegram_message_ids = [")
+		for i, id := range post.MessageIDs {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(strconv.FormatInt(id, 10))
+		}
+		sb.WriteString("]\n")
+		if post.MediaGroupID != "" {
+			sb.WriteString("telegram_media_group_id = ")
+			sb.WriteString(tomlString(post.MediaGroupID))
+			sb.WriteString("\n")
+		}
+	}
+
 	sb.WriteString("+++\n\n")
 	return sb.String()
 }
+
+// tomlString quotes s as a TOML basic string
+func tomlString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
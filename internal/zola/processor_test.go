@@ -1,9 +1,12 @@
 package zola
 
 import (
+	"slices"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/BurntSushi/toml"
 )
 
 func TestProcessContent_Empty(t *testing.T) {
@@ -51,7 +54,7 @@ func TestProcessContent_MultipleSpoilers(t *testing.T) {
 
 func TestProcessContent_InlineCode(t *testing.T) {
 	input := "Use <code>fmt.Println()</code> to print"
-	expected := "Use <code>fmt.Println()</code> to print"
+	expected := "Use `fmt.Println()` to print"
 	result := ProcessContent(input)
 	if result != expected {
 		t.Errorf("Expected %q, got %q", expected, result)
@@ -59,10 +62,9 @@ func TestProcessContent_InlineCode(t *testing.T) {
 }
 
 func TestProcessContent_InlineCodeWithAngleBrackets(t *testing.T) {
-	// TypeScript escapes < and > to &lt; and &gt; in code tags
-	// But if input already has entities, they stay as entities
-	input := "Check <code>if x < 10 && y > 5</code> condition"
-	expected := "Check <code>if x &lt; 10 && y &gt; 5</code> condition"
+	// Code spans are literal in Markdown, so entities are decoded
+	input := "Check <code>if x &lt; 10 &amp;&amp; y &gt; 5</code> condition"
+	expected := "Check `if x < 10 && y > 5` condition"
 	result := ProcessContent(input)
 	if result != expected {
 		t.Errorf("Expected %q, got %q", expected, result)
@@ -96,7 +98,7 @@ func TestProcessContent_CodeBlockWithLanguage(t *testing.T) {
 
 func TestProcessContent_Blockquote(t *testing.T) {
 	input := "<blockquote>This is a quote\nwith multiple lines</blockquote>"
-	expected := "<blockquote>This is a quote<br>with multiple lines</blockquote>"
+	expected := "> This is a quote  \n> with multiple lines"
 	result := ProcessContent(input)
 	if result != expected {
 		t.Errorf("Expected %q, got %q", expected, result)
@@ -118,11 +120,8 @@ More text with <spoiler>hidden content</spoiler>.
 </code></pre>
 
 Final text.`
-	// TypeScript processes line breaks before converting code blocks
-	// So code blocks may have double spaces in their content
-	// But actually, <pre> sections are left untouched during line break processing
-	// So code blocks should NOT have double spaces
-	expected := "Here's some text  \nwith line breaks.  \n\n<blockquote>This is quoted<br>text</blockquote>  \n\nMore text with <span class=\"spoiler\">hidden content</span>.  \n\n```go\nfunc test() {\n    return true\n}\n```  \n\nFinal text."
+	// Blocks are separated from paragraphs by a single blank line and code blocks keep their content as is
+	expected := "Here's some text  \nwith line breaks.\n\n> This is quoted  \n> text\n\nMore text with <span class=\"spoiler\">hidden content</span>.\n\n```go\nfunc test() {\n    return true\n}\n```\n\nFinal text."
 	result := ProcessContent(input)
 	if result != expected {
 		t.Errorf("Expected %q, got %q", expected, result)
@@ -130,8 +129,7 @@ Final text.`
 }
 
 func TestProcessContent_HTMLEntities(t *testing.T) {
-	// TypeScript implementation does NOT decode HTML entities
-	// They remain as-is in the output
+	// Entities are decoded and escaped again, since Markdown passes inline HTML through
 	input := "Text with &lt;entities&gt; and &amp; symbols"
 	expected := "Text with &lt;entities&gt; and &amp; symbols"
 	result := ProcessContent(input)
@@ -294,3 +292,90 @@ func TestBuildFrontMatter_EmptyImages(t *testing.T) {
 		t.Errorf("Expected no [extra] section, got:\n%q", result)
 	}
 }
+
+func TestBuildFrontMatter_WithTagsAndDraft(t *testing.T) {
+	post := Post{
+		ID:         321,
+		Title:      "Tagged",
+		Content:    "Content",
+		Date:       time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+		ImageNames: []string{"image_0.jpg"},
+		Tags:       []string{"news", "releases"},
+		Draft:      true,
+	}
+	result := BuildFrontMatter(post)
+	expected := `+++
+title = "Tagged"
+date = 2024-05-01T09:00:00Z
+draft = true
+
+[taxonomies]
+tags = ["news", "releases"]
+
+[extra]
+images = ["image_0.jpg"]
++++
+
+`
+	if result != expected {
+		t.Errorf("Expected:\n%q\nGot:\n%q", expected, result)
+	}
+}
+
+func TestBuildFrontMatter_EscapesStrings(t *testing.T) {
+	post := Post{
+		ID:      322,
+		Title:   "Back\\slash \"and\" tab\t",
+		Content: "Content",
+		Date:    time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+		Tags:    []string{`C:\path`, "line\nbreak", "bell\a", "del\x7f"},
+	}
+
+	var doc struct {
+		Title      string
+		Taxonomies struct{ Tags []string }
+	}
+	frontMatter := strings.TrimSuffix(strings.TrimPrefix(BuildFrontMatter(post), "+++\n"), "+++\n\n")
+	if _, err := toml.Decode(frontMatter, &doc); err != nil {
+		t.Fatalf("expected valid TOML, got %v:\n%s", err, frontMatter)
+	}
+	if doc.Title != post.Title {
+		t.Errorf("expected title %q, got %q", post.Title, doc.Title)
+	}
+	if !slices.Equal(doc.Taxonomies.Tags, post.Tags) {
+		t.Errorf("expected tags %q, got %q", post.Tags, doc.Taxonomies.Tags)
+	}
+}
+
+func TestBuildTitle_Strategies(t *testing.T) {
+	content := "<b>Release v1.2</b>\nDetails here  \n0x1234abcd"
+
+	tests := []struct {
+		strategy string
+		expected string
+	}{
+		{TitleStrategyAddress, "MyChannel [0x1234abcd]"},
+		{TitleStrategyFirstLine, "Release v1.2"},
+		{TitleStrategyChannel, "MyChannel"},
+		{"unknown", "MyChannel [0x1234abcd]"},
+	}
+
+	for _, tt := range tests {
+		if result := BuildTitle(tt.strategy, content, "MyChannel"); result != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.strategy, tt.expected, result)
+		}
+	}
+}
+
+func TestBuildTitle_FirstLineTruncated(t *testing.T) {
+	content := "\n" + strings.Repeat("a", 150)
+
+	result := BuildTitle(TitleStrategyFirstLine, content, "MyChannel")
+	if result != strings.Repeat("a", maxTitleLength)+"…" {
+		t.Errorf("Expected truncated title, got %q", result)
+	}
+
+	if result := BuildTitle(TitleStrategyFirstLine, "", "MyChannel"); result != "MyChannel" {
+		t.Errorf("Expected channel name for empty content, got %q", result)
+	}
+}
@@ -2,14 +2,17 @@ package zola
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/en9inerd/postpal/internal/git"
+	"github.com/go-git/go-billy/v6/osfs"
+	"github.com/go-git/go-billy/v6/util"
 )
 
 // Service handles Zola blog post creation and management
@@ -20,6 +23,12 @@ type Service struct {
 	channelID       string
 	gitService      *git.Service
 	exportedDataDir string
+	index           *Index
+	titleStrategy   string
+	tags            []string
+	draft           bool
+	fs              FS
+	dryRun          *overlayFS // Filesystem of a dry run, nil when writing to fs
 }
 
 // NewService creates a new Zola post service
@@ -31,10 +40,52 @@ func NewService(postsDir, relPostsDir, repoDir, channelID string, gitService *gi
 		channelID:       channelID,
 		gitService:      gitService,
 		exportedDataDir: exportedDataDir,
+		fs:              osfs.New(repoDir),
+		index:           NewIndex(""),
+		titleStrategy:   TitleStrategyAddress,
 	}
 }
 
-// CreatePost creates a new Zola blog post from a Post struct and media files
+// WithIndex sets the index used to map Telegram messages to posts.
+// Without it the service keeps an in-memory index that is lost on restart.
+func (s *Service) WithIndex(index *Index) *Service {
+	s.index = index
+	return s
+}
+
+// WithFS sets the filesystem posts are written to, rooted at the repository, e.g. the
+// worktree filesystem of an in-memory go-git repository. Without it posts are written to
+// repoDir on disk. Files are only staged when the service has a git service.
+func (s *Service) WithFS(fs FS) *Service {
+	s.fs = fs
+	return s
+}
+
+// WithTitleStrategy sets how post titles are derived from content (see TitleStrategies)
+func (s *Service) WithTitleStrategy(strategy string) *Service {
+	s.titleStrategy = strategy
+	return s
+}
+
+// WithTags sets the tags added to every post
+func (s *Service) WithTags(tags []string) *Service {
+	s.tags = tags
+	return s
+}
+
+// WithDraft marks every written post as a draft, which Zola does not publish
+func (s *Service) WithDraft(draft bool) *Service {
+	s.draft = draft
+	return s
+}
+
+// PostsDir returns the directory posts are written to
+func (s *Service) PostsDir() string {
+	return s.postsDir
+}
+
+// CreatePost creates a new Zola blog post from a Post struct and media files.
+// The post is added to the index in memory; SaveIndex persists it once it is committed.
 func (s *Service) CreatePost(ctx context.Context, post Post, mediaFiles [][]byte) error {
 	imageNames := make([]string, len(mediaFiles))
 	for i := range mediaFiles {
@@ -44,257 +95,348 @@ func (s *Service) CreatePost(ctx context.Context, post Post, mediaFiles [][]byte
 	post.ImageNames = imageNames
 
 	var filename string
-	var postFilePath string
 
 	if len(post.ImageNames) > 0 {
 		// Create directory for post with media
-		postDir := filepath.Join(s.postsDir, strconv.FormatInt(post.ID, 10))
-		if err := os.MkdirAll(postDir, 0755); err != nil {
+		postDir := filepath.Join(s.relPostsDir, strconv.FormatInt(post.ID, 10))
+		if err := s.fs.MkdirAll(postDir, 0755); err != nil {
 			return fmt.Errorf("failed to create post directory: %w", err)
 		}
 		filename = filepath.Join(strconv.FormatInt(post.ID, 10), "index.md")
-		postFilePath = filepath.Join(s.postsDir, filename)
 	} else {
-		if err := os.MkdirAll(s.postsDir, 0755); err != nil {
+		if err := s.fs.MkdirAll(s.relPostsDir, 0755); err != nil {
 			return fmt.Errorf("failed to create posts directory: %w", err)
 		}
 		filename = strconv.FormatInt(post.ID, 10) + ".md"
-		postFilePath = filepath.Join(s.postsDir, filename)
-	}
-
-	processedContent := ProcessContent(post.Content)
-	if post.Title == "" {
-		post.Title = ExtractTitle(post.Content, s.channelID)
 	}
-	processedContent = RemoveAddressPattern(processedContent)
 
-	frontMatter := BuildFrontMatter(post)
-	postContent := frontMatter + processedContent + "\n"
-
-	if err := os.WriteFile(postFilePath, []byte(postContent), 0644); err != nil {
+	postPath := filepath.Join(s.relPostsDir, filename)
+	if err := util.WriteFile(s.fs, postPath, []byte(s.renderPost(post)), 0644); err != nil {
 		return fmt.Errorf("failed to write post file: %w", err)
 	}
 
-	relPostPath := filepath.Join(s.relPostsDir, filename)
-	if err := s.gitService.Add(relPostPath); err != nil {
+	if err := s.stage(postPath); err != nil {
 		return fmt.Errorf("failed to add post file to git: %w", err)
 	}
 
 	for i, mediaFile := range mediaFiles {
-		imageFilename := post.ImageNames[i]
-		imagePath := filepath.Join(s.postsDir, strconv.FormatInt(post.ID, 10), imageFilename)
-		relImagePath := filepath.Join(s.relPostsDir, strconv.FormatInt(post.ID, 10), imageFilename)
+		imagePath := filepath.Join(s.relPostsDir, strconv.FormatInt(post.ID, 10), post.ImageNames[i])
 
-		if err := os.WriteFile(imagePath, mediaFile, 0644); err != nil {
+		if err := util.WriteFile(s.fs, imagePath, mediaFile, 0644); err != nil {
 			return fmt.Errorf("failed to write image file: %w", err)
 		}
 
-		if err := s.gitService.Add(relImagePath); err != nil {
+		if err := s.stage(imagePath); err != nil {
 			return fmt.Errorf("failed to add image file to git: %w", err)
 		}
 	}
 
+	s.indexPost(post)
+
 	return nil
 }
 
-// EditPost edits an existing post, finding the closest post ID
-func (s *Service) EditPost(ctx context.Context, post Post, mediaFile []byte) error {
-	originalPostID := post.ID
+// PostID returns the post a Telegram message is published in
+func (s *Service) PostID(chatID, messageID int64) (int64, bool) {
+	entry, ok := s.index.Lookup(chatID, messageID)
+	return entry.PostID, ok
+}
 
-	editablePostID, err := s.getEditablePostID(post.ID)
-	if err != nil {
-		return fmt.Errorf("failed to find editable post: %w", err)
+// EditPost updates the post an edited Telegram message belongs to.
+// post.ID and post.ChatID identify the edited message; the post and the image slot
+// of the message are resolved through the index.
+func (s *Service) EditPost(ctx context.Context, post Post, mediaFile []byte) error {
+	entry, ok := s.index.Lookup(post.ChatID, post.ID)
+	if !ok {
+		return fmt.Errorf("%w: message %d", ErrPostNotFound, post.ID)
 	}
 
-	imageNames, err := s.getPostImageNames(editablePostID)
+	postID := entry.PostID
+	postIDStr := strconv.FormatInt(postID, 10)
+
+	imageNames, err := s.getPostImageNames(postID)
 	if err != nil {
 		return fmt.Errorf("failed to get post image names: %w", err)
 	}
 
-	numOfMediaFiles := len(imageNames)
-	post.ID = editablePostID
-
-	if post.Content != "" {
-		if numOfMediaFiles > 0 {
-			if len(post.ImageNames) > 0 {
-				firstImageName := post.ImageNames[0]
-				parts := strings.Split(firstImageName, ".")
-				format := "jpg"
-				if len(parts) > 1 {
-					format = parts[len(parts)-1]
-				}
-				post.ImageNames = make([]string, numOfMediaFiles)
-				for i := range post.ImageNames {
-					post.ImageNames[i] = fmt.Sprintf("image_%d.%s", i, format)
-				}
-			} else {
-				post.ImageNames = imageNames
-			}
+	var renamedFrom, renamedTo string
+	if mediaFile != nil && len(imageNames) > 0 {
+		oldName, newName, err := s.replaceImage(postID, entry.Slot, mediaFile)
+		if err != nil {
+			return err
 		}
-
-		processedContent := ProcessContent(post.Content)
-		if post.Title == "" {
-			post.Title = ExtractTitle(post.Content, s.channelID)
+		if oldName != "" && oldName != newName {
+			renamedFrom, renamedTo = oldName, newName
 		}
-		processedContent = RemoveAddressPattern(processedContent)
-
-		var filename string
-		var postFilePath string
 
-		if numOfMediaFiles > 0 {
-			postDir := filepath.Join(s.postsDir, strconv.FormatInt(editablePostID, 10))
-			if err := os.MkdirAll(postDir, 0755); err != nil {
-				return fmt.Errorf("failed to create post directory: %w", err)
-			}
-			filename = filepath.Join(strconv.FormatInt(editablePostID, 10), "index.md")
-			postFilePath = filepath.Join(s.postsDir, filename)
-		} else {
-			filename = strconv.FormatInt(editablePostID, 10) + ".md"
-			postFilePath = filepath.Join(s.postsDir, filename)
+		imageNames, err = s.getPostImageNames(postID)
+		if err != nil {
+			return fmt.Errorf("failed to get post image names: %w", err)
 		}
+	}
+
+	var filename string
+	if len(imageNames) > 0 {
+		filename = filepath.Join(postIDStr, "index.md")
+	} else {
+		filename = postIDStr + ".md"
+	}
+	postPath := filepath.Join(s.relPostsDir, filename)
 
-		frontMatter := BuildFrontMatter(post)
-		postContent := frontMatter + processedContent + "\n"
+	if post.Content != "" {
+		post.ID = postID
+		post.ImageNames = imageNames
+		post.MessageIDs = s.index.postMessageIDs(postID)
+		post.MediaGroupID = s.index.postMediaGroupID(postID)
 
-		if err := os.WriteFile(postFilePath, []byte(postContent), 0644); err != nil {
+		if err := util.WriteFile(s.fs, postPath, []byte(s.renderPost(post)), 0644); err != nil {
 			return fmt.Errorf("failed to write post file: %w", err)
 		}
-
-		relPostPath := filepath.Join(s.relPostsDir, filename)
-		if err := s.gitService.Add(relPostPath); err != nil {
-			return fmt.Errorf("failed to add post file to git: %w", err)
+	} else if renamedFrom != "" {
+		// Only the media changed, keep the content and point the front matter at the new image
+		content, err := util.ReadFile(s.fs, postPath)
+		if err != nil {
+			return fmt.Errorf("failed to read post file: %w", err)
 		}
+		updated := strings.Replace(string(content), `"`+renamedFrom+`"`, `"`+renamedTo+`"`, 1)
+		if err := util.WriteFile(s.fs, postPath, []byte(updated), 0644); err != nil {
+			return fmt.Errorf("failed to write post file: %w", err)
+		}
+	} else {
+		return nil
 	}
 
-	if mediaFile != nil {
-		index := originalPostID - editablePostID
-		format := getImageFormat(mediaFile)
-		imageFilename := fmt.Sprintf("image_%d.%s", index, format)
-		imagePath := filepath.Join(s.postsDir, strconv.FormatInt(editablePostID, 10), imageFilename)
-		relImagePath := filepath.Join(s.relPostsDir, strconv.FormatInt(editablePostID, 10), imageFilename)
+	if err := s.stage(postPath); err != nil {
+		return fmt.Errorf("failed to add post file to git: %w", err)
+	}
 
-		postDir := filepath.Join(s.postsDir, strconv.FormatInt(editablePostID, 10))
-		if err := os.MkdirAll(postDir, 0755); err != nil {
-			return fmt.Errorf("failed to create post directory: %w", err)
-		}
+	return nil
+}
 
-		if err := os.WriteFile(imagePath, mediaFile, 0644); err != nil {
-			return fmt.Errorf("failed to write image file: %w", err)
-		}
+// renderPost builds the post file contents, applying the service's title strategy, tags and draft setting
+func (s *Service) renderPost(post Post) string {
+	processedContent := EntitiesToMarkdown(post.Content, post.Entities)
+	if post.Title == "" {
+		post.Title = BuildTitle(s.titleStrategy, post.Content, s.channelID)
+	}
+	if s.titleStrategy == TitleStrategyAddress {
+		processedContent = RemoveAddressPattern(processedContent)
+	}
+	if len(post.Tags) == 0 {
+		post.Tags = s.tags
+	}
+	post.Draft = post.Draft || s.draft
 
-		if err := s.gitService.Add(relImagePath); err != nil {
-			return fmt.Errorf("failed to add image file to git: %w", err)
+	return BuildFrontMatter(post) + processedContent + "\n"
+}
+
+// replaceImage writes the image of a slot, removing a previous image of the slot with another format.
+// It returns the previous and the new image name.
+func (s *Service) replaceImage(postID int64, slot int, mediaFile []byte) (string, string, error) {
+	postDir := filepath.Join(s.relPostsDir, strconv.FormatInt(postID, 10))
+
+	prefix := fmt.Sprintf("image_%d.", slot)
+	newName := prefix + getImageFormat(mediaFile)
+
+	imageNames, err := s.getPostImageNames(postID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get post image names: %w", err)
+	}
+
+	var oldName string
+	for _, name := range imageNames {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		oldName = name
+		if name != newName {
+			if err := s.fs.Remove(filepath.Join(postDir, name)); err != nil {
+				return "", "", fmt.Errorf("failed to remove old image file: %w", err)
+			}
+			_ = s.unstage(filepath.Join(postDir, name))
 		}
 	}
 
-	return nil
+	if err := util.WriteFile(s.fs, filepath.Join(postDir, newName), mediaFile, 0644); err != nil {
+		return "", "", fmt.Errorf("failed to write image file: %w", err)
+	}
+
+	if err := s.stage(filepath.Join(postDir, newName)); err != nil {
+		return "", "", fmt.Errorf("failed to add image file to git: %w", err)
+	}
+
+	return oldName, newName, nil
 }
 
-// DeletePost deletes one or more posts (comma-separated IDs)
-func (s *Service) DeletePost(ctx context.Context, ids string) error {
+// DeletePost deletes one or more posts (comma-separated IDs) of a chat and stages the removal.
+// An ID may be any Telegram message of a post; IDs missing from the index are treated as post IDs.
+// It returns ErrPostNotFound for an ID that matches no post.
+func (s *Service) DeletePost(ctx context.Context, chatID int64, ids string) error {
 	for idStr := range strings.SplitSeq(ids, ",") {
 		idStr = strings.TrimSpace(idStr)
 		if idStr == "" {
 			continue
 		}
 
-		postID, err := strconv.ParseInt(idStr, 10, 64)
+		messageID, err := strconv.ParseInt(idStr, 10, 64)
 		if err != nil {
 			return fmt.Errorf("invalid post ID: %s", idStr)
 		}
 
-		imageNames, err := s.getPostImageNames(postID)
-		if err != nil {
-			continue
+		postID := messageID
+		if entry, ok := s.index.Lookup(chatID, messageID); ok {
+			postID = entry.PostID
 		}
 
-		if len(imageNames) > 0 {
-			postDir := filepath.Join(s.postsDir, strconv.FormatInt(postID, 10))
-			if err := os.RemoveAll(postDir); err != nil {
+		postDir := filepath.Join(s.relPostsDir, strconv.FormatInt(postID, 10))
+		postPath := postDir + ".md"
+		if info, err := s.fs.Stat(postDir); err == nil && info.IsDir() {
+			imageNames, err := s.getPostImageNames(postID)
+			if err != nil {
+				return err
+			}
+			if err := util.RemoveAll(s.fs, postDir); err != nil {
 				return fmt.Errorf("failed to remove post directory: %w", err)
 			}
 
-			relPostDir := filepath.Join(s.relPostsDir, strconv.FormatInt(postID, 10))
-			_ = s.gitService.Remove(filepath.Join(relPostDir, "index.md"))
+			_ = s.unstage(filepath.Join(postDir, "index.md"))
 
 			for _, imageName := range imageNames {
-				_ = s.gitService.Remove(filepath.Join(relPostDir, imageName))
+				_ = s.unstage(filepath.Join(postDir, imageName))
 			}
+		} else if _, err := s.fs.Stat(postPath); err == nil {
+			if err := s.fs.Remove(postPath); err != nil {
+				return fmt.Errorf("failed to remove post file: %w", err)
+			}
+			_ = s.unstage(postPath)
 		} else {
-			filename := strconv.FormatInt(postID, 10) + ".md"
-			postFilePath := filepath.Join(s.postsDir, filename)
-			_ = os.Remove(postFilePath)
-
-			relPostPath := filepath.Join(s.relPostsDir, filename)
-			_ = s.gitService.Remove(relPostPath)
+			return fmt.Errorf("%w: message %d", ErrPostNotFound, messageID)
 		}
-	}
 
-	commitMsg := fmt.Sprintf("Delete post(s): %s", ids)
-	if err := s.gitService.CommitAndPush(ctx, commitMsg); err != nil {
-		return fmt.Errorf("failed to commit and push deletion: %w", err)
+		s.index.RemovePost(postID)
 	}
 
 	return nil
 }
 
-// getEditablePostID finds the closest existing post ID to the given ID
-func (s *Service) getEditablePostID(postID int64) (int64, error) {
-	entries, err := os.ReadDir(s.postsDir)
+// SaveIndex persists the changes to the post index once the changes they record are committed
+func (s *Service) SaveIndex() error {
+	if err := s.index.Save(); err != nil {
+		return fmt.Errorf("failed to save post index: %w", err)
+	}
+	return nil
+}
+
+// RevertIndex drops the changes to the post index since it was last saved,
+// e.g. when the changes of the posts they record are discarded
+func (s *Service) RevertIndex() {
+	s.index.Revert()
+}
+
+// RebuildIndex reconstructs the index by scanning the front matter of existing posts.
+// Posts without Telegram references are indexed under their own ID, with image slot N
+// mapped to message ID+N, for any chat. It returns the number of indexed posts.
+func (s *Service) RebuildIndex() (int, error) {
+	entries, err := s.fs.ReadDir(s.relPostsDir)
 	if err != nil {
 		return 0, fmt.Errorf("failed to read posts directory: %w", err)
 	}
 
-	var postIDs []int64
+	s.index.reset()
+
+	count := 0
 	for _, entry := range entries {
 		name := entry.Name()
-		if strings.Contains(name, "index") {
+
+		var postPath string
+		if entry.IsDir() {
+			postPath = filepath.Join(s.relPostsDir, name, "index.md")
+		} else if idStr, ok := strings.CutSuffix(name, ".md"); ok {
+			name = idStr
+			postPath = filepath.Join(s.relPostsDir, entry.Name())
+		} else {
 			continue
 		}
 
-		var id int64
-		if strings.Contains(name, ".") {
-			idStr := strings.Split(name, ".")[0]
-			var err error
-			id, err = strconv.ParseInt(idStr, 10, 64)
-			if err != nil {
+		postID, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		content, err := util.ReadFile(s.fs, postPath)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
 				continue
 			}
-		} else {
-			var err error
-			id, err = strconv.ParseInt(name, 10, 64)
+			return count, fmt.Errorf("failed to read post %d: %w", postID, err)
+		}
+
+		refs := parseFrontMatterRefs(string(content))
+		post := Post{
+			ID:           postID,
+			ChatID:       refs.chatID,
+			MessageIDs:   refs.messageIDs,
+			MediaGroupID: refs.mediaGroupID,
+		}
+
+		if len(post.MessageIDs) == 0 {
+			imageNames, err := s.getPostImageNames(postID)
 			if err != nil {
-				continue
+				return count, err
 			}
+			for _, slot := range imageSlots(imageNames) {
+				s.index.Put(0, postID+int64(slot), IndexEntry{PostID: postID, Slot: slot})
+			}
+			if len(imageNames) == 0 {
+				s.index.Put(0, postID, IndexEntry{PostID: postID})
+			}
+		} else {
+			s.indexPost(post)
 		}
-		postIDs = append(postIDs, id)
+		count++
 	}
 
-	if len(postIDs) == 0 {
-		return postID, nil
+	if err := s.index.Save(); err != nil {
+		return count, fmt.Errorf("failed to save post index: %w", err)
 	}
 
-	closestID := postIDs[0]
-	minDiff := abs(postID - closestID)
+	return count, nil
+}
 
-	for _, id := range postIDs[1:] {
-		diff := abs(postID - id)
-		if diff < minDiff {
-			minDiff = diff
-			closestID = id
-		}
+// indexPost records the messages and media group of a post in the index
+func (s *Service) indexPost(post Post) {
+	messageIDs := post.MessageIDs
+	if len(messageIDs) == 0 {
+		messageIDs = []int64{post.ID}
+	}
+
+	for slot, messageID := range messageIDs {
+		s.index.Put(post.ChatID, messageID, IndexEntry{PostID: post.ID, Slot: slot})
 	}
+	if post.MediaGroupID != "" {
+		s.index.PutMediaGroup(post.MediaGroupID, post.ID)
+	}
+}
 
-	return closestID, nil
+// imageSlots returns the slot numbers of image_<slot>.<ext> file names
+func imageSlots(imageNames []string) []int {
+	var slots []int
+	for _, name := range imageNames {
+		slotStr, _, _ := strings.Cut(strings.TrimPrefix(name, "image_"), ".")
+		if slot, err := strconv.Atoi(slotStr); err == nil {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
 }
 
 // getPostImageNames returns the list of image file names for a post
 func (s *Service) getPostImageNames(postID int64) ([]string, error) {
-	postDir := filepath.Join(s.postsDir, strconv.FormatInt(postID, 10))
+	postDir := filepath.Join(s.relPostsDir, strconv.FormatInt(postID, 10))
 
-	entries, err := os.ReadDir(postDir)
+	entries, err := s.fs.ReadDir(postDir)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return []string{}, nil
 		}
 		return nil, fmt.Errorf("failed to read post directory: %w", err)
@@ -335,10 +477,3 @@ func getImageFormat(data []byte) string {
 
 	return "jpg"
 }
-
-func abs(x int64) int64 {
-	if x < 0 {
-		return -x
-	}
-	return x
-}
@@ -2,6 +2,7 @@ package zola
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -139,42 +140,62 @@ func TestService_CreatePost_WithMedia(t *testing.T) {
 	}
 }
 
-func TestService_EditPost_FindClosestID(t *testing.T) {
+func TestService_EditPost_UnknownMessage(t *testing.T) {
 	service, tempDir := setupTestService(t)
-	_ = context.Background()
+	ctx := context.Background()
 
 	postsDir := filepath.Join(tempDir, "content", "posts")
-	// Ensure directory exists
-	err := os.MkdirAll(postsDir, 0755)
-	if err != nil {
+	if err := os.MkdirAll(postsDir, 0755); err != nil {
 		t.Fatalf("failed to create posts directory: %v", err)
 	}
 
-	// Create existing posts: 100, 105, 110
-	for _, id := range []int64{100, 105, 110} {
+	// Posts 100 and 105 exist on disk, but message 103 was never published
+	for _, id := range []int64{100, 105} {
 		postPath := filepath.Join(postsDir, fmt.Sprintf("%d.md", id))
-		err := os.WriteFile(postPath, []byte("existing post"), 0644)
-		if err != nil {
+		if err := os.WriteFile(postPath, []byte("existing post"), 0644); err != nil {
 			t.Fatalf("failed to create test post: %v", err)
 		}
 	}
 
-	editableID, err := service.getEditablePostID(103)
-	if err != nil {
-		t.Fatalf("getEditablePostID failed: %v", err)
+	post := Post{ID: 103, ChatID: -100123, Content: "Edited", Date: time.Now()}
+	err := service.EditPost(ctx, post, nil)
+	if !errors.Is(err, ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
 	}
 
-	if editableID != 105 {
-		t.Errorf("expected closest post ID to be 105, got %d", editableID)
+	for _, id := range []int64{100, 105} {
+		content, _ := os.ReadFile(filepath.Join(postsDir, fmt.Sprintf("%d.md", id)))
+		if string(content) != "existing post" {
+			t.Errorf("expected post %d to be untouched, got: %s", id, content)
+		}
 	}
+}
 
-	editableID, err = service.getEditablePostID(107)
+func TestService_EditPost_TextOnly(t *testing.T) {
+	service, tempDir := setupTestService(t)
+	ctx := context.Background()
+
+	post := Post{ID: 150, ChatID: -100123, Content: "Original", Date: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)}
+	if err := service.CreatePost(ctx, post, nil); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+
+	edit := Post{ID: 150, ChatID: -100123, Content: "Edited", Date: post.Date}
+	if err := service.EditPost(ctx, edit, nil); err != nil {
+		t.Fatalf("EditPost failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "content", "posts", "150.md"))
 	if err != nil {
-		t.Fatalf("getEditablePostID failed: %v", err)
+		t.Fatalf("failed to read post file: %v", err)
 	}
 
-	if editableID != 105 {
-		t.Errorf("expected closest post ID to be 105, got %d", editableID)
+	contentStr := string(content)
+	if !contains(contentStr, "Edited") || contains(contentStr, "Original") {
+		t.Errorf("expected edited content, got: %s", contentStr)
+	}
+	if !contains(contentStr, "telegram_message_ids = [150]") {
+		t.Errorf("expected message references to be kept, got: %s", contentStr)
 	}
 }
 
@@ -182,22 +203,20 @@ func TestService_EditPost_WithExistingMedia(t *testing.T) {
 	service, tempDir := setupTestService(t)
 	ctx := context.Background()
 
-	postsDir := filepath.Join(tempDir, "content", "posts")
-	postDir := filepath.Join(postsDir, "200")
-
-	if err := os.MkdirAll(postDir, 0755); err != nil {
-		t.Fatalf("failed to create post directory: %v", err)
-	}
-
-	if err := os.WriteFile(filepath.Join(postDir, "image_0.jpg"), createJPEGBytes(), 0644); err != nil {
-		t.Fatalf("failed to create image: %v", err)
+	post := Post{
+		ID:         200,
+		ChatID:     -100123,
+		Content:    "Album",
+		Date:       time.Now(),
+		MessageIDs: []int64{200, 201},
 	}
-	if err := os.WriteFile(filepath.Join(postDir, "image_1.jpg"), createJPEGBytes(), 0644); err != nil {
-		t.Fatalf("failed to create image: %v", err)
+	if err := service.CreatePost(ctx, post, [][]byte{createJPEGBytes(), createJPEGBytes()}); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
 	}
 
 	editPost := Post{
 		ID:      200,
+		ChatID:  -100123,
 		Content: "Updated content",
 		Date:    time.Now(),
 	}
@@ -206,7 +225,7 @@ func TestService_EditPost_WithExistingMedia(t *testing.T) {
 		t.Fatalf("EditPost failed: %v", err)
 	}
 
-	indexPath := filepath.Join(postDir, "index.md")
+	indexPath := filepath.Join(tempDir, "content", "posts", "200", "index.md")
 	content, err := os.ReadFile(indexPath)
 	if err != nil {
 		t.Fatalf("failed to read post file: %v", err)
@@ -216,37 +235,51 @@ func TestService_EditPost_WithExistingMedia(t *testing.T) {
 	if !contains(contentStr, "image_0.jpg") || !contains(contentStr, "image_1.jpg") {
 		t.Errorf("expected front matter to contain existing images, got: %s", contentStr)
 	}
+	if !contains(contentStr, "Updated content") {
+		t.Errorf("expected updated content, got: %s", contentStr)
+	}
 }
 
 func TestService_EditPost_WithNewMedia(t *testing.T) {
 	service, tempDir := setupTestService(t)
 	ctx := context.Background()
 
-	postsDir := filepath.Join(tempDir, "content", "posts")
-	postDir := filepath.Join(postsDir, "300")
-
-	if err := os.MkdirAll(postDir, 0755); err != nil {
-		t.Fatalf("failed to create post directory: %v", err)
+	post := Post{
+		ID:           300,
+		ChatID:       -100123,
+		Content:      "Album caption",
+		Date:         time.Now(),
+		MessageIDs:   []int64{300, 301},
+		MediaGroupID: "album-1",
+	}
+	if err := service.CreatePost(ctx, post, [][]byte{createJPEGBytes(), createJPEGBytes()}); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
 	}
 
-	if err := os.WriteFile(filepath.Join(postDir, "image_0.jpg"), createJPEGBytes(), 0644); err != nil {
-		t.Fatalf("failed to create image: %v", err)
+	// The second photo of the album is replaced with a PNG, without a caption
+	edit := Post{ID: 301, ChatID: -100123, Date: time.Now()}
+	if err := service.EditPost(ctx, edit, createPNGBytes()); err != nil {
+		t.Fatalf("EditPost failed: %v", err)
 	}
 
-	post := Post{
-		ID:      305,
-		Content: "Updated content with new image",
-		Date:    time.Now(),
+	postDir := filepath.Join(tempDir, "content", "posts", "300")
+	if _, err := os.Stat(filepath.Join(postDir, "image_1.png")); os.IsNotExist(err) {
+		t.Error("expected image_1.png to exist")
 	}
-
-	newMedia := createPNGBytes()
-	if err := service.EditPost(ctx, post, newMedia); err != nil {
-		t.Fatalf("EditPost failed: %v", err)
+	if _, err := os.Stat(filepath.Join(postDir, "image_1.jpg")); err == nil {
+		t.Error("expected image_1.jpg to be replaced")
 	}
 
-	newImagePath := filepath.Join(postDir, "image_5.png")
-	if _, err := os.Stat(newImagePath); os.IsNotExist(err) {
-		t.Errorf("expected new image image_5.png to exist (index = 305 - 300 = 5)")
+	content, err := os.ReadFile(filepath.Join(postDir, "index.md"))
+	if err != nil {
+		t.Fatalf("failed to read post file: %v", err)
+	}
+	contentStr := string(content)
+	if !contains(contentStr, `images = ["image_0.jpg", "image_1.png"]`) {
+		t.Errorf("expected front matter to reference the new image, got: %s", contentStr)
+	}
+	if !contains(contentStr, "Album caption") {
+		t.Errorf("expected content to be kept, got: %s", contentStr)
 	}
 }
 
@@ -268,8 +301,8 @@ func TestService_DeletePost_TextOnly(t *testing.T) {
 
 	// DeletePost will try to commit, but since files aren't in git, it will fail
 	// We'll just verify the files are deleted, not the git operations
-	if err := service.DeletePost(ctx, "400"); err != nil && !strings.Contains(err.Error(), "no changes to commit") {
-		t.Fatalf("DeletePost failed with unexpected error: %v", err)
+	if err := service.DeletePost(ctx, -100123, "400"); err != nil {
+		t.Fatalf("DeletePost failed: %v", err)
 	}
 
 	// Verify file was deleted
@@ -299,8 +332,8 @@ func TestService_DeletePost_WithMedia(t *testing.T) {
 		t.Fatalf("failed to create image: %v", err)
 	}
 
-	if err := service.DeletePost(ctx, "500"); err != nil && !strings.Contains(err.Error(), "no changes to commit") {
-		t.Fatalf("DeletePost failed with unexpected error: %v", err)
+	if err := service.DeletePost(ctx, -100123, "500"); err != nil {
+		t.Fatalf("DeletePost failed: %v", err)
 	}
 
 	if _, err := os.Stat(postDir); err == nil {
@@ -324,8 +357,8 @@ func TestService_DeletePost_MultipleIDs(t *testing.T) {
 		}
 	}
 
-	if err := service.DeletePost(ctx, "600, 601, 602"); err != nil && !strings.Contains(err.Error(), "no changes to commit") {
-		t.Fatalf("DeletePost failed with unexpected error: %v", err)
+	if err := service.DeletePost(ctx, -100123, "600, 601, 602"); err != nil {
+		t.Fatalf("DeletePost failed: %v", err)
 	}
 
 	for _, id := range []string{"600", "601", "602"} {
@@ -336,31 +369,152 @@ func TestService_DeletePost_MultipleIDs(t *testing.T) {
 	}
 }
 
-func TestService_getEditablePostID_FiltersIndex(t *testing.T) {
+func TestService_DeletePost_ByAlbumMessage(t *testing.T) {
 	service, tempDir := setupTestService(t)
+	ctx := context.Background()
 
-	postsDir := filepath.Join(tempDir, "content", "posts")
-	postDir := filepath.Join(postsDir, "700")
+	post := Post{ID: 550, ChatID: -100123, Content: "Album", Date: time.Now(), MessageIDs: []int64{550, 551}}
+	if err := service.CreatePost(ctx, post, [][]byte{createJPEGBytes(), createJPEGBytes()}); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
 
-	if err := os.MkdirAll(postDir, 0755); err != nil {
-		t.Fatalf("failed to create post directory: %v", err)
+	if err := service.DeletePost(ctx, -100123, "551"); err != nil {
+		t.Fatalf("DeletePost failed: %v", err)
 	}
 
-	if err := os.WriteFile(filepath.Join(postDir, "index.md"), []byte("test"), 0644); err != nil {
-		t.Fatalf("failed to create index.md: %v", err)
+	if _, err := os.Stat(filepath.Join(tempDir, "content", "posts", "550")); err == nil {
+		t.Error("expected post directory to be deleted")
 	}
+	if _, ok := service.index.Lookup(-100123, 550); ok {
+		t.Error("expected post to be removed from the index")
+	}
+}
 
-	if err := os.WriteFile(filepath.Join(postsDir, "701.md"), []byte("test"), 0644); err != nil {
+func TestService_DeletePost_OtherChatMessage(t *testing.T) {
+	service, tempDir := setupTestService(t)
+	ctx := context.Background()
+
+	// Message 551 of another channel is published as post 560
+	post := Post{ID: 560, ChatID: -100999, Content: "Album", Date: time.Now(), MessageIDs: []int64{560, 551}}
+	if err := service.CreatePost(ctx, post, [][]byte{createJPEGBytes(), createJPEGBytes()}); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+
+	if err := service.DeletePost(ctx, -100123, "551"); !errors.Is(err, ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "content", "posts", "560")); err != nil {
+		t.Errorf("expected the post of the other channel to be kept, got %v", err)
+	}
+	if _, ok := service.index.Lookup(-100999, 551); !ok {
+		t.Error("expected the post of the other channel to stay indexed")
+	}
+}
+
+func TestService_RevertIndex(t *testing.T) {
+	service, _ := setupTestService(t)
+	ctx := context.Background()
+
+	kept := Post{ID: 800, ChatID: -100123, Content: "Kept", Date: time.Now(), MessageIDs: []int64{800}}
+	if err := service.CreatePost(ctx, kept, nil); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if err := service.SaveIndex(); err != nil {
+		t.Fatalf("SaveIndex failed: %v", err)
+	}
+
+	dropped := Post{ID: 801, ChatID: -100123, Content: "Dropped", Date: time.Now(), MessageIDs: []int64{801}}
+	if err := service.CreatePost(ctx, dropped, nil); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if err := service.DeletePost(ctx, -100123, "800"); err != nil {
+		t.Fatalf("DeletePost failed: %v", err)
+	}
+
+	// The changes of the discarded jobs are dropped, the saved post is indexed again
+	service.RevertIndex()
+	if _, ok := service.index.Lookup(-100123, 800); !ok {
+		t.Error("expected the saved post to be indexed after the revert")
+	}
+	if _, ok := service.index.Lookup(-100123, 801); ok {
+		t.Error("expected the unsaved post to be dropped by the revert")
+	}
+}
+
+func TestService_RebuildIndex(t *testing.T) {
+	service, tempDir := setupTestService(t)
+	ctx := context.Background()
+
+	album := Post{
+		ID:           700,
+		ChatID:       -100123,
+		Content:      "Album",
+		Date:         time.Now(),
+		MessageIDs:   []int64{700, 702},
+		MediaGroupID: "album-7",
+	}
+	if err := service.CreatePost(ctx, album, [][]byte{createJPEGBytes(), createPNGBytes()}); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+
+	postsDir := filepath.Join(tempDir, "content", "posts")
+
+	// Posts written before Telegram references were stored in the front matter
+	if err := os.WriteFile(filepath.Join(postsDir, "701.md"), []byte("+++\ntitle = \"old\"\n+++\n\ntext\n"), 0644); err != nil {
 		t.Fatalf("failed to create post file: %v", err)
 	}
+	legacyDir := filepath.Join(postsDir, "710")
+	if err := os.MkdirAll(legacyDir, 0755); err != nil {
+		t.Fatalf("failed to create post directory: %v", err)
+	}
+	for _, name := range []string{"index.md", "image_0.jpg", "image_2.jpg"} {
+		if err := os.WriteFile(filepath.Join(legacyDir, name), []byte("+++\n+++\n"), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
 
-	editableID, err := service.getEditablePostID(702)
+	indexPath := filepath.Join(tempDir, "data", "post-index.json")
+	service.WithIndex(NewIndex(indexPath))
+
+	count, err := service.RebuildIndex()
 	if err != nil {
-		t.Fatalf("getEditablePostID failed: %v", err)
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 indexed posts, got %d", count)
+	}
+
+	index, err := LoadIndex(indexPath)
+	if err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+
+	tests := []struct {
+		chatID, messageID int64
+		want              IndexEntry
+	}{
+		{-100123, 700, IndexEntry{PostID: 700, Slot: 0}},
+		{-100123, 702, IndexEntry{PostID: 700, Slot: 1}},
+		{-100123, 701, IndexEntry{PostID: 701, Slot: 0}},
+		{-100999, 712, IndexEntry{PostID: 710, Slot: 2}},
+	}
+	for _, tt := range tests {
+		got, ok := index.Lookup(tt.chatID, tt.messageID)
+		if !ok {
+			t.Errorf("expected message %d:%d to be indexed", tt.chatID, tt.messageID)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("message %d:%d: expected %+v, got %+v", tt.chatID, tt.messageID, tt.want, got)
+		}
 	}
 
-	if editableID != 701 {
-		t.Errorf("expected closest post ID to be 701, got %d", editableID)
+	if postID, ok := index.LookupMediaGroup("album-7"); !ok || postID != 700 {
+		t.Errorf("expected media group album-7 to map to post 700, got %d", postID)
+	}
+	if _, ok := index.Lookup(-100123, 701+1000); ok {
+		t.Error("expected unknown message not to be found")
 	}
 }
 